@@ -1,47 +1,126 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/deis/workflow-manager/config"
 	"github.com/deis/workflow-manager/data"
+	"github.com/deis/workflow-manager/diagnostics"
 	"github.com/deis/workflow-manager/handlers"
 	"github.com/deis/workflow-manager/jobs"
 	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/upstream"
+	"github.com/deis/workflow-manager/webhooks"
 	"github.com/gorilla/mux"
 	kcl "k8s.io/kubernetes/pkg/client/unversioned"
 )
 
 func main() {
+	level, err := logger.ParseLevel(config.Spec.LogLevel)
+	if err != nil {
+		logger.Default().Fatalf("Error parsing LOG_LEVEL (%s)", err)
+	}
+	logger.SetDefault(logger.New(os.Stderr, level))
+	log := logger.Default()
+	// ctx is cancelled once shutdown is complete, or the shutdown deadline has passed. Cancelling it
+	// interrupts any periodic jobs and upstream API requests that are still in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	kubeClient, err := kcl.NewInCluster()
 	if err != nil {
 		log.Fatalf("Error creating new Kubernetes client (%s)", err)
 	}
-	apiClient, err := config.GetSwaggerClient(config.Spec.VersionsAPIURL)
+	swaggerClient, err := config.NewSwaggerClient(ctx, config.VersionsAPIClientOptions())
+	if err != nil {
+		log.Fatalf("Error creating new swagger api client for the versions API (%s)", err)
+	}
+	doctorAPIClient, err := config.NewSwaggerClient(ctx, config.DoctorAPIClientOptions())
+	if err != nil {
+		log.Fatalf("Error creating new swagger api client for the doctor API (%s)", err)
+	}
+	apiClient, err := upstream.NewClient(swaggerClient, config.Spec.APIVersion)
 	if err != nil {
-		log.Fatalf("Error creating new swagger api client (%s)", err)
+		log.Fatalf("Error creating new versions api client (%s)", err)
 	}
-	deisK8sResources := k8s.NewResourceInterfaceNamespaced(kubeClient, config.Spec.DeisNamespace)
+	componentFilter, err := k8s.NewComponentFilter(config.Spec.ComponentSelector, config.Spec.ComponentExcludeSelector)
+	if err != nil {
+		log.Fatalf("Error parsing the component label selectors (%s)", err)
+	}
+	deisK8sResources := k8s.NewResourceInterfaceNamespaced(
+		kubeClient,
+		config.Spec.DeisNamespace,
+	).WithComponentFilter(componentFilter)
 	clusterID := data.NewClusterIDFromPersistentStorage(deisK8sResources.Secrets())
-	installedDeisData := data.NewInstalledDeisData(deisK8sResources)
-	availableVersion := data.NewAvailableVersionsFromAPI(
-		apiClient,
-		config.Spec.VersionsAPIURL,
+	namespaces, err := k8s.ResolveNamespaces(
+		kubeClient,
+		k8s.ParseNamespaces(config.Spec.Namespaces),
+		config.Spec.NamespaceSelector,
+		config.Spec.DeisNamespace,
+	)
+	if err != nil {
+		log.Fatalf("Error resolving the namespaces to inventory (%s)", err)
+	}
+	// namespaces are only resolved once, so those created later aren't inventoried until a restart
+	log.Infof("Inventorying namespaces %v", namespaces)
+	// an inventory watches each namespace, so that listing components doesn't call the k8s API
+	nsResources := make([]*k8s.ResourceInterfaceNamespaced, len(namespaces))
+	inventories := make([]k8s.ComponentLister, len(namespaces))
+	for i, ns := range namespaces {
+		nsResources[i] = k8s.NewResourceInterfaceNamespaced(kubeClient, ns).WithComponentFilter(componentFilter)
+		inventory := k8s.NewInventory(log, nsResources[i], time.Duration(config.Spec.InventoryResync)*time.Second)
+		inventory.Run(ctx.Done())
+		if err := inventory.WaitForSync(time.Duration(config.Spec.InventorySyncTimeout) * time.Second); err != nil {
+			log.With("namespace", ns).WithError(err).Warnf("Timed out waiting for the component inventory, its components are skipped until it syncs")
+		}
+		inventories[i] = inventory
+	}
+	installedDeisData := data.NewInstalledDeisData(k8s.NewMultiComponentLister(log, inventories...))
+	if _, err := data.ParseComponentTrains(config.Spec.ComponentTrains); err != nil {
+		log.Fatalf("Error parsing COMPONENT_TRAINS (%s)", err)
+	}
+	availableVersion, err := getAvailableVersions(apiClient)
+	if err != nil {
+		log.Fatalf("Error creating available versions source (%s)", err)
+	}
+	availableVersion, err = data.NewPersistentAvailableVersions(
+		availableVersion,
+		data.NewVersionsCacheStoreFromSecret(deisK8sResources.Secrets()),
 	)
+	if err != nil {
+		log.Warnf("Error loading the persisted available versions cache (%s)", err)
+	}
+	redaction, err := data.ConfiguredRedactionRules(data.NewRedactionKeyFromPersistentStorage(deisK8sResources.Secrets()))
+	if err != nil {
+		log.Fatalf("Error parsing REDACT_KEY_PATTERNS (%s)", err)
+	}
+	doctorHistory, err := data.ConfiguredDoctorHistory(deisK8sResources.Secrets())
+	if err != nil {
+		log.Fatalf("Error creating the doctor report history (%s)", err)
+	}
 	availableComponentVersion := data.NewLatestReleasedComponent(deisK8sResources, availableVersion)
+	updateNotifier, err := getUpdateNotifier(availableComponentVersion, deisK8sResources.Secrets())
+	if err != nil {
+		log.Fatalf("Error creating the webhook notifier (%s)", err)
+	}
 
 	pollDur := time.Duration(config.Spec.Polling) * time.Second
 	// we want to do the following jobs according to our remote API interval:
-	// 1. get latest stable deis component versions
+	// 1. get latest deis component versions, on the release train that each component follows
 	// 2. send diagnostic data, if appropriate
 	glvdPeriodic := jobs.NewGetLatestVersionDataPeriodic(
 		installedDeisData,
 		clusterID,
 		availableVersion,
 		availableComponentVersion,
+		updateNotifier,
 		pollDur,
 	)
 
@@ -49,22 +128,131 @@ func main() {
 		apiClient,
 		clusterID,
 		deisK8sResources,
+		installedDeisData,
 		availableVersion,
 		pollDur,
 	)
-	toDo := []jobs.Periodic{glvdPeriodic, svPeriodic}
-	log.Printf("Starting periodic jobs at interval %s", pollDur)
-	ch := jobs.DoPeriodic(toDo)
-	defer close(ch)
+	toDo := []jobs.Periodic{
+		jobs.WithRetry(glvdPeriodic, jobs.ConfiguredRetryPolicy(config.Spec.LatestVersionsRetryMaxAttempts)),
+		jobs.WithRetry(svPeriodic, jobs.ConfiguredRetryPolicy(config.Spec.SendVersionsRetryMaxAttempts)),
+	}
+	log.Infof("Starting periodic jobs at interval %s", pollDur)
+	results := jobs.NewResults()
+	startupJitter := time.Duration(config.Spec.StartupJitter) * time.Second
+	periodics := jobs.DoPeriodic(ctx, toDo, results, startupJitter)
 
 	// Get a new router, with handler functions
-	r := handlers.RegisterRoutes(mux.NewRouter(), availableVersion, deisK8sResources)
+	r := handlers.RegisterRoutes(
+		mux.NewRouter(),
+		availableVersion,
+		deisK8sResources,
+		nsResources,
+		installedDeisData,
+		clusterID,
+		results,
+		doctorAPIClient,
+		redaction,
+		diagnostics.NewEngine(diagnostics.ConfiguredChecks()...),
+		doctorHistory,
+	)
 	// Bind to a port and pass our router in
 	hostStr := fmt.Sprintf(":%s", config.Spec.Port)
-	log.Printf("Serving on %s", hostStr)
-	if err := http.ListenAndServe(hostStr, r); err != nil {
-		close(ch)
-		log.Println("Unable to open up TLS listener")
-		log.Fatal("ListenAndServe: ", err)
+	server := &http.Server{Addr: hostStr, Handler: r}
+	serveErrCh := make(chan error, 1)
+	go func() {
+		log.Infof("Serving on %s", hostStr)
+		serveErrCh <- server.ListenAndServe()
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	exitCode := 0
+	select {
+	case err := <-serveErrCh:
+		log.WithError(err).Errorf("ListenAndServe failed")
+		exitCode = 1
+	case sig := <-sigCh:
+		log.Infof("Received %s, shutting down", sig)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(config.Spec.ShutdownTimeout)*time.Second,
+	)
+	defer shutdownCancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Error shutting down the HTTP server (%s)", err)
+	}
+	if err := periodics.Drain(shutdownCtx); err != nil {
+		log.Warnf("Periodic jobs did not finish before the shutdown deadline, cancelling them (%s)", err)
+	}
+	cancel()
+	periodics.Wait()
+	log.Infof("Shutdown complete")
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// getAvailableVersions returns the data.AvailableVersions implementation selected by
+// config.Spec.VersionsSource
+func getAvailableVersions(apiClient upstream.Client) (data.AvailableVersions, error) {
+	switch config.Spec.VersionsSource {
+	case config.VersionsSourceAPI:
+		return data.NewAvailableVersionsFromAPI(apiClient, config.Spec.VersionsAPIURL), nil
+	case config.VersionsSourceCatalog:
+		if config.Spec.CatalogURL == "" {
+			return nil, fmt.Errorf("a catalog URL is required for the %s versions source", config.VersionsSourceCatalog)
+		}
+		if config.Spec.CatalogSkipVerify {
+			logger.Default().Warnf("Not verifying the signature of the version catalog at %s", config.Spec.CatalogURL)
+			return data.NewAvailableVersionsFromCatalog(config.Spec.CatalogURL, "", nil), nil
+		}
+		if config.Spec.CatalogPublicKey == "" {
+			return nil, fmt.Errorf("a catalog public key is required unless catalog signature verification is skipped")
+		}
+		pemBytes, err := ioutil.ReadFile(config.Spec.CatalogPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		key, err := data.ParseCatalogPublicKey(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return data.NewAvailableVersionsFromCatalog(
+			config.Spec.CatalogURL,
+			config.Spec.CatalogSignatureURL,
+			key,
+		), nil
+	}
+	return nil, fmt.Errorf("unknown versions source %q", config.Spec.VersionsSource)
+}
+
+// getUpdateNotifier returns the jobs.UpdateNotifier that delivers to the webhooks configured in
+// config.Spec, or nil if there are none. versions provides the release notes of available
+// versions, and secrets manages the secret that remembers the delivered notifications
+func getUpdateNotifier(
+	versions data.AvailableComponentVersion,
+	secrets k8s.KubeSecretGetterCreatorUpdater,
+) (jobs.UpdateNotifier, error) {
+	urls, err := webhooks.ParseURLs(config.Spec.WebhookURLs)
+	if err != nil || len(urls) == 0 {
+		return nil, err
+	}
+	if config.Spec.WebhookSecret == "" {
+		logger.Default().Warnf("Not signing webhook deliveries, since no webhook secret is set")
+	}
+	client, err := config.NewHTTPClient(config.WebhookClientOptions())
+	if err != nil {
+		return nil, err
 	}
+	return webhooks.NewNotifier(
+		urls,
+		config.Spec.WebhookSecret,
+		client,
+		webhooks.ConfiguredRetryPolicy(),
+		time.Duration(config.Spec.WebhookNotifyTimeout)*time.Second,
+		versions,
+		webhooks.NewNotifiedStoreFromSecret(secrets),
+	), nil
 }
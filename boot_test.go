@@ -11,7 +11,9 @@ import (
 	"github.com/arschles/assert"
 	"github.com/deis/workflow-manager/config"
 	"github.com/deis/workflow-manager/data"
+	"github.com/deis/workflow-manager/diagnostics"
 	"github.com/deis/workflow-manager/handlers"
+	"github.com/deis/workflow-manager/k8s"
 	"github.com/deis/workflow-manager/mocks"
 	apiclient "github.com/deis/workflow-manager/pkg/swagger/client"
 	"github.com/gorilla/mux"
@@ -29,10 +31,13 @@ func newServer(apiClient *apiclient.WorkflowManager) *httptest.Server {
 	r.Handle("/id", idHdl)
 	docHdl := handlers.DoctorHandler(
 		mocks.InstalledMockData{},
-		mocks.RunningK8sMockData{}, // TODO: mock k8s node data
+		[]k8s.RunningK8sData{mocks.RunningK8sMockData{Name: "deis"}}, // TODO: mock k8s node data
 		&mocks.ClusterIDMockData{},
 		mocks.LatestMockData{},
 		apiClient,
+		data.RedactionRules{DropEnvValues: true, HashAddresses: true, HashKey: mocks.RedactionKeyMockData{}},
+		diagnostics.NewEngine(diagnostics.ConfiguredChecks()...),
+		nil,
 	)
 	r.Handle("/doctor", docHdl).Methods("POST")
 	return httptest.NewServer(r)
@@ -1,11 +1,6 @@
 package config
 
-import (
-	apiclient "github.com/deis/workflow-manager/pkg/swagger/client"
-	httptransport "github.com/go-swagger/go-swagger/httpkit/client"
-	"github.com/kelseyhightower/envconfig"
-	"net/url"
-)
+import "github.com/kelseyhightower/envconfig"
 
 // Specification config struct
 type Specification struct {
@@ -13,26 +8,99 @@ type Specification struct {
 	Polling        int    `default:"43200" envconfig:"POLL_INTERVAL_SEC"` // 43200 seconds = 12 hours
 	VersionsAPIURL string `envconfig:"VERSIONS_API_URL" default:"https://versions-staging.deis.com"`
 	DoctorAPIURL   string `envconfig:"DOCTOR_API_URL" default:"https://doctor-staging.deis.com"`
-	APIVersion     string `envconfig:"API_VERSION" default:"v3"`
+	APIVersion     string `envconfig:"API_VERSION" default:"v3"` // versions API version: "v2", "v3" or "auto"
 	CheckVersions  bool   `default:"true" envconfig:"CHECK_VERSIONS"`
 	DeisNamespace  string `default:"deis" envconfig:"DEIS_NAMESPACE"`
+	LogLevel       string `default:"info" envconfig:"LOG_LEVEL"` // "debug", "info", "warn" or "error"
+	// namespaces to inventory and report in doctor reports, in addition to those matching
+	// NamespaceSelector. "*" selects every namespace. Defaults to DeisNamespace if neither is set.
+	// Both are only resolved at startup
+	Namespaces        string `envconfig:"NAMESPACES"` // comma separated, e.g. "deis,kube-system"
+	NamespaceSelector string `envconfig:"NAMESPACE_SELECTOR"`
+	ShutdownTimeout   int    `default:"20" envconfig:"SHUTDOWN_TIMEOUT_SEC"` // time to wait for requests and jobs to finish on SIGTERM
+	// retry policy for failed periodic jobs. The per-job max attempts override RetryMaxAttempts when they are greater than zero
+	RetryMaxAttempts               int     `default:"5" envconfig:"RETRY_MAX_ATTEMPTS"`
+	RetryInitialBackoff            int     `default:"30" envconfig:"RETRY_INITIAL_BACKOFF_SEC"`
+	RetryMaxBackoff                int     `default:"1800" envconfig:"RETRY_MAX_BACKOFF_SEC"` // 1800 seconds = 30 minutes
+	RetryJitter                    float64 `default:"0.5" envconfig:"RETRY_JITTER"`
+	SendVersionsRetryMaxAttempts   int     `default:"0" envconfig:"SEND_VERSIONS_RETRY_MAX_ATTEMPTS"`
+	LatestVersionsRetryMaxAttempts int     `default:"0" envconfig:"LATEST_VERSIONS_RETRY_MAX_ATTEMPTS"`
+	StartupJitter                  int     `default:"30" envconfig:"STARTUP_JITTER_SEC"` // max random delay before the first run of each job
+	// source of available component versions: "api" for the versions API, or "catalog" for a signed catalog at CatalogURL
+	VersionsSource      string `default:"api" envconfig:"VERSIONS_SOURCE"`
+	CatalogURL          string `envconfig:"CATALOG_URL"`           // file path, file:// or http(s):// URL
+	CatalogSignatureURL string `envconfig:"CATALOG_SIGNATURE_URL"` // defaults to CatalogURL + ".sig"
+	CatalogPublicKey    string `envconfig:"CATALOG_PUBLIC_KEY"`    // path to the PEM encoded public key that signs the catalog
+	CatalogSkipVerify   bool   `default:"false" envconfig:"CATALOG_SKIP_VERIFY"`
+	// release train that components follow, e.g. "stable" or "beta". The component.deis.io/train
+	// annotation overrides it per component, and ComponentTrains overrides both
+	ReleaseTrain    string `default:"stable" envconfig:"RELEASE_TRAIN"`
+	ComponentTrains string `envconfig:"COMPONENT_TRAINS"` // comma separated component=train pairs
+	// label selectors for the workloads that are reported as components. Workloads annotated with
+	// component.deis.io/ignore=true are never reported
+	ComponentSelector        string `envconfig:"COMPONENT_SELECTOR"`
+	ComponentExcludeSelector string `envconfig:"COMPONENT_EXCLUDE_SELECTOR"`
+	// HTTP client settings for the versions and doctor APIs. Empty proxies are read from the environment
+	UserAgent                     string `default:"deis-workflow-manager" envconfig:"USER_AGENT"`
+	VersionsAPITimeout            int    `default:"30" envconfig:"VERSIONS_API_TIMEOUT_SEC"`
+	VersionsAPICACert             string `envconfig:"VERSIONS_API_CA_CERT"` // path to a PEM encoded CA bundle
+	VersionsAPIInsecureSkipVerify bool   `default:"false" envconfig:"VERSIONS_API_INSECURE_SKIP_VERIFY"`
+	VersionsAPIProxy              string `envconfig:"VERSIONS_API_PROXY"`
+	DoctorAPITimeout              int    `default:"30" envconfig:"DOCTOR_API_TIMEOUT_SEC"`
+	DoctorAPICACert               string `envconfig:"DOCTOR_API_CA_CERT"` // path to a PEM encoded CA bundle
+	DoctorAPIInsecureSkipVerify   bool   `default:"false" envconfig:"DOCTOR_API_INSECURE_SKIP_VERIFY"`
+	DoctorAPIProxy                string `envconfig:"DOCTOR_API_PROXY"`
+	// the installed component inventory is kept up to date by watching each inventoried namespace
+	InventoryResync      int `default:"600" envconfig:"INVENTORY_RESYNC_SEC"`
+	InventorySyncTimeout int `default:"30" envconfig:"INVENTORY_SYNC_TIMEOUT_SEC"` // time to wait for the initial list at boot
+	// doctor reports are scrubbed before they're published. RedactKeyPatterns is a comma separated
+	// list of regular expressions, matched against field keys and environment variable names
+	RedactEnvValues     bool   `default:"true" envconfig:"REDACT_ENV_VALUES"`
+	RedactKeyPatterns   string `default:"(?i)passw,(?i)secret,(?i)token,(?i)credential,(?i)api[-_]?key,(?i)private[-_]?key,last-applied-configuration" envconfig:"REDACT_KEY_PATTERNS"`
+	RedactHashAddresses bool   `default:"true" envconfig:"REDACT_HASH_ADDRESSES"` // hash IPs and hostnames
+	// doctor reports include the most recent logs of every container in DeisNamespace, and in the
+	// reported namespaces listed in DoctorLogNamespaces ("*" for all of them), and of the previous
+	// instance of every restarted container. Longer logs keep their latest lines
+	DoctorLogs             bool   `default:"true" envconfig:"DOCTOR_LOGS"`
+	DoctorLogNamespaces    string `envconfig:"DOCTOR_LOG_NAMESPACES"`
+	DoctorLogTailLines     int    `default:"500" envconfig:"DOCTOR_LOG_TAIL_LINES"`
+	DoctorLogSince         int    `default:"0" envconfig:"DOCTOR_LOG_SINCE_SEC"`             // 0 for no age limit
+	DoctorLogMaxBytes      int    `default:"262144" envconfig:"DOCTOR_LOG_MAX_BYTES"`        // per container
+	DoctorLogMaxTotalBytes int    `default:"8388608" envconfig:"DOCTOR_LOG_MAX_TOTAL_BYTES"` // per namespace
+	DoctorLogTimeout       int    `default:"10" envconfig:"DOCTOR_LOG_TIMEOUT_SEC"`          // per pod
+	DoctorLogTotalTimeout  int    `default:"60" envconfig:"DOCTOR_LOG_TOTAL_TIMEOUT_SEC"`    // per report
+	// the most recent doctor reports are kept in the cluster, each in a secret, or in DoctorHistoryDir
+	// if it's set, e.g. on a persistent volume. 0 disables the history
+	DoctorHistorySize int    `default:"10" envconfig:"DOCTOR_HISTORY_SIZE"`
+	DoctorHistoryDir  string `envconfig:"DOCTOR_HISTORY_DIR"`
+	// thresholds of the diagnostic checks that are served at /diagnostics and embedded in doctor reports
+	DiagnosticsPendingThreshold    int `default:"300" envconfig:"DIAGNOSTICS_PENDING_THRESHOLD_SEC"` // pods pending for longer are reported
+	DiagnosticsEventBurstThreshold int `default:"10" envconfig:"DIAGNOSTICS_EVENT_BURST_THRESHOLD"`  // warning events per object and reason
+	DiagnosticsEventBurstWindow    int `default:"600" envconfig:"DIAGNOSTICS_EVENT_BURST_WINDOW_SEC"`
+	// when an update becomes available for a component, it's POSTed to each of WebhookURLs (comma
+	// separated), signed with WebhookSecret. Failed deliveries are retried with exponential backoff
+	WebhookURLs                string `envconfig:"WEBHOOK_URLS"`
+	WebhookSecret              string `envconfig:"WEBHOOK_SECRET"` // HMAC-SHA256 key
+	WebhookTimeout             int    `default:"10" envconfig:"WEBHOOK_TIMEOUT_SEC"`
+	WebhookCACert              string `envconfig:"WEBHOOK_CA_CERT"` // path to a PEM encoded CA bundle
+	WebhookRetryMaxAttempts    int    `default:"5" envconfig:"WEBHOOK_RETRY_MAX_ATTEMPTS"`
+	WebhookRetryInitialBackoff int    `default:"5" envconfig:"WEBHOOK_RETRY_INITIAL_BACKOFF_SEC"`
+	WebhookRetryMaxBackoff     int    `default:"60" envconfig:"WEBHOOK_RETRY_MAX_BACKOFF_SEC"`
+	WebhookNotifyTimeout       int    `default:"300" envconfig:"WEBHOOK_NOTIFY_TIMEOUT_SEC"` // per version check, for all deliveries
+	// available versions fetched longer ago than this are reported as stale
+	VersionsCacheMaxAge int `default:"86400" envconfig:"VERSIONS_CACHE_MAX_AGE_SEC"` // 86400 seconds = 24 hours
 }
 
+const (
+	// VersionsSourceAPI is the VersionsSource value for the versions API
+	VersionsSourceAPI = "api"
+	// VersionsSourceCatalog is the VersionsSource value for a signed catalog
+	VersionsSourceCatalog = "catalog"
+)
+
 // Spec is an exportable variable that contains workflow manager config data
 var Spec Specification
 
 func init() {
 	envconfig.Process("workflow_manager", &Spec)
 }
-
-func GetSwaggerClient(apiURL string) (*apiclient.WorkflowManager, error) {
-	urlDet, err := url.Parse(apiURL)
-	if err != nil {
-		return nil, err
-	}
-	// create the transport
-	transport := httptransport.New(urlDet.Host, "", []string{urlDet.Scheme})
-	apiClient := apiclient.Default
-	apiClient.SetTransport(transport)
-	return apiClient, nil
-}
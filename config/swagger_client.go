@@ -0,0 +1,290 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/metrics"
+	apiclient "github.com/deis/workflow-manager/pkg/swagger/client"
+	"github.com/go-swagger/go-swagger/client"
+	httptransport "github.com/go-swagger/go-swagger/httpkit/client"
+	strfmt "github.com/go-swagger/go-swagger/strfmt"
+)
+
+const defaultUserAgent = "deis-workflow-manager"
+
+// SwaggerClientOptions configures a swagger API client created by NewSwaggerClient
+type SwaggerClientOptions struct {
+	// URL is the base URL of the API. Its path, if any, is prepended to every request path
+	URL string
+	// Timeout is the maximum duration of a single request, including reading the response body.
+	// Zero means no timeout
+	Timeout time.Duration
+	// CACertFile is the path to a PEM encoded CA bundle that is trusted in addition to the system
+	// roots. Empty means only the system roots are trusted
+	CACertFile string
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool
+	// Proxy is the URL of the HTTP proxy to send requests through. Empty means the proxy is read
+	// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	Proxy string
+	// UserAgent is sent in the User-Agent header of every request. Empty means "deis-workflow-manager"
+	UserAgent string
+}
+
+// VersionsAPIClientOptions returns the SwaggerClientOptions for the versions API, read from Spec
+func VersionsAPIClientOptions() SwaggerClientOptions {
+	return SwaggerClientOptions{
+		URL:                Spec.VersionsAPIURL,
+		Timeout:            time.Duration(Spec.VersionsAPITimeout) * time.Second,
+		CACertFile:         Spec.VersionsAPICACert,
+		InsecureSkipVerify: Spec.VersionsAPIInsecureSkipVerify,
+		Proxy:              Spec.VersionsAPIProxy,
+		UserAgent:          Spec.UserAgent,
+	}
+}
+
+// DoctorAPIClientOptions returns the SwaggerClientOptions for the doctor API, read from Spec
+func DoctorAPIClientOptions() SwaggerClientOptions {
+	return SwaggerClientOptions{
+		URL:                Spec.DoctorAPIURL,
+		Timeout:            time.Duration(Spec.DoctorAPITimeout) * time.Second,
+		CACertFile:         Spec.DoctorAPICACert,
+		InsecureSkipVerify: Spec.DoctorAPIInsecureSkipVerify,
+		Proxy:              Spec.DoctorAPIProxy,
+		UserAgent:          Spec.UserAgent,
+	}
+}
+
+// GetSwaggerClient returns a new swagger API client that makes requests to apiURL, using the
+// default options
+func GetSwaggerClient(apiURL string) (*apiclient.WorkflowManager, error) {
+	return NewSwaggerClient(context.Background(), SwaggerClientOptions{URL: apiURL})
+}
+
+// NewSwaggerClient returns a new swagger API client configured by opts. Every client has its own
+// transport, so clients for different APIs never share settings. Every request made by the
+// returned client is cancelled when ctx is done, and logged with the logger that ctx carries. Use
+// WithContext to bind the requests of a job or a request to its own context. Returns an error if
+// opts.URL or opts.Proxy isn't an absolute http(s) URL, or if opts.CACertFile can't be read
+func NewSwaggerClient(ctx context.Context, opts SwaggerClientOptions) (*apiclient.WorkflowManager, error) {
+	apiURL, err := parseHTTPURL(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q (%s)", opts.URL, err)
+	}
+	httpTransport, err := newHTTPTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	userAgent := opts.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	transport := &swaggerTransport{
+		base:      ctx,
+		ctx:       ctx,
+		apiURL:    apiURL,
+		timeout:   opts.Timeout,
+		userAgent: userAgent,
+		rt:        httpTransport,
+	}
+	return apiclient.New(transport, strfmt.Default), nil
+}
+
+// WithContext returns a copy of apiClient whose requests are cancelled when ctx is done, as well as
+// when the context apiClient was created with is done, and are logged with the logger that ctx
+// carries. apiClient is returned as is if it wasn't created by NewSwaggerClient
+func WithContext(ctx context.Context, apiClient *apiclient.WorkflowManager) *apiclient.WorkflowManager {
+	t, ok := apiClient.Transport.(*swaggerTransport)
+	if !ok {
+		return apiClient
+	}
+	bound := *t
+	bound.ctx = ctx
+	return apiclient.New(&bound, strfmt.Default)
+}
+
+// WebhookClientOptions returns the SwaggerClientOptions for webhook deliveries, read from Spec.
+// Webhooks aren't a swagger API, so the options have no URL, and only configure NewHTTPClient
+func WebhookClientOptions() SwaggerClientOptions {
+	return SwaggerClientOptions{
+		Timeout:    time.Duration(Spec.WebhookTimeout) * time.Second,
+		CACertFile: Spec.WebhookCACert,
+		UserAgent:  Spec.UserAgent,
+	}
+}
+
+// NewHTTPClient returns a new http.Client with the timeout, proxy and TLS settings in opts. Its
+// requests aren't logged, since the URLs they're made to may carry credentials. opts.URL and
+// opts.UserAgent are ignored
+func NewHTTPClient(opts SwaggerClientOptions) (*http.Client, error) {
+	httpTransport, err := newHTTPTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: httpTransport, Timeout: opts.Timeout}, nil
+}
+
+// newHTTPTransport returns a new http.Transport with the proxy and TLS settings in opts
+func newHTTPTransport(opts SwaggerClientOptions) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if opts.Proxy != "" {
+		proxyURL, err := parseHTTPURL(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q (%s)", opts.Proxy, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.CACertFile != "" {
+		pemBytes, err := ioutil.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pemBytes) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACertFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	}, nil
+}
+
+// parseHTTPURL parses rawURL, and returns an error unless it's an absolute http or https URL
+func parseHTTPURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+	return u, nil
+}
+
+// swaggerTransport fulfills the client.Transport interface by submitting each operation through a
+// swagger runtime whose requests are bound to ctx, and to base, the context the client was created
+// with. Runtimes share rt, so that connections are reused
+type swaggerTransport struct {
+	base      context.Context
+	ctx       context.Context
+	apiURL    *url.URL
+	timeout   time.Duration
+	userAgent string
+	rt        http.RoundTripper
+}
+
+// Submit is the client.Transport interface implementation
+func (s *swaggerTransport) Submit(op *client.Operation) (interface{}, error) {
+	runtime := httptransport.New(s.apiURL.Host, s.apiURL.Path, []string{s.apiURL.Scheme})
+	runtime.Transport = &contextRoundTripper{
+		base:      s.base,
+		ctx:       s.ctx,
+		timeout:   s.timeout,
+		userAgent: s.userAgent,
+		rt:        s.rt,
+	}
+	return metrics.InstrumentTransport(runtime).Submit(op)
+}
+
+// contextRoundTripper is an http.RoundTripper that binds every request to ctx and base, limits it
+// to timeout and sets its User-Agent header. Requests are logged with the logger that ctx carries
+type contextRoundTripper struct {
+	base      context.Context
+	ctx       context.Context
+	timeout   time.Duration
+	userAgent string
+	rt        http.RoundTripper
+}
+
+// RoundTrip is the http.RoundTripper interface implementation
+func (c *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := withCancelOn(c.ctx, c.base)
+	if c.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, c.timeout)
+		cancelCtx := cancel
+		cancel = func() {
+			cancelTimeout()
+			cancelCtx()
+		}
+	}
+	outReq := req.WithContext(ctx)
+	outReq.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		outReq.Header[k] = v
+	}
+	outReq.Header.Set("User-Agent", c.userAgent)
+	start := time.Now()
+	resp, err := c.rt.RoundTrip(outReq)
+	log := logger.FromContext(outReq.Context()).WithFields(logger.Fields{
+		"api":         req.URL.Host,
+		"method":      req.Method,
+		"path":        req.URL.Path,
+		"duration_ms": time.Since(start).Seconds() * 1000,
+	})
+	if err != nil {
+		log.WithError(err).Warnf("upstream request failed")
+		cancel()
+		return nil, err
+	}
+	log.With("status", resp.StatusCode).Infof("upstream request")
+	// the timeout covers reading the body, so it can only be released once the body is closed
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// withCancelOn returns a copy of ctx that's also cancelled when base is done
+func withCancelOn(ctx, base context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if base.Err() != nil {
+		cancel()
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-base.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// cancelOnClose is an io.ReadCloser that calls cancel when it's closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close is the io.Closer interface implementation
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+)
+
+// newPingServer returns a test server that answers pings, counting them in *pings and recording the
+// User-Agent header of the last one in *userAgent
+func newPingServer(pings *int, userAgent *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*pings++
+		*userAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Write([]byte("{}"))
+	}))
+}
+
+func TestNewSwaggerClientsAreIndependent(t *testing.T) {
+	var versionsPings, doctorPings int
+	var versionsUA, doctorUA string
+	versionsServer := newPingServer(&versionsPings, &versionsUA)
+	defer versionsServer.Close()
+	doctorServer := newPingServer(&doctorPings, &doctorUA)
+	defer doctorServer.Close()
+
+	versionsClient, err := NewSwaggerClient(context.Background(), SwaggerClientOptions{URL: versionsServer.URL})
+	assert.NoErr(t, err)
+	doctorClient, err := NewSwaggerClient(context.Background(), SwaggerClientOptions{
+		URL:       doctorServer.URL,
+		UserAgent: "doctor-test",
+	})
+	assert.NoErr(t, err)
+	_, err = versionsClient.Operations.Ping(nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, versionsPings, 1, "number of versions API pings")
+	assert.Equal(t, doctorPings, 0, "number of doctor API pings")
+	assert.Equal(t, versionsUA, defaultUserAgent, "versions API user agent")
+	_, err = doctorClient.Operations.Ping(nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, versionsPings, 1, "number of versions API pings")
+	assert.Equal(t, doctorPings, 1, "number of doctor API pings")
+	assert.Equal(t, doctorUA, "doctor-test", "doctor API user agent")
+}
+
+func TestNewSwaggerClientInvalidOptions(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range []SwaggerClientOptions{
+		{URL: ""},
+		{URL: "versions.deis.com"},
+		{URL: "ftp://versions.deis.com"},
+		{URL: "https://%zz"},
+		{URL: "https://versions.deis.com", Proxy: "not a proxy"},
+		{URL: "https://versions.deis.com", CACertFile: "/does/not/exist.pem"},
+	} {
+		_, err := NewSwaggerClient(ctx, opts)
+		assert.True(t, err != nil, "expected an error for options %+v", opts)
+	}
+	_, err := NewSwaggerClient(ctx, SwaggerClientOptions{URL: "https://versions.deis.com/prefix", Proxy: "http://proxy:3128"})
+	assert.NoErr(t, err)
+}
+
+func TestNewSwaggerClientTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer ts.Close()
+	apiClient, err := NewSwaggerClient(context.Background(), SwaggerClientOptions{
+		URL:     ts.URL,
+		Timeout: 50 * time.Millisecond,
+	})
+	assert.NoErr(t, err)
+	_, err = apiClient.Operations.Ping(nil)
+	assert.True(t, err != nil, "expected a timeout error")
+}
+
+func TestWithContext(t *testing.T) {
+	var pings int
+	var userAgent string
+	ts := newPingServer(&pings, &userAgent)
+	defer ts.Close()
+	apiClient, err := NewSwaggerClient(context.Background(), SwaggerClientOptions{URL: ts.URL})
+	assert.NoErr(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	bound := WithContext(ctx, apiClient)
+	_, err = bound.Operations.Ping(nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, pings, 1, "number of pings")
+	cancel()
+	_, err = bound.Operations.Ping(nil)
+	assert.True(t, err != nil, "expected an error for a request with a cancelled context")
+	// the client that was bound is unaffected
+	_, err = apiClient.Operations.Ping(nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, pings, 2, "number of pings")
+
+	// requests are also cancelled when the context the client was created with is done
+	baseCtx, baseCancel := context.WithCancel(context.Background())
+	apiClient, err = NewSwaggerClient(baseCtx, SwaggerClientOptions{URL: ts.URL})
+	assert.NoErr(t, err)
+	baseCancel()
+	_, err = WithContext(context.Background(), apiClient).Operations.Ping(nil)
+	assert.True(t, err != nil, "expected an error for a request of a client with a cancelled context")
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"context"
+
 	"github.com/deis/workflow-manager/k8s"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
 )
@@ -8,7 +10,7 @@ import (
 // AvailableComponentVersion is an interface for managing component version data
 type AvailableComponentVersion interface {
 	// will have a Get method to retrieve available component version data
-	Get(component string, cluster models.Cluster) (models.Version, error)
+	Get(ctx context.Context, component models.Component, cluster models.Cluster) (models.Version, error)
 }
 
 // latestReleasedComponent fulfills the AvailableComponentVersion interface
@@ -29,8 +31,9 @@ func NewLatestReleasedComponent(
 }
 
 // Get method for LatestReleasedComponent
-func (c *latestReleasedComponent) Get(component string, cluster models.Cluster) (models.Version, error) {
+func (c *latestReleasedComponent) Get(ctx context.Context, component models.Component, cluster models.Cluster) (models.Version, error) {
 	version, err := GetLatestVersion(
+		ctx,
 		component,
 		cluster,
 		c.availableVersions,
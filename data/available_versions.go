@@ -1,34 +1,40 @@
 package data
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"github.com/deis/workflow-manager/config"
-	apiclient "github.com/deis/workflow-manager/pkg/swagger/client"
-	"github.com/deis/workflow-manager/pkg/swagger/client/operations"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"github.com/deis/workflow-manager/upstream"
 )
 
 // AvailableVersions is an interface for managing available component version data
 type AvailableVersions interface {
 	// Cached returns the internal cache of component versions. Returns the empty slice on a miss
 	Cached() []models.ComponentVersion
-	// Refresh gets the latest versions of each component listed in the given cluster
-	Refresh(models.Cluster) ([]models.ComponentVersion, error)
+	// Refresh gets the latest versions of each component listed in the given cluster. Requests it
+	// makes are bound to ctx
+	Refresh(context.Context, models.Cluster) ([]models.ComponentVersion, error)
 	// Store stores the given slice of models.ComponentVersion in internal storage
 	Store([]models.ComponentVersion)
+	// CachedAt returns the time at which the internal cache was last stored. Returns the zero time
+	// if it never has been
+	CachedAt() time.Time
 }
 
 type availableVersionsFromAPI struct {
 	cache           []models.ComponentVersion
+	cachedAt        time.Time
 	rwm             *sync.RWMutex
 	baseVersionsURL string
-	apiClient       *apiclient.WorkflowManager
+	apiClient       upstream.Client
 }
 
 // NewAvailableVersionsFromAPI returns a new AvailableVersions implementation that fetches its version information from a workflow manager API. It uses baseVersionsURL as the server address. If that parameter is passed as the empty string, uses config.Spec.VersionsAPIURL
 func NewAvailableVersionsFromAPI(
-	apiClient *apiclient.WorkflowManager,
+	apiClient upstream.Client,
 	baseVersionsURL string,
 ) AvailableVersions {
 	if baseVersionsURL == "" {
@@ -43,23 +49,38 @@ func NewAvailableVersionsFromAPI(
 }
 
 // Refresh method for AvailableVersionsFromAPI
-func (a availableVersionsFromAPI) Refresh(cluster models.Cluster) ([]models.ComponentVersion, error) {
-	reqBody := operations.GetComponentsByLatestReleaseBody{}
+func (a *availableVersionsFromAPI) Refresh(ctx context.Context, cluster models.Cluster) ([]models.ComponentVersion, error) {
+	var components []*models.ComponentVersion
+	// components of the same name in different namespaces only need to be requested once per train
+	requested := make(map[string]bool)
 	for _, component := range cluster.Components {
+		key := component.Component.Name + " " + trainOf(*component)
+		if requested[key] {
+			continue
+		}
+		requested[key] = true
 		cv := new(models.ComponentVersion)
 		cv.Component = &models.Component{}
 		cv.Version = &models.Version{}
 		cv.Component.Name = component.Component.Name
-		cv.Version.Train = "stable"
-		reqBody.Data = append(reqBody.Data, cv)
+		cv.Version.Train = trainOf(*component)
+		components = append(components, cv)
 	}
 
-	resp, err := a.apiClient.Operations.GetComponentsByLatestRelease(&operations.GetComponentsByLatestReleaseParams{Body: reqBody})
+	latest, err := a.apiClient.GetLatestVersions(ctx, components)
 	if err != nil {
 		return []models.ComponentVersion{}, err
 	}
+	trains := componentNameTrains(cluster)
 	ret := []models.ComponentVersion{}
-	for _, cv := range resp.Payload.Data {
+	for _, cv := range latest {
+		// record the train that each version was requested for, in case the response omits it. That's
+		// ambiguous if components of its name follow several trains, so those are left without one
+		if cv.Version != nil && cv.Version.Train == "" && cv.Component != nil && len(trains[cv.Component.Name]) == 1 {
+			for train := range trains[cv.Component.Name] {
+				cv.Version.Train = train
+			}
+		}
 		ret = append(ret, *cv)
 	}
 	a.Store(ret)
@@ -78,4 +99,12 @@ func (a *availableVersionsFromAPI) Store(c []models.ComponentVersion) {
 	a.rwm.Lock()
 	defer a.rwm.Unlock()
 	a.cache = c
+	a.cachedAt = time.Now()
+}
+
+// CachedAt is the AvailableVersions interface implementation
+func (a availableVersionsFromAPI) CachedAt() time.Time {
+	a.rwm.RLock()
+	defer a.rwm.RUnlock()
+	return a.cachedAt
 }
@@ -0,0 +1,163 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	apierrors "k8s.io/kubernetes/pkg/api/errors"
+)
+
+const (
+	versionsCacheSecretName = "deis-workflow-manager-versions"
+	versionsCacheKey        = "versions"
+	versionsFetchedAtKey    = "fetched-at"
+)
+
+// VersionsCacheStore is an interface for persisting available component versions across restarts
+type VersionsCacheStore interface {
+	// Load returns the stored component versions and the time at which they were fetched. Returns
+	// a nil slice if nothing has been stored
+	Load() ([]models.ComponentVersion, time.Time, error)
+	// Save stores the given component versions, fetched at the given time
+	Save([]models.ComponentVersion, time.Time) error
+}
+
+// secretVersionsCacheStore fulfills the VersionsCacheStore interface using a kubernetes secret
+type secretVersionsCacheStore struct {
+	secrets k8s.KubeSecretGetterCreatorUpdater
+}
+
+// NewVersionsCacheStoreFromSecret returns a new VersionsCacheStore that persists component versions
+// in a secret next to the deis-workflow-manager secret, using secrets to get, create and update it
+func NewVersionsCacheStoreFromSecret(secrets k8s.KubeSecretGetterCreatorUpdater) VersionsCacheStore {
+	return &secretVersionsCacheStore{secrets: secrets}
+}
+
+// Load is the VersionsCacheStore interface implementation
+func (s *secretVersionsCacheStore) Load() ([]models.ComponentVersion, time.Time, error) {
+	secret, err := s.secrets.Get(versionsCacheSecretName)
+	if apierrors.IsNotFound(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if secret.Data[versionsCacheKey] == nil {
+		return nil, time.Time{}, nil
+	}
+	var versions []models.ComponentVersion
+	if err := json.Unmarshal(secret.Data[versionsCacheKey], &versions); err != nil {
+		return nil, time.Time{}, err
+	}
+	fetchedAt, err := time.Parse(time.RFC3339, string(secret.Data[versionsFetchedAtKey]))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return versions, fetchedAt, nil
+}
+
+// Save is the VersionsCacheStore interface implementation
+func (s *secretVersionsCacheStore) Save(versions []models.ComponentVersion, fetchedAt time.Time) error {
+	js, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	secret, err := s.secrets.Get(versionsCacheSecretName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err != nil || secret == nil {
+		newSecret := new(api.Secret)
+		newSecret.Name = versionsCacheSecretName
+		newSecret.Data = map[string][]byte{
+			versionsCacheKey:     js,
+			versionsFetchedAtKey: []byte(fetchedAt.UTC().Format(time.RFC3339)),
+		}
+		_, err := s.secrets.Create(newSecret)
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[versionsCacheKey] = js
+	secret.Data[versionsFetchedAtKey] = []byte(fetchedAt.UTC().Format(time.RFC3339))
+	_, err = s.secrets.Update(secret)
+	return err
+}
+
+// persistentAvailableVersions fulfills the AvailableVersions interface by wrapping another
+// AvailableVersions, and saving every successful refresh to a VersionsCacheStore
+type persistentAvailableVersions struct {
+	AvailableVersions
+	store     VersionsCacheStore
+	rwm       *sync.RWMutex
+	fetchedAt time.Time
+}
+
+// NewPersistentAvailableVersions returns a new AvailableVersions that delegates to av, and saves the
+// result of every successful Refresh to store. Any versions already in store are loaded into av's
+// cache, and CachedAt reports the time at which they were originally fetched. If they can't be
+// loaded, the returned AvailableVersions is still usable and the load error is returned with it
+func NewPersistentAvailableVersions(av AvailableVersions, store VersionsCacheStore) (AvailableVersions, error) {
+	p := &persistentAvailableVersions{
+		AvailableVersions: av,
+		store:             store,
+		rwm:               new(sync.RWMutex),
+	}
+	versions, fetchedAt, err := store.Load()
+	if err != nil {
+		return p, err
+	}
+	if len(versions) > 0 {
+		av.Store(versions)
+		p.fetchedAt = fetchedAt
+	}
+	return p, nil
+}
+
+// Refresh is the AvailableVersions interface implementation
+func (p *persistentAvailableVersions) Refresh(ctx context.Context, cluster models.Cluster) ([]models.ComponentVersion, error) {
+	versions, err := p.AvailableVersions.Refresh(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	p.setFetchedAt(now)
+	if err := p.store.Save(versions, now); err != nil {
+		logger.FromContext(ctx).WithError(err).Warnf("unable to persist available versions cache")
+	}
+	return versions, nil
+}
+
+// Store is the AvailableVersions interface implementation
+func (p *persistentAvailableVersions) Store(c []models.ComponentVersion) {
+	p.AvailableVersions.Store(c)
+	p.setFetchedAt(time.Now())
+}
+
+// CachedAt is the AvailableVersions interface implementation. It returns the time at which the
+// cached versions were fetched, which may precede the current process
+func (p *persistentAvailableVersions) CachedAt() time.Time {
+	p.rwm.RLock()
+	defer p.rwm.RUnlock()
+	return p.fetchedAt
+}
+
+func (p *persistentAvailableVersions) setFetchedAt(t time.Time) {
+	p.rwm.Lock()
+	defer p.rwm.Unlock()
+	p.fetchedAt = t
+}
+
+// VersionsAreStale returns true if the versions cached by a were fetched more than maxAge ago. It
+// returns false if nothing has been cached yet
+func VersionsAreStale(a AvailableVersions, maxAge time.Duration) bool {
+	cachedAt := a.CachedAt()
+	return !cachedAt.IsZero() && time.Since(cachedAt) > maxAge
+}
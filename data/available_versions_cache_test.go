@@ -0,0 +1,130 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	apierrors "k8s.io/kubernetes/pkg/api/errors"
+)
+
+// Creating a novel mock struct that fulfills the k8s.KubeSecretGetterCreatorUpdater interface,
+// storing secrets in memory
+type mockSecrets struct {
+	secrets map[string]*api.Secret
+	updates int
+}
+
+func (m *mockSecrets) Get(name string) (*api.Secret, error) {
+	sec, ok := m.secrets[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(api.Resource("secrets"), name)
+	}
+	return sec, nil
+}
+
+func (m *mockSecrets) Create(sec *api.Secret) (*api.Secret, error) {
+	m.secrets[sec.Name] = sec
+	return sec, nil
+}
+
+func (m *mockSecrets) Update(sec *api.Secret) (*api.Secret, error) {
+	m.updates++
+	m.secrets[sec.Name] = sec
+	return sec, nil
+}
+
+// Creating a novel mock struct that fulfills the VersionsCacheStore interface
+type mockVersionsCacheStore struct {
+	versions  []models.ComponentVersion
+	fetchedAt time.Time
+}
+
+func (m *mockVersionsCacheStore) Load() ([]models.ComponentVersion, time.Time, error) {
+	return m.versions, m.fetchedAt, nil
+}
+
+func (m *mockVersionsCacheStore) Save(versions []models.ComponentVersion, fetchedAt time.Time) error {
+	m.versions = versions
+	m.fetchedAt = fetchedAt
+	return nil
+}
+
+// Creating a novel mock struct that fulfills the AvailableVersions interface, caching in memory
+type memoryAvailableVersions struct {
+	cache    []models.ComponentVersion
+	cachedAt time.Time
+}
+
+func (a *memoryAvailableVersions) Refresh(ctx context.Context, cluster models.Cluster) ([]models.ComponentVersion, error) {
+	vsns := getCachedComponentVersions()
+	a.Store(vsns)
+	return vsns, nil
+}
+
+func (a *memoryAvailableVersions) Store(c []models.ComponentVersion) {
+	a.cache = c
+	a.cachedAt = time.Now()
+}
+
+func (a *memoryAvailableVersions) Cached() []models.ComponentVersion {
+	return a.cache
+}
+
+func (a *memoryAvailableVersions) CachedAt() time.Time {
+	return a.cachedAt
+}
+
+func TestVersionsCacheStoreFromSecret(t *testing.T) {
+	secrets := &mockSecrets{secrets: make(map[string]*api.Secret)}
+	store := NewVersionsCacheStoreFromSecret(secrets)
+	versions, fetchedAt, err := store.Load()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(versions), 0, "number of versions before the first save")
+	assert.True(t, fetchedAt.IsZero(), "fetched-at time was set before the first save")
+
+	fetched := time.Date(2016, time.June, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoErr(t, store.Save(getCachedComponentVersions(), fetched))
+	assert.Equal(t, len(secrets.secrets), 1, "number of secrets")
+	versions, fetchedAt, err = store.Load()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(versions), 1, "number of versions")
+	assert.Equal(t, versions[0].Version.Version, "v2-beta", "stored version")
+	assert.True(t, fetchedAt.Equal(fetched), "fetched-at time %s, expected %s", fetchedAt, fetched)
+
+	// a second save updates the existing secret
+	assert.NoErr(t, store.Save(getCachedComponentVersions(), fetched.Add(time.Hour)))
+	assert.Equal(t, secrets.updates, 1, "number of secret updates")
+	_, fetchedAt, err = store.Load()
+	assert.NoErr(t, err)
+	assert.True(t, fetchedAt.Equal(fetched.Add(time.Hour)), "fetched-at time %s was not updated", fetchedAt)
+}
+
+func TestPersistentAvailableVersions(t *testing.T) {
+	fetched := time.Now().Add(-48 * time.Hour)
+	store := &mockVersionsCacheStore{versions: getCachedComponentVersions(), fetchedAt: fetched}
+	vsns, err := NewPersistentAvailableVersions(&memoryAvailableVersions{}, store)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(vsns.Cached()), 1, "number of cached versions loaded at boot")
+	assert.True(t, vsns.CachedAt().Equal(fetched), "cached-at time %s, expected %s", vsns.CachedAt(), fetched)
+	assert.True(t, VersionsAreStale(vsns, 24*time.Hour), "versions fetched %s were not stale", fetched)
+	assert.False(t, VersionsAreStale(vsns, 72*time.Hour), "versions fetched %s were stale", fetched)
+
+	_, err = vsns.Refresh(context.Background(), models.Cluster{})
+	assert.NoErr(t, err)
+	assert.True(t, store.fetchedAt.After(fetched), "refreshed versions were not persisted")
+	assert.False(t, VersionsAreStale(vsns, 24*time.Hour), "refreshed versions were stale")
+}
+
+func getCachedComponentVersions() []models.ComponentVersion {
+	desc := mockComponentDescription
+	return []models.ComponentVersion{
+		{
+			Component: &models.Component{Name: mockComponentName, Description: &desc},
+			Version:   &models.Version{Version: mockComponentVersion},
+		},
+	}
+}
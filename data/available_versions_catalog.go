@@ -0,0 +1,229 @@
+package data
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-manager/data/semver"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+const (
+	catalogSignatureSuffix = ".sig"
+	catalogFetchTimeout    = 30 * time.Second
+)
+
+// availableVersionsFromCatalog fulfills the AvailableVersions interface using a signed catalog of
+// component versions, for clusters that can't reach the versions API
+type availableVersionsFromCatalog struct {
+	cache        []models.ComponentVersion
+	cachedAt     time.Time
+	rwm          *sync.RWMutex
+	catalogURL   string
+	signatureURL string
+	publicKey    crypto.PublicKey
+	httpClient   *http.Client
+}
+
+// NewAvailableVersionsFromCatalog returns a new AvailableVersions implementation that reads its
+// version information from the catalog at catalogURL, which may be a local file path (e.g. a
+// mounted ConfigMap), a file:// URL or an http(s):// URL. The catalog is a JSON
+// ComponentVersionsJSONWrapper. Its detached, base64 encoded SHA-256 signature is read from
+// signatureURL, or from catalogURL+".sig" if signatureURL is empty, and verified using publicKey.
+// If publicKey is nil, the signature is not verified
+func NewAvailableVersionsFromCatalog(
+	catalogURL string,
+	signatureURL string,
+	publicKey crypto.PublicKey,
+) AvailableVersions {
+	if signatureURL == "" {
+		signatureURL = catalogURL + catalogSignatureSuffix
+	}
+	return &availableVersionsFromCatalog{
+		rwm:          new(sync.RWMutex),
+		catalogURL:   catalogURL,
+		signatureURL: signatureURL,
+		publicKey:    publicKey,
+		httpClient:   &http.Client{Timeout: catalogFetchTimeout},
+	}
+}
+
+// ParseCatalogPublicKey parses a PEM encoded PKIX RSA or ECDSA public key, for use with
+// NewAvailableVersionsFromCatalog
+func ParseCatalogPublicKey(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM data found in catalog public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported catalog public key type %T", key)
+}
+
+// Refresh is the AvailableVersions interface implementation. It returns the newest version in the
+// catalog of each component listed in the given cluster, on the release train that it follows
+func (a *availableVersionsFromCatalog) Refresh(ctx context.Context, cluster models.Cluster) ([]models.ComponentVersion, error) {
+	catalog, err := a.fetch(ctx, a.catalogURL)
+	if err != nil {
+		return nil, fmt.Errorf("reading version catalog %s (%s)", a.catalogURL, err)
+	}
+	if a.publicKey != nil {
+		sig, err := a.fetch(ctx, a.signatureURL)
+		if err != nil {
+			return nil, fmt.Errorf("reading version catalog signature %s (%s)", a.signatureURL, err)
+		}
+		if err := verifyCatalogSignature(a.publicKey, catalog, sig); err != nil {
+			return nil, err
+		}
+	}
+	var wrapper ComponentVersionsJSONWrapper
+	if err := json.Unmarshal(catalog, &wrapper); err != nil {
+		return nil, fmt.Errorf("parsing version catalog %s (%s)", a.catalogURL, err)
+	}
+	ret := newestCatalogVersions(wrapper.Data, cluster)
+	a.Store(ret)
+	return ret, nil
+}
+
+// Cached is the AvailableVersions interface implementation
+func (a *availableVersionsFromCatalog) Cached() []models.ComponentVersion {
+	a.rwm.RLock()
+	defer a.rwm.RUnlock()
+	return a.cache
+}
+
+// Store is the AvailableVersions interface implementation
+func (a *availableVersionsFromCatalog) Store(c []models.ComponentVersion) {
+	a.rwm.Lock()
+	defer a.rwm.Unlock()
+	a.cache = c
+	a.cachedAt = time.Now()
+}
+
+// CachedAt is the AvailableVersions interface implementation
+func (a *availableVersionsFromCatalog) CachedAt() time.Time {
+	a.rwm.RLock()
+	defer a.rwm.RUnlock()
+	return a.cachedAt
+}
+
+// fetch reads the contents of a local file path, file:// URL or http(s):// URL
+func (a *availableVersionsFromCatalog) fetch(ctx context.Context, location string) ([]byte, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "":
+		return ioutil.ReadFile(location)
+	case "file":
+		return ioutil.ReadFile(u.Path)
+	case "http", "https":
+		req, err := http.NewRequest("GET", location, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := a.httpClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected response status %s", resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+	return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+}
+
+// ecdsaSignature is the ASN.1 structure of an ECDSA signature, as produced by
+// "openssl dgst -sha256 -sign"
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// verifyCatalogSignature verifies that the base64 encoded sig is a signature of the SHA-256 digest
+// of catalog by the private key corresponding to pub
+func verifyCatalogSignature(pub crypto.PublicKey, catalog, sig []byte) error {
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("decoding version catalog signature (%s)", err)
+	}
+	digest := sha256.Sum256(catalog)
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], rawSig); err != nil {
+			return fmt.Errorf("invalid version catalog signature (%s)", err)
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		var es ecdsaSignature
+		if _, err := asn1.Unmarshal(rawSig, &es); err != nil {
+			return fmt.Errorf("decoding version catalog signature (%s)", err)
+		}
+		if !ecdsa.Verify(key, digest[:], es.R, es.S) {
+			return errors.New("invalid version catalog signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported catalog public key type %T", pub)
+}
+
+// newestCatalogVersions returns the newest version in catalog of each component in cluster, on each
+// release train that components of its name follow. Catalog entries without a train are considered
+// to be stable
+func newestCatalogVersions(catalog []models.ComponentVersion, cluster models.Cluster) []models.ComponentVersion {
+	trains := componentNameTrains(cluster)
+	newest := make(map[string]models.ComponentVersion)
+	var keys []string
+	for _, cv := range catalog {
+		if cv.Component == nil || cv.Version == nil {
+			continue
+		}
+		cvTrain := cv.Version.Train
+		if cvTrain == "" {
+			cvTrain = stableTrain
+		}
+		if !trains[cv.Component.Name][cvTrain] {
+			continue
+		}
+		key := cv.Component.Name + " " + cvTrain
+		prev, ok := newest[key]
+		if !ok {
+			keys = append(keys, key)
+			newest[key] = cv
+			continue
+		}
+		if n, err := semver.Newest(prev.Version.Version, cv.Version.Version); err == nil && n != prev.Version.Version {
+			newest[key] = cv
+		}
+	}
+	ret := make([]models.ComponentVersion, 0, len(keys))
+	for _, key := range keys {
+		ret = append(ret, newest[key])
+	}
+	return ret
+}
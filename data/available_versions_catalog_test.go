@@ -0,0 +1,139 @@
+package data
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+const mockCatalog = `{
+  "data": [
+    {"component": {"name": "controller"}, "version": {"train": "stable", "version": "2.9.0"}},
+    {"component": {"name": "controller"}, "version": {"train": "stable", "version": "2.10.0"}},
+    {"component": {"name": "controller"}, "version": {"train": "beta", "version": "2.11.0-beta1"}},
+    {"component": {"name": "router"}, "version": {"train": "stable", "version": "2.3.0"}},
+    {"component": {"name": "not-installed"}, "version": {"train": "stable", "version": "1.0.0"}}
+  ]
+}`
+
+func TestAvailableVersionsFromCatalogFile(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoErr(t, err)
+	digest := sha256.Sum256([]byte(mockCatalog))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	assert.NoErr(t, err)
+	sig, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+	assert.NoErr(t, err)
+	pub := parseTestPublicKey(t, &key.PublicKey)
+
+	dir, err := ioutil.TempDir("", "catalog")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+	catalogPath := filepath.Join(dir, "catalog.json")
+	assert.NoErr(t, ioutil.WriteFile(catalogPath, []byte(mockCatalog), 0644))
+	assert.NoErr(t, ioutil.WriteFile(catalogPath+".sig", []byte(base64.StdEncoding.EncodeToString(sig)), 0644))
+
+	vsns := NewAvailableVersionsFromCatalog(catalogPath, "", pub)
+	assert.True(t, vsns.CachedAt().IsZero(), "cache time was set before the first refresh")
+	compVsns, err := vsns.Refresh(context.Background(), getCatalogTestCluster())
+	assert.NoErr(t, err)
+	assert.Equal(t, len(compVsns), 2, "number of component versions")
+	assert.Equal(t, compVsns[0].Component.Name, "controller", "first component name")
+	assert.Equal(t, compVsns[0].Version.Version, "2.10.0", "newest stable controller version")
+	assert.Equal(t, compVsns[1].Component.Name, "router", "second component name")
+	assert.Equal(t, len(vsns.Cached()), 2, "number of cached component versions")
+	assert.False(t, vsns.CachedAt().IsZero(), "cache time was not set by refresh")
+
+	// components on the beta train get the newest beta version
+	betaCluster := getCatalogTestCluster()
+	betaCluster.Components[0].Version.Train = "beta"
+	compVsns, err = vsns.Refresh(context.Background(), betaCluster)
+	assert.NoErr(t, err)
+	assert.Equal(t, compVsns[0].Version.Version, "2.11.0-beta1", "newest beta controller version")
+	assert.Equal(t, compVsns[1].Version.Version, "2.3.0", "newest stable router version")
+
+	// a controller on each train, in different namespaces, gets the newest version of each
+	staging := "deis-staging"
+	betaCluster.Components = append(betaCluster.Components, &models.ComponentVersion{
+		Component: &models.Component{Name: "controller", Namespace: &staging},
+		Version:   &models.Version{Version: "2.9.0"},
+	})
+	compVsns, err = vsns.Refresh(context.Background(), betaCluster)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(compVsns), 3, "number of component versions")
+	assert.Equal(t, compVsns[0].Version.Version, "2.10.0", "newest stable controller version")
+	assert.Equal(t, compVsns[1].Version.Version, "2.11.0-beta1", "newest beta controller version")
+
+	// a tampered catalog must be rejected
+	assert.NoErr(t, ioutil.WriteFile(catalogPath, []byte(mockCatalog+" "), 0644))
+	_, err = vsns.Refresh(context.Background(), getCatalogTestCluster())
+	assert.True(t, err != nil, "expected an error for a tampered catalog")
+}
+
+func TestAvailableVersionsFromCatalogURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoErr(t, err)
+	digest := sha256.Sum256([]byte(mockCatalog))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	assert.NoErr(t, err)
+	pub := parseTestPublicKey(t, &key.PublicKey)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/catalog.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mockCatalog))
+	})
+	mux.HandleFunc("/signature", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(sig)))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	vsns := NewAvailableVersionsFromCatalog(ts.URL+"/catalog.json", ts.URL+"/signature", pub)
+	compVsns, err := vsns.Refresh(context.Background(), getCatalogTestCluster())
+	assert.NoErr(t, err)
+	assert.Equal(t, len(compVsns), 2, "number of component versions")
+
+	// the default signature URL doesn't exist on the test server
+	vsns = NewAvailableVersionsFromCatalog(ts.URL+"/catalog.json", "", pub)
+	_, err = vsns.Refresh(context.Background(), getCatalogTestCluster())
+	assert.True(t, err != nil, "expected an error for a missing signature")
+
+	// verification can be disabled
+	vsns = NewAvailableVersionsFromCatalog(ts.URL+"/catalog.json", "", nil)
+	compVsns, err = vsns.Refresh(context.Background(), getCatalogTestCluster())
+	assert.NoErr(t, err)
+	assert.Equal(t, len(compVsns), 2, "number of component versions")
+}
+
+func parseTestPublicKey(t *testing.T, pub interface{}) crypto.PublicKey {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NoErr(t, err)
+	parsed, err := ParseCatalogPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoErr(t, err)
+	return parsed
+}
+
+func getCatalogTestCluster() models.Cluster {
+	return models.Cluster{
+		Components: []*models.ComponentVersion{
+			{Component: &models.Component{Name: "controller"}, Version: &models.Version{Version: "2.9.0"}},
+			{Component: &models.Component{Name: "router"}, Version: &models.Version{Version: "2.3.0"}},
+		},
+	}
+}
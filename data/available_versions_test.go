@@ -1,23 +1,26 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/arschles/assert"
 	"github.com/deis/workflow-manager/config"
 	"github.com/deis/workflow-manager/pkg/swagger/client/operations"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"github.com/deis/workflow-manager/upstream"
 )
 
 // Creating a novel mock struct that fulfills the AvailableVersions interface
 type testAvailableVersions struct{}
 
-func (a testAvailableVersions) Refresh(cluster models.Cluster) ([]models.ComponentVersion, error) {
+func (a testAvailableVersions) Refresh(ctx context.Context, cluster models.Cluster) ([]models.ComponentVersion, error) {
 	data := getMockComponentVersions()
 	var componentVersions []models.ComponentVersion
 	if err := json.Unmarshal(data, &componentVersions); err != nil {
@@ -34,10 +37,14 @@ func (a testAvailableVersions) Cached() []models.ComponentVersion {
 	return nil
 }
 
+func (a testAvailableVersions) CachedAt() time.Time {
+	return time.Time{}
+}
+
 // Creating another mock struct that fulfills the AvailableVersions interface
 type shouldBypassAvailableVersions struct{}
 
-func (a shouldBypassAvailableVersions) Refresh(cluster models.Cluster) ([]models.ComponentVersion, error) {
+func (a shouldBypassAvailableVersions) Refresh(ctx context.Context, cluster models.Cluster) ([]models.ComponentVersion, error) {
 	var componentVersions []models.ComponentVersion
 	data := []byte(fmt.Sprintf(`[{
 	  "components": [
@@ -66,6 +73,10 @@ func (a shouldBypassAvailableVersions) Cached() []models.ComponentVersion {
 	return nil
 }
 
+func (a shouldBypassAvailableVersions) CachedAt() time.Time {
+	return time.Time{}
+}
+
 // Calls GetAvailableVersions twice, the first time we expect our passed-in struct w/ Refresh() method
 // to be invoked, the 2nd time we expect to receive the same value back (cached in memory)
 // and for the passed-in Refresh() method to be ignored
@@ -73,10 +84,10 @@ func TestGetAvailableVersions(t *testing.T) {
 	mock := getMockComponentVersions()
 	var mockVersions []models.ComponentVersion
 	assert.NoErr(t, json.Unmarshal(mock, &mockVersions))
-	versions, err := GetAvailableVersions(testAvailableVersions{}, models.Cluster{})
+	versions, err := GetAvailableVersions(context.Background(), testAvailableVersions{}, models.Cluster{})
 	assert.NoErr(t, err)
 	assert.Equal(t, versions, mockVersions, "component versions data")
-	versions, err = GetAvailableVersions(shouldBypassAvailableVersions{}, models.Cluster{})
+	versions, err = GetAvailableVersions(context.Background(), shouldBypassAvailableVersions{}, models.Cluster{})
 	assert.NoErr(t, err)
 	assert.Equal(t, versions, mockVersions, "component versions data")
 }
@@ -103,12 +114,17 @@ func TestRefreshAvailableVersions(t *testing.T) {
 	defer ts.Close()
 	apiclient, err := config.GetSwaggerClient(ts.URL)
 	assert.NoErr(t, err)
+	upstreamClient, err := upstream.NewClient(apiclient, upstream.APIVersion3)
+	assert.NoErr(t, err)
 	vsns := availableVersionsFromAPI{
 		rwm:             new(sync.RWMutex),
 		baseVersionsURL: ts.URL,
-		apiClient:       apiclient,
+		apiClient:       upstreamClient,
 	}
-	retCompVsns, err := vsns.Refresh(models.Cluster{})
+	assert.True(t, vsns.CachedAt().IsZero(), "cache time was set before the first refresh")
+	retCompVsns, err := vsns.Refresh(context.Background(), models.Cluster{})
 	assert.NoErr(t, err)
 	assert.Equal(t, len(retCompVsns), len(expectedCompVsns.Data), "number of component versions")
+	assert.Equal(t, len(vsns.Cached()), len(expectedCompVsns.Data), "number of cached component versions")
+	assert.False(t, vsns.CachedAt().IsZero(), "cache time was not set by refresh")
 }
@@ -19,7 +19,7 @@ type ClusterID interface {
 	StoreInCache(string)
 }
 
-// GetID gets the cluster ID from the cache. on a cache miss, uses the k8s API to get it
+// GetID gets the cluster ID from the cache. on a cache miss, uses the k8s API to get it and stores it in the cache
 func GetID(id ClusterID) (string, error) {
 	// First, check to see if we have an in-memory copy
 	data := id.Cached()
@@ -29,6 +29,7 @@ func GetID(id ClusterID) (string, error) {
 		if err != nil {
 			return "", err
 		}
+		id.StoreInCache(d)
 		data = d
 	}
 	return data, nil
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+)
+
+// the rollout states of a Deployment
+const (
+	rolloutComplete    = "complete"
+	rolloutProgressing = "progressing"
+	rolloutPaused      = "paused"
+)
+
+// podStatus returns a models.ComponentStatus with the number of pods that are ready, and the
+// restarts of their containers, summed across pods. pods should be the pods selected by the
+// component's workload
+func podStatus(pods []api.Pod) *models.ComponentStatus {
+	status := &models.ComponentStatus{}
+	for _, pod := range pods {
+		if podReady(pod) {
+			status.Ready++
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			status.Restarts += int64(cs.RestartCount)
+		}
+	}
+	return status
+}
+
+// deploymentStatus returns the status of deployment, whose pods are pods
+func deploymentStatus(deployment extensions.Deployment, pods []api.Pod) *models.ComponentStatus {
+	status := podStatus(pods)
+	status.Desired = int64(deployment.Spec.Replicas)
+	status.Available = int64(deployment.Status.AvailableReplicas)
+	status.Updated = int64(deployment.Status.UpdatedReplicas)
+	switch {
+	case deployment.Spec.Paused:
+		status.Rollout = rolloutPaused
+	// the deployment controller hasn't seen the latest spec yet
+	case deployment.Status.ObservedGeneration < deployment.Generation,
+		status.Updated < status.Desired,
+		// old replicas are still being scaled down
+		int64(deployment.Status.Replicas) > status.Updated,
+		status.Available < status.Updated:
+		status.Rollout = rolloutProgressing
+	default:
+		status.Rollout = rolloutComplete
+	}
+	status.Healthy = status.Rollout == rolloutComplete && status.Ready >= status.Desired
+	return status
+}
+
+// daemonSetStatus returns the status of daemonSet, whose pods are pods
+func daemonSetStatus(daemonSet extensions.DaemonSet, pods []api.Pod) *models.ComponentStatus {
+	status := podStatus(pods)
+	status.Desired = int64(daemonSet.Status.DesiredNumberScheduled)
+	status.Scheduled = int64(daemonSet.Status.CurrentNumberScheduled)
+	status.Misscheduled = int64(daemonSet.Status.NumberMisscheduled)
+	status.Available = status.Ready
+	status.Healthy = status.Scheduled == status.Desired && status.Ready >= status.Desired && status.Misscheduled == 0
+	return status
+}
+
+// replicasStatus returns the status of a Replica Set or a Replication Controller with desired
+// replicas, whose pods are pods. Neither reports its available replicas, so its ready pods are
+// counted as available
+func replicasStatus(desired int64, pods []api.Pod) *models.ComponentStatus {
+	status := podStatus(pods)
+	status.Desired = desired
+	status.Available = status.Ready
+	status.Healthy = status.Ready >= status.Desired
+	return status
+}
+
+// jobStatus returns the status of job, whose pods are pods. A job's desired replicas are its
+// completions, and its available replicas are the pods that have succeeded. A job is healthy until
+// one of its pods fails
+func jobStatus(job extensions.Job, pods []api.Pod) *models.ComponentStatus {
+	status := podStatus(pods)
+	status.Desired = 1
+	if job.Spec.Completions != nil {
+		status.Desired = int64(*job.Spec.Completions)
+	}
+	status.Available = int64(job.Status.Succeeded)
+	status.Healthy = job.Status.Failed == 0
+	return status
+}
+
+// podReady returns true if pod is running and has a true Ready condition
+func podReady(pod api.Pod) bool {
+	if pod.Status.Phase != api.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == api.PodReady {
+			return cond.Status == api.ConditionTrue
+		}
+	}
+	return false
+}
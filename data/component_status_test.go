@@ -0,0 +1,108 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+)
+
+func getTestReadyPod(app string, ready bool, restarts int) api.Pod {
+	pod := getTestPod(app, "quay.io/deis/"+app+":v2.3.0", "")
+	pod.Status.Phase = api.PodRunning
+	condition := api.ConditionFalse
+	if ready {
+		condition = api.ConditionTrue
+	}
+	pod.Status.Conditions = []api.PodCondition{{Type: api.PodReady, Status: condition}}
+	pod.Status.ContainerStatuses[0].RestartCount = restarts
+	return pod
+}
+
+func TestPodStatus(t *testing.T) {
+	pending := getTestPod("deis-router", "quay.io/deis/router:v2.3.0", "")
+	status := podStatus([]api.Pod{
+		getTestReadyPod("deis-router", true, 2),
+		getTestReadyPod("deis-router", false, 3),
+		pending,
+	})
+	assert.Equal(t, status.Ready, int64(1), "ready pods")
+	assert.Equal(t, status.Restarts, int64(5), "restarts")
+}
+
+func TestDeploymentStatus(t *testing.T) {
+	deployment := getTestDeployment("deis-router", "quay.io/deis/router:v2.3.0")
+	deployment.Generation = 2
+	deployment.Spec.Replicas = 2
+	deployment.Status = extensions.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           2,
+		UpdatedReplicas:    2,
+		AvailableReplicas:  2,
+	}
+	pods := []api.Pod{getTestReadyPod("deis-router", true, 0), getTestReadyPod("deis-router", true, 1)}
+	status := deploymentStatus(deployment, pods)
+	assert.Equal(t, status.Rollout, rolloutComplete, "rollout")
+	assert.Equal(t, status.Desired, int64(2), "desired replicas")
+	assert.Equal(t, status.Available, int64(2), "available replicas")
+	assert.Equal(t, status.Restarts, int64(1), "restarts")
+	assert.True(t, status.Healthy, "rolled out deployment was not healthy")
+
+	// an old replica is still being scaled down
+	deployment.Status.Replicas = 3
+	status = deploymentStatus(deployment, pods)
+	assert.Equal(t, status.Rollout, rolloutProgressing, "rollout")
+	assert.False(t, status.Healthy, "progressing deployment was healthy")
+
+	// the deployment controller hasn't observed the latest spec
+	deployment.Status.Replicas = 2
+	deployment.Generation = 3
+	status = deploymentStatus(deployment, pods)
+	assert.Equal(t, status.Rollout, rolloutProgressing, "rollout")
+
+	deployment.Spec.Paused = true
+	status = deploymentStatus(deployment, pods)
+	assert.Equal(t, status.Rollout, rolloutPaused, "rollout")
+	assert.False(t, status.Healthy, "paused deployment was healthy")
+}
+
+func TestDaemonSetStatus(t *testing.T) {
+	daemonSet := extensions.DaemonSet{
+		Status: extensions.DaemonSetStatus{DesiredNumberScheduled: 2, CurrentNumberScheduled: 2},
+	}
+	pods := []api.Pod{getTestReadyPod("deis-logger-fluentd", true, 0), getTestReadyPod("deis-logger-fluentd", true, 0)}
+	status := daemonSetStatus(daemonSet, pods)
+	assert.Equal(t, status.Scheduled, int64(2), "scheduled pods")
+	assert.Equal(t, status.Available, int64(2), "available pods")
+	assert.True(t, status.Healthy, "fully scheduled daemon set was not healthy")
+
+	daemonSet.Status.NumberMisscheduled = 1
+	status = daemonSetStatus(daemonSet, pods)
+	assert.Equal(t, status.Misscheduled, int64(1), "misscheduled pods")
+	assert.False(t, status.Healthy, "misscheduled daemon set was healthy")
+}
+
+func TestReplicasStatus(t *testing.T) {
+	pods := []api.Pod{getTestReadyPod("deis-registry-proxy", true, 0), getTestReadyPod("deis-registry-proxy", false, 0)}
+	status := replicasStatus(2, pods)
+	assert.Equal(t, status.Available, int64(1), "available replicas")
+	assert.False(t, status.Healthy, "under replicated component was healthy")
+	status = replicasStatus(1, pods)
+	assert.True(t, status.Healthy, "fully replicated component was not healthy")
+}
+
+func TestJobStatus(t *testing.T) {
+	job := extensions.Job{Status: extensions.JobStatus{Succeeded: 1}}
+	status := jobStatus(job, nil)
+	assert.Equal(t, status.Desired, int64(1), "desired completions")
+	assert.Equal(t, status.Available, int64(1), "succeeded pods")
+	assert.True(t, status.Healthy, "succeeded job was not healthy")
+
+	completions := 3
+	job.Spec.Completions = &completions
+	job.Status.Failed = 1
+	status = jobStatus(job, nil)
+	assert.Equal(t, status.Desired, int64(3), "desired completions")
+	assert.False(t, status.Healthy, "failed job was healthy")
+}
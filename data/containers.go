@@ -0,0 +1,170 @@
+package data
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/labels"
+)
+
+// the annotations that carry init containers and their statuses, before they became first class
+// pod spec fields. The beta annotations take precedence over the alpha ones
+var (
+	initContainersAnnotations        = []string{"pod.beta.kubernetes.io/init-containers", "pod.alpha.kubernetes.io/init-containers"}
+	initContainerStatusesAnnotations = []string{"pod.beta.kubernetes.io/init-container-statuses", "pod.alpha.kubernetes.io/init-container-statuses"}
+)
+
+// imageIDPrefixes are the runtime specific prefixes of the image IDs found in container statuses
+var imageIDPrefixes = []string{"docker-pullable://", "docker://", "rkt://"}
+
+// containerImages returns a models.ContainerImage for each init container and container in
+// template. pods should be the pods selected by the template's owner. Unless the template pins a
+// digest, each container's digest is resolved from the pods' statuses. A container is up to date if
+// all of pods run the template's image, and resolve it to the same digest, or to the pinned one.
+// Whether it's up to date is unknown, and left unset, if there are no pods
+func containerImages(template api.PodTemplateSpec, pods []api.Pod) []*models.ContainerImage {
+	var ret []*models.ContainerImage
+	for _, c := range initContainers(template.Annotations) {
+		ret = append(ret, containerImage(c, true, pods))
+	}
+	for _, c := range template.Spec.Containers {
+		ret = append(ret, containerImage(c, false, pods))
+	}
+	return ret
+}
+
+// containerImage returns the models.ContainerImage for container c, as run by pods
+func containerImage(c api.Container, init bool, pods []api.Pod) *models.ContainerImage {
+	_, tag, digest := splitImage(c.Image)
+	ci := &models.ContainerImage{
+		Name:   c.Name,
+		Image:  c.Image,
+		Tag:    tag,
+		Digest: digest,
+		Init:   init,
+	}
+	if len(pods) == 0 {
+		return ci
+	}
+	upToDate := true
+	// a pinned digest is what every pod should resolve the image to
+	resolved := digest
+	for _, pod := range pods {
+		podContainers, statuses := pod.Spec.Containers, pod.Status.ContainerStatuses
+		if init {
+			podContainers, statuses = initContainers(pod.Annotations), initContainerStatuses(pod.Annotations)
+		}
+		podContainer, ok := findContainer(podContainers, c.Name)
+		if !ok || podContainer.Image != c.Image {
+			upToDate = false
+			continue
+		}
+		for _, status := range statuses {
+			if status.Name != c.Name || status.ImageID == "" {
+				continue
+			}
+			podDigest := imageIDDigest(status.ImageID)
+			if resolved == "" {
+				resolved = podDigest
+			} else if podDigest != resolved {
+				// the image resolved to a digest other than the pinned one, or to different digests on
+				// different nodes
+				upToDate = false
+			}
+		}
+	}
+	ci.Digest = resolved
+	ci.UpToDate = &upToDate
+	return ci
+}
+
+// selectPods returns the pods in namespace that match selector
+func selectPods(pods []api.Pod, namespace string, selector labels.Selector) []api.Pod {
+	var ret []api.Pod
+	for _, pod := range pods {
+		if pod.Namespace == namespace && selector.Matches(labels.Set(pod.Labels)) {
+			ret = append(ret, pod)
+		}
+	}
+	return ret
+}
+
+// firstImage returns a pointer to the image of the first (non-init) container in images, or nil if
+// there isn't one
+func firstImage(images []*models.ContainerImage) *string {
+	for _, ci := range images {
+		if !ci.Init {
+			image := ci.Image
+			return &image
+		}
+	}
+	return nil
+}
+
+// findContainer returns the container in containers named name
+func findContainer(containers []api.Container, name string) (api.Container, bool) {
+	for _, c := range containers {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return api.Container{}, false
+}
+
+// initContainers returns the init containers declared in the given pod or pod template annotations
+func initContainers(annotations map[string]string) []api.Container {
+	for _, key := range initContainersAnnotations {
+		if js, ok := annotations[key]; ok {
+			var containers []api.Container
+			if err := json.Unmarshal([]byte(js), &containers); err == nil {
+				return containers
+			}
+		}
+	}
+	return nil
+}
+
+// initContainerStatuses returns the init container statuses recorded in the given pod annotations
+func initContainerStatuses(annotations map[string]string) []api.ContainerStatus {
+	for _, key := range initContainerStatusesAnnotations {
+		if js, ok := annotations[key]; ok {
+			var statuses []api.ContainerStatus
+			if err := json.Unmarshal([]byte(js), &statuses); err == nil {
+				return statuses
+			}
+		}
+	}
+	return nil
+}
+
+// splitImage splits a docker image reference such as "quay.io/deis/controller:v2.0.0" into its
+// repository, tag and digest. The tag is empty if the image is referenced by digest only
+func splitImage(image string) (repo, tag, digest string) {
+	repo = image
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo, digest = repo[:i], repo[i+1:]
+	}
+	// a colon after the last slash separates the tag. Others separate a registry host and port
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, tag = repo[:i], repo[i+1:]
+	} else if digest == "" {
+		tag = "latest"
+	}
+	return repo, tag, digest
+}
+
+// imageIDDigest returns the digest in a container status image ID, such as
+// "docker-pullable://quay.io/deis/controller@sha256:..." or "docker://sha256:..."
+func imageIDDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	for _, prefix := range imageIDPrefixes {
+		if strings.HasPrefix(imageID, prefix) {
+			return strings.TrimPrefix(imageID, prefix)
+		}
+	}
+	return imageID
+}
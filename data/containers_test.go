@@ -0,0 +1,94 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func TestSplitImage(t *testing.T) {
+	for _, tc := range []struct {
+		image, repo, tag, digest string
+	}{
+		{"quay.io/deis/controller:v2.0.0", "quay.io/deis/controller", "v2.0.0", ""},
+		{"localhost:5000/foo", "localhost:5000/foo", "latest", ""},
+		{"localhost:5000/foo:canary", "localhost:5000/foo", "canary", ""},
+		{"deis/router@sha256:abc", "deis/router", "", "sha256:abc"},
+		{"deis/router:v2.3.0@sha256:abc", "deis/router", "v2.3.0", "sha256:abc"},
+	} {
+		repo, tag, digest := splitImage(tc.image)
+		assert.Equal(t, repo, tc.repo, "repository of "+tc.image)
+		assert.Equal(t, tag, tc.tag, "tag of "+tc.image)
+		assert.Equal(t, digest, tc.digest, "digest of "+tc.image)
+	}
+}
+
+func TestImageIDDigest(t *testing.T) {
+	assert.Equal(t, imageIDDigest("docker-pullable://quay.io/deis/controller@sha256:abc"), "sha256:abc", "digest")
+	assert.Equal(t, imageIDDigest("docker://sha256:def"), "sha256:def", "digest")
+	assert.Equal(t, imageIDDigest("sha256:ghi"), "sha256:ghi", "digest")
+}
+
+func TestContainerImagesInitContainers(t *testing.T) {
+	initJSON := `[{"name":"wait-for-db","image":"quay.io/deis/wait:v1"}]`
+	template := api.PodTemplateSpec{
+		ObjectMeta: api.ObjectMeta{
+			Annotations: map[string]string{"pod.beta.kubernetes.io/init-containers": initJSON},
+		},
+		Spec: api.PodSpec{Containers: []api.Container{{Name: "deis-controller", Image: "quay.io/deis/controller:v2.0.0"}}},
+	}
+	pod := api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			Annotations: map[string]string{
+				"pod.beta.kubernetes.io/init-containers":         initJSON,
+				"pod.beta.kubernetes.io/init-container-statuses": `[{"name":"wait-for-db","imageID":"docker://sha256:init"}]`,
+			},
+		},
+		Spec: template.Spec,
+		Status: api.PodStatus{
+			ContainerStatuses: []api.ContainerStatus{{Name: "deis-controller", ImageID: "docker://sha256:ctl"}},
+		},
+	}
+	images := containerImages(template, []api.Pod{pod})
+	assert.Equal(t, len(images), 2, "number of container images")
+	assert.True(t, images[0].Init, "first container wasn't an init container")
+	assert.Equal(t, images[0].Name, "wait-for-db", "init container name")
+	assert.Equal(t, images[0].Tag, "v1", "init container tag")
+	assert.Equal(t, images[0].Digest, "sha256:init", "init container digest")
+	assert.True(t, *images[0].UpToDate, "init container wasn't up to date")
+	assert.False(t, images[1].Init, "second container was an init container")
+	assert.Equal(t, images[1].Digest, "sha256:ctl", "container digest")
+	assert.True(t, *images[1].UpToDate, "container wasn't up to date")
+	assert.Equal(t, *firstImage(images), "quay.io/deis/controller:v2.0.0", "first image")
+}
+
+func TestContainerImagesWithoutPods(t *testing.T) {
+	template := api.PodTemplateSpec{
+		Spec: api.PodSpec{Containers: []api.Container{{Name: "deis-controller", Image: "quay.io/deis/controller:v2.0.0"}}},
+	}
+	images := containerImages(template, nil)
+	assert.Equal(t, len(images), 1, "number of container images")
+	assert.True(t, images[0].UpToDate == nil, "up to date was set without pods")
+}
+
+func TestContainerImagesPinnedDigest(t *testing.T) {
+	const image = "quay.io/deis/controller@sha256:pinned"
+	template := api.PodTemplateSpec{
+		Spec: api.PodSpec{Containers: []api.Container{{Name: "deis-controller", Image: image}}},
+	}
+	pod := func(imageID string) api.Pod {
+		return api.Pod{
+			Spec: template.Spec,
+			Status: api.PodStatus{
+				ContainerStatuses: []api.ContainerStatus{{Name: "deis-controller", ImageID: imageID}},
+			},
+		}
+	}
+	images := containerImages(template, []api.Pod{pod("docker-pullable://quay.io/deis/controller@sha256:pinned")})
+	assert.Equal(t, images[0].Digest, "sha256:pinned", "container digest")
+	assert.True(t, *images[0].UpToDate, "container running the pinned digest wasn't up to date")
+	images = containerImages(template, []api.Pod{pod("docker://sha256:other")})
+	assert.Equal(t, images[0].Digest, "sha256:pinned", "container digest")
+	assert.False(t, *images[0].UpToDate, "container running another digest was up to date")
+}
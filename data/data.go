@@ -1,23 +1,27 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"strings"
+	"time"
 
 	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/data/semver"
 	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/logger"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
 )
 
 const (
-	wfmSecretName      = "deis-workflow-manager"
-	clusterIDSecretKey = "cluster-id"
+	wfmSecretName         = "deis-workflow-manager"
+	clusterIDSecretKey    = "cluster-id"
+	redactionKeySecretKey = "redaction-key"
 )
 
 // GetCluster collects all cluster metadata and returns a Cluster
 func GetCluster(
+	ctx context.Context,
 	c InstalledData,
 	i ClusterID,
 	v AvailableComponentVersion,
@@ -28,8 +32,8 @@ func GetCluster(
 	if err != nil {
 		return models.Cluster{}, err
 	}
-	if err := AddUpdateData(&cluster, v); err != nil {
-		log.Printf("unable to decorate cluster data with available updates data: %#v", err)
+	if err := AddUpdateData(ctx, &cluster, v); err != nil {
+		logger.FromContext(ctx).WithError(err).Warnf("unable to decorate cluster data with available updates data")
 	}
 	// Get the cluster ID
 	id, err := GetID(i)
@@ -43,11 +47,11 @@ func GetCluster(
 
 // AddUpdateData adds UpdateAvailable field data to cluster components
 // Any cluster object modifications are made "in-place"
-func AddUpdateData(c *models.Cluster, v AvailableComponentVersion) error {
+func AddUpdateData(ctx context.Context, c *models.Cluster, v AvailableComponentVersion) error {
 	// Determine if any components have an available update
 	for i, component := range c.Components {
 		installed := component.Version.Version
-		latest, err := v.Get(component.Component.Name, *c)
+		latest, err := v.Get(ctx, *component.Component, *c)
 		if err != nil {
 			return err
 		}
@@ -60,12 +64,12 @@ func AddUpdateData(c *models.Cluster, v AvailableComponentVersion) error {
 }
 
 // GetAvailableVersions gets available component version data from the cache. If there was a cache miss, gets the versions from the k8s and versions APIs
-func GetAvailableVersions(a AvailableVersions, cluster models.Cluster) ([]models.ComponentVersion, error) {
+func GetAvailableVersions(ctx context.Context, a AvailableVersions, cluster models.Cluster) ([]models.ComponentVersion, error) {
 	// First, check to see if we have an in-memory copy
 	data := a.Cached()
 	// If we don't have any cached data, get the data from the remote authority
 	if len(data) == 0 {
-		d, err := a.Refresh(cluster)
+		d, err := a.Refresh(ctx, cluster)
 		if err != nil {
 			return nil, err
 		}
@@ -76,36 +80,50 @@ func GetAvailableVersions(a AvailableVersions, cluster models.Cluster) ([]models
 
 // GetInstalled collects all installed components and returns a Cluster
 func GetInstalled(g InstalledData) (models.Cluster, error) {
-	installed, err := g.Get()
-	if err != nil {
-		return models.Cluster{}, err
-	}
-	var cluster models.Cluster
-	cluster, err = ParseJSONCluster(installed)
+	cluster, err := g.Get()
 	if err != nil {
 		return models.Cluster{}, err
 	}
 	return cluster, nil
 }
 
-// GetLatestVersion returns the latest known version of a deis component
+// GetLatestVersion returns the latest known version of a deis component, on the release train that
+// it follows in cluster. Versions without a train are considered to be stable, as they are in the
+// version catalog, and the newest of several versions on the train is returned
 func GetLatestVersion(
-	component string,
+	ctx context.Context,
+	component models.Component,
 	cluster models.Cluster,
 	availVsns AvailableVersions,
 ) (models.Version, error) {
 	var latestVersion models.Version
-	latestVersions, err := GetAvailableVersions(availVsns, cluster)
+	latestVersions, err := GetAvailableVersions(ctx, availVsns, cluster)
 	if err != nil {
 		return models.Version{}, err
 	}
+	train := clusterTrains(cluster)[namespacedName(component)]
+	if train == "" {
+		train = ComponentTrain(component.Name, nil)
+	}
 	for _, componentVersion := range latestVersions {
-		if componentVersion.Component.Name == component {
+		if componentVersion.Component == nil || componentVersion.Version == nil {
+			continue
+		}
+		// versions without a train predate release train support, when every release was stable
+		cvTrain := componentVersion.Version.Train
+		if cvTrain == "" {
+			cvTrain = stableTrain
+		}
+		if componentVersion.Component.Name != component.Name || cvTrain != train {
+			continue
+		}
+		if latestVersion.Version == "" ||
+			newestVersion(latestVersion.Version, componentVersion.Version.Version) != latestVersion.Version {
 			latestVersion = *componentVersion.Version
 		}
 	}
 	if latestVersion.Version == "" {
-		return models.Version{}, fmt.Errorf("latest version not available for %s", component)
+		return models.Version{}, fmt.Errorf("latest %s version not available for %s", train, component.Name)
 	}
 	return latestVersion, nil
 }
@@ -122,75 +140,96 @@ func ParseJSONCluster(rawJSON []byte) (models.Cluster, error) {
 
 // NewestSemVer returns the newest (largest) semver string
 func NewestSemVer(v1 string, v2 string) (string, error) {
-	v1Slice := strings.Split(v1, ".")
-	v2Slice := strings.Split(v2, ".")
-	for i, subVer1 := range v1Slice {
-		if v2Slice[i] > subVer1 {
-			return v2, nil
-		} else if subVer1 > v2Slice[i] {
-			return v1, nil
-		}
-	}
-	return v1, nil
+	return semver.Newest(v1, v2)
 }
 
-// GetDoctorInfo collects doctor info and return DoctorInfo struct
+// GetDoctorInfo collects doctor info and return DoctorInfo struct. ks holds the k8s data of each
+// namespace to report, and nodes are read from the first of them. Failures to collect k8s data are
+// logged with the logger carried by ctx
 func GetDoctorInfo(
+	ctx context.Context,
 	c InstalledData, // workflow cluster data
-	k k8s.RunningK8sData, // k8s data
+	ks []k8s.RunningK8sData, // k8s data, per namespace
 	i ClusterID,
 	v AvailableComponentVersion,
 ) (models.DoctorInfo, error) {
-	cluster, err := GetCluster(c, i, v)
+	cluster, err := GetCluster(ctx, c, i, v)
 	if err != nil {
 		return models.DoctorInfo{}, err
 	}
-	nodes := getK8sNodes(k)
-	namespaces := []*models.Namespace{getK8sDeisNamespace(k)}
+	log := logger.FromContext(ctx)
+	var nodes []*models.K8sResource
+	var nodeSummaries []*models.NodeSummary
+	if len(ks) > 0 {
+		nodes = getK8sNodes(log, ks[0])
+		nodeSummaries = getK8sNodeSummaries(log, ks[0])
+	}
+	// logs are only collected from the namespaces that are opted in, within one deadline for all of
+	// them
+	logNamespaces := doctorLogNamespaces()
+	logOpts := configuredPodLogOptions()
+	namespaces := make([]*models.Namespace, 0, len(ks))
+	for _, k := range ks {
+		nsLogOpts := &logOpts
+		if !config.Spec.DoctorLogs || !(logNamespaces[k.Namespace()] || logNamespaces[k8s.AllNamespaces]) {
+			nsLogOpts = nil
+		}
+		namespaces = append(namespaces, getK8sNamespace(log.With("namespace", k.Namespace()), k, nsLogOpts))
+	}
 	doctor := models.DoctorInfo{
-		Workflow:   &cluster,
-		Nodes:      nodes,
-		Namespaces: namespaces,
+		Workflow:      &cluster,
+		Nodes:         nodes,
+		NodeSummaries: nodeSummaries,
+		Namespaces:    namespaces,
 	}
 	return doctor, nil
 }
 
-// getK8sDeisNamespace is a helper function that returns data
-// from the "deis" K8s namespace for RESTful consumption
-func getK8sDeisNamespace(k k8s.RunningK8sData) *models.Namespace {
+// getK8sNamespace is a helper function that returns data
+// from k's K8s namespace for RESTful consumption. Container logs are only collected if logOpts
+// isn't nil
+func getK8sNamespace(log *logger.Logger, k k8s.RunningK8sData, logOpts *k8s.PodLogOptions) *models.Namespace {
 	pods, err := k8s.GetPodsModels(k)
 	if err != nil {
-		log.Printf("unable to get K8s pods data: %#v", err)
+		log.WithError(err).Warnf("unable to get K8s pods data")
 	}
 	services, err := k8s.GetServicesModels(k)
 	if err != nil {
-		log.Printf("unable to get K8s services data: %#v", err)
+		log.WithError(err).Warnf("unable to get K8s services data")
 	}
 	replicationControllers, err := k8s.GetReplicationControllersModels(k)
 	if err != nil {
-		log.Printf("unable to get K8s RC data: %#v", err)
+		log.WithError(err).Warnf("unable to get K8s RC data")
 	}
 	replicaSets, err := k8s.GetReplicaSetsModels(k)
 	if err != nil {
-		log.Printf("unable to get K8s replicaSets data: %#v", err)
+		log.WithError(err).Warnf("unable to get K8s replicaSets data")
 	}
 	daemonSets, err := k8s.GetDaemonSetsModels(k)
 	if err != nil {
-		log.Printf("unable to get K8s daemonSets data: %#v", err)
+		log.WithError(err).Warnf("unable to get K8s daemonSets data")
 	}
 	deployments, err := k8s.GetDeploymentsModels(k)
 	if err != nil {
-		log.Printf("unable to get K8s deployments data: %#v", err)
+		log.WithError(err).Warnf("unable to get K8s deployments data")
 	}
 	events, err := k8s.GetEventsModels(k)
 	if err != nil {
-		log.Printf("unable to get K8s events data: %#v", err)
+		log.WithError(err).Warnf("unable to get K8s events data")
+	}
+	var logs []*models.ContainerLog
+	if logOpts != nil {
+		logs, err = k8s.GetLogsModels(log, k, *logOpts)
+		if err != nil {
+			log.WithError(err).Warnf("unable to get K8s container logs")
+		}
 	}
 	return &models.Namespace{
-		Name:                   config.Spec.DeisNamespace,
+		Name:                   k.Namespace(),
 		DaemonSets:             daemonSets,
 		Deployments:            deployments,
 		Events:                 events,
+		Logs:                   logs,
 		Pods:                   pods,
 		ReplicaSets:            replicaSets,
 		ReplicationControllers: replicationControllers,
@@ -198,16 +237,57 @@ func getK8sDeisNamespace(k k8s.RunningK8sData) *models.Namespace {
 	}
 }
 
+// configuredPodLogOptions returns the k8s.PodLogOptions configured in config.Spec. Its deadline
+// is config.Spec.DoctorLogTotalTimeout from now, if that's set
+func configuredPodLogOptions() k8s.PodLogOptions {
+	opts := k8s.PodLogOptions{
+		TailLines:     int64(config.Spec.DoctorLogTailLines),
+		Since:         time.Duration(config.Spec.DoctorLogSince) * time.Second,
+		MaxBytes:      int64(config.Spec.DoctorLogMaxBytes),
+		MaxTotalBytes: int64(config.Spec.DoctorLogMaxTotalBytes),
+		Timeout:       time.Duration(config.Spec.DoctorLogTimeout) * time.Second,
+	}
+	if config.Spec.DoctorLogTotalTimeout > 0 {
+		opts.Deadline = time.Now().Add(time.Duration(config.Spec.DoctorLogTotalTimeout) * time.Second)
+	}
+	return opts
+}
+
+// doctorLogNamespaces returns the set of namespaces whose container logs are collected: the deis
+// namespace, and those listed in config.Spec.DoctorLogNamespaces
+func doctorLogNamespaces() map[string]bool {
+	ret := map[string]bool{config.Spec.DeisNamespace: true}
+	for _, ns := range k8s.ParseNamespaces(config.Spec.DoctorLogNamespaces) {
+		ret[ns] = true
+	}
+	return ret
+}
+
 // getK8sNodes is a helper function that returns K8s nodes data for RESTful consumption
-func getK8sNodes(k k8s.RunningK8sData) []*models.K8sResource {
+func getK8sNodes(log *logger.Logger, k k8s.RunningK8sData) []*models.K8sResource {
 	nodes, err := k8s.GetNodesModels(k)
 	if err != nil {
-		log.Println("unable to get K8s nodes data")
+		log.WithError(err).Warnf("unable to get K8s nodes data")
 	}
 	return nodes
 }
 
-// newestVersion is a temporary static implementation of a real "return newest version" function
+// getK8sNodeSummaries is a helper function that returns K8s node summaries for RESTful consumption
+func getK8sNodeSummaries(log *logger.Logger, k k8s.RunningK8sData) []*models.NodeSummary {
+	summaries, err := k8s.GetNodeSummariesModels(k)
+	if err != nil {
+		log.WithError(err).Warnf("unable to get K8s node summaries")
+	}
+	return summaries
+}
+
+// newestVersion returns the newest of the installed version v1 and the available version v2. If
+// the two can't be compared (e.g. v1 is a development build), v1 is returned so that no update is
+// reported
 func newestVersion(v1 string, v2 string) string {
-	return v1
+	newest, err := NewestSemVer(v1, v2)
+	if err != nil {
+		return v1
+	}
+	return newest
 }
@@ -1,10 +1,13 @@
 package data
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/k8s"
 	"github.com/deis/workflow-manager/mocks"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
 )
@@ -35,8 +38,8 @@ func (c *testClusterID) StoreInCache(cid string) {
 type mockInstalledComponents struct {
 }
 
-func (g mockInstalledComponents) Get() ([]byte, error) {
-	return []byte(fmt.Sprintf(`{
+func (g mockInstalledComponents) Get() (models.Cluster, error) {
+	return ParseJSONCluster([]byte(fmt.Sprintf(`{
 	  "components": [
 	    {
 	      "component": {
@@ -48,7 +51,7 @@ func (g mockInstalledComponents) Get() ([]byte, error) {
 	      }
 	    }
 	  ]
-	}`, mockComponentName, mockComponentDescription, mockComponentVersion)), nil
+	}`, mockComponentName, mockComponentDescription, mockComponentVersion)))
 }
 
 // Creating a novel mock struct that fulfills the InstalledComponentVersion interface
@@ -78,6 +81,7 @@ func TestGetID(t *testing.T) {
 	id, err := GetID(cid)
 	assert.NoErr(t, err)
 	assert.Equal(t, id, mockClusterID, "cluster ID value")
+	assert.Equal(t, cid.Cached(), mockClusterID, "cached cluster ID value")
 	cid.cache = "something else"
 	id, err = GetID(cid)
 	assert.NoErr(t, err)
@@ -87,6 +91,7 @@ func TestGetID(t *testing.T) {
 func TestGetCluster(t *testing.T) {
 	mockCluster := getMockCluster(t)
 	cluster, err := GetCluster(
+		context.Background(),
 		mocks.InstalledMockData{},
 		&mocks.ClusterIDMockData{},
 		mocks.LatestMockData{},
@@ -98,22 +103,88 @@ func TestGetCluster(t *testing.T) {
 func TestGetDoctorInfo(t *testing.T) {
 	mockCluster := getMockCluster(t)
 	doctorInfo, err := GetDoctorInfo(
+		context.Background(),
 		mocks.InstalledMockData{},
-		mocks.RunningK8sMockData{}, // TODO: add k8s mock data
+		[]k8s.RunningK8sData{ // TODO: add k8s mock data
+			mocks.RunningK8sMockData{Name: "deis"},
+			mocks.RunningK8sMockData{Name: "kube-system"},
+		},
 		&mocks.ClusterIDMockData{},
 		mocks.LatestMockData{},
 	)
 	assert.NoErr(t, err)
 	assert.Equal(t, *doctorInfo.Workflow, mockCluster, "clusters")
+	assert.Equal(t, len(doctorInfo.Namespaces), 2, "number of namespaces")
+	assert.Equal(t, doctorInfo.Namespaces[0].Name, "deis", "first namespace name")
+	assert.Equal(t, doctorInfo.Namespaces[1].Name, "kube-system", "second namespace name")
+}
+
+func TestGetDoctorInfoLogNamespaces(t *testing.T) {
+	defer func(spec config.Specification) { config.Spec = spec }(config.Spec)
+	config.Spec.DeisNamespace = "deis"
+	config.Spec.DoctorLogs = true
+	ks := []k8s.RunningK8sData{
+		mocks.RunningK8sMockData{Name: "deis"},
+		mocks.RunningK8sMockData{Name: "kube-system"},
+		mocks.RunningK8sMockData{Name: "apps"},
+	}
+	getDoctorInfo := func() models.DoctorInfo {
+		doctorInfo, err := GetDoctorInfo(context.Background(), mocks.InstalledMockData{}, ks, &mocks.ClusterIDMockData{}, mocks.LatestMockData{})
+		assert.NoErr(t, err)
+		return doctorInfo
+	}
+	// only the deis namespace's logs are collected by default
+	config.Spec.DoctorLogNamespaces = ""
+	doctorInfo := getDoctorInfo()
+	assert.True(t, doctorInfo.Namespaces[0].Logs != nil, "deis logs weren't collected")
+	assert.True(t, doctorInfo.Namespaces[1].Logs == nil, "kube-system logs were collected")
+	assert.True(t, doctorInfo.Namespaces[2].Logs == nil, "apps logs were collected")
+
+	config.Spec.DoctorLogNamespaces = "kube-system"
+	doctorInfo = getDoctorInfo()
+	assert.True(t, doctorInfo.Namespaces[1].Logs != nil, "opted in kube-system logs weren't collected")
+	assert.True(t, doctorInfo.Namespaces[2].Logs == nil, "apps logs were collected")
+
+	config.Spec.DoctorLogNamespaces = "*"
+	doctorInfo = getDoctorInfo()
+	assert.True(t, doctorInfo.Namespaces[2].Logs != nil, "apps logs weren't collected with every namespace opted in")
+}
+
+// Creating a novel mock struct that fulfills the AvailableComponentVersion interface
+type mockAvailableComponentVersion struct {
+	version string
+}
+
+func (c mockAvailableComponentVersion) Get(ctx context.Context, component models.Component, cluster models.Cluster) (models.Version, error) {
+	return models.Version{Version: c.version}, nil
 }
 
 func TestAddUpdateData(t *testing.T) {
 	mockCluster := getMockCluster(t)
+	// the mock latest versions ("v2-beta") are prereleases of the installed versions ("2.0.0")
+	err := AddUpdateData(context.Background(), &mockCluster, mocks.LatestMockData{})
+	assert.NoErr(t, err)
+	for _, component := range mockCluster.Components {
+		assert.True(t, component.UpdateAvailable == nil, "unexpected update available for %s", component.Component.Name)
+	}
 	// AddUpdateData should add an "UpdateAvailable" field to any components whose versions are out-of-date
-	err := AddUpdateData(&mockCluster, mocks.LatestMockData{})
+	const newer = "2.10.0"
+	err = AddUpdateData(context.Background(), &mockCluster, mockAvailableComponentVersion{version: newer})
 	assert.NoErr(t, err)
-	//TODO: when newestVersion is implemented, actually test for the addition of "UpdateAvailable" fields.
-	// tracked in https://github.com/deis/workflow-manager/issues/52
+	for _, component := range mockCluster.Components {
+		assert.True(t, component.UpdateAvailable != nil, "no update available for %s", component.Component.Name)
+		assert.Equal(t, *component.UpdateAvailable, newer, "update available")
+	}
+	// development builds are never reported as out-of-date
+	dev := getMockCluster(t)
+	for _, component := range dev.Components {
+		component.Version.Version = "git-abc123"
+	}
+	err = AddUpdateData(context.Background(), &dev, mockAvailableComponentVersion{version: newer})
+	assert.NoErr(t, err)
+	for _, component := range dev.Components {
+		assert.True(t, component.UpdateAvailable == nil, "unexpected update available for %s", component.Component.Name)
+	}
 }
 
 func TestGetInstalled(t *testing.T) {
@@ -154,7 +225,7 @@ func TestParseJSONCluster(t *testing.T) {
 func TestNewestSemVer(t *testing.T) {
 	// Verify that NewestSemVer returns correct semver string for larger major, minor, and patch substrings
 	const v1Lower = "2.0.0"
-	v2s := [3]string{"3.0.0", "2.1.0", "2.0.1"}
+	v2s := [4]string{"3.0.0", "2.1.0", "2.0.1", "2.0.10"}
 	for _, v2 := range v2s {
 		newest, err := NewestSemVer(v1Lower, v2)
 		assert.NoErr(t, err)
@@ -165,7 +236,7 @@ func TestNewestSemVer(t *testing.T) {
 	}
 	// Verify that NewestSemVer returns correct semver string for smaller major, minor, and patch substrings
 	const v1Higher = "2.4.5"
-	v2s = [3]string{"1.99.23", "2.3.99", "2.4.4"}
+	v2s = [4]string{"1.99.23", "2.3.99", "2.4.4", "2.4.5-beta2"}
 	for _, v2 := range v2s {
 		newest, err := NewestSemVer(v1Higher, v2)
 		assert.NoErr(t, err)
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"path"
+	"time"
+
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+// the name of the directory that every file in a doctor bundle is written to
+const doctorBundleDir = "doctor"
+
+// bundleFile is a file in a doctor bundle, holding the JSON representation of obj
+type bundleFile struct {
+	name string
+	obj  interface{}
+}
+
+// WriteDoctorBundle writes doctor to w as a gzipped tarball with one JSON file per resource kind:
+// workflow.json, nodes.json, nodeSummaries.json, redactions.json, findings.json and
+// namespaces/<namespace>/<kind>.json, including the namespace's container logs in logs.json
+func WriteDoctorBundle(w io.Writer, doctor models.DoctorInfo) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	now := time.Now()
+	files := []bundleFile{
+		{"workflow.json", doctor.Workflow},
+		{"nodes.json", doctor.Nodes},
+		{"nodeSummaries.json", doctor.NodeSummaries},
+		{"redactions.json", doctor.Redactions},
+		{"findings.json", doctor.Findings},
+	}
+	for _, ns := range doctor.Namespaces {
+		if ns == nil {
+			continue
+		}
+		dir := path.Join("namespaces", ns.Name)
+		files = append(files, []bundleFile{
+			{path.Join(dir, "daemonSets.json"), ns.DaemonSets},
+			{path.Join(dir, "deployments.json"), ns.Deployments},
+			{path.Join(dir, "events.json"), ns.Events},
+			{path.Join(dir, "logs.json"), ns.Logs},
+			{path.Join(dir, "pods.json"), ns.Pods},
+			{path.Join(dir, "replicaSets.json"), ns.ReplicaSets},
+			{path.Join(dir, "replicationControllers.json"), ns.ReplicationControllers},
+			{path.Join(dir, "services.json"), ns.Services},
+		}...)
+	}
+	for _, f := range files {
+		b, err := json.MarshalIndent(f.obj, "", "  ")
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    path.Join(doctorBundleDir, f.name),
+			Mode:    0644,
+			Size:    int64(len(b)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+func TestWriteDoctorBundle(t *testing.T) {
+	doctor := models.DoctorInfo{
+		Workflow: &models.Cluster{ID: "abc"},
+		Nodes:    []*models.K8sResource{{Data: map[string]interface{}{"kind": "Node"}}},
+		Namespaces: []*models.Namespace{
+			{
+				Name: "deis",
+				Pods: []*models.K8sResource{{Data: map[string]interface{}{"kind": "Pod"}}},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoErr(t, WriteDoctorBundle(&buf, doctor))
+	gzr, err := gzip.NewReader(&buf)
+	assert.NoErr(t, err)
+	tr := tar.NewReader(gzr)
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoErr(t, err)
+		b, err := ioutil.ReadAll(tr)
+		assert.NoErr(t, err)
+		files[hdr.Name] = b
+	}
+	for _, name := range []string{
+		"doctor/workflow.json",
+		"doctor/nodes.json",
+		"doctor/nodeSummaries.json",
+		"doctor/redactions.json",
+		"doctor/findings.json",
+		"doctor/namespaces/deis/daemonSets.json",
+		"doctor/namespaces/deis/deployments.json",
+		"doctor/namespaces/deis/events.json",
+		"doctor/namespaces/deis/logs.json",
+		"doctor/namespaces/deis/pods.json",
+		"doctor/namespaces/deis/replicaSets.json",
+		"doctor/namespaces/deis/replicationControllers.json",
+		"doctor/namespaces/deis/services.json",
+	} {
+		_, ok := files[name]
+		assert.True(t, ok, "bundle has no %s", name)
+	}
+	assert.Equal(t, len(files), 13, "number of files")
+	cluster := new(models.Cluster)
+	assert.NoErr(t, json.Unmarshal(files["doctor/workflow.json"], cluster))
+	assert.Equal(t, cluster.ID, "abc", "cluster ID")
+	var pods []*models.K8sResource
+	assert.NoErr(t, json.Unmarshal(files["doctor/namespaces/deis/pods.json"], &pods))
+	assert.Equal(t, len(pods), 1, "number of pods")
+}
@@ -0,0 +1,366 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+// the kinds of changes that are reported between two snapshots
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeVersion  = "version"
+	ChangeImage    = "image"
+	ChangeReplicas = "replicas"
+)
+
+// warningEventType is the type of the k8s events that are reported when they're new
+const warningEventType = "Warning"
+
+// ComponentChange is a change to a component between two cluster snapshots. From and To are the
+// values before and after a version, image or replicas change, the version of an added component,
+// or the version of a removed component
+type ComponentChange struct {
+	Namespace string `json:"namespace,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Name      string `json:"name"`
+	Change    string `json:"change"`
+	// Container is the container whose image changed
+	Container string `json:"container,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+}
+
+// EventChange is a warning event that's new, or that recurred Count more times, since the earlier
+// of two doctor reports
+type EventChange struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+	Count     int64  `json:"count"`
+}
+
+// NodeChange is a node that was added or removed between two doctor reports
+type NodeChange struct {
+	Name   string `json:"name"`
+	Change string `json:"change"`
+}
+
+// DoctorDiff is the difference between two doctor reports
+type DoctorDiff struct {
+	// From and To identify the compared reports, e.g. by their UUIDs
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	Components []ComponentChange `json:"components"`
+	Events     []EventChange     `json:"events"`
+	Nodes      []NodeChange      `json:"nodes"`
+}
+
+// DiffClusters returns the changes to the components of from that are found in to: added and
+// removed components, and changes to their versions, container images and desired replicas
+func DiffClusters(from, to models.Cluster) []ComponentChange {
+	fromComponents := componentsByKey(from)
+	toComponents := componentsByKey(to)
+	changes := []ComponentChange{}
+	for key, f := range fromComponents {
+		if _, ok := toComponents[key]; !ok {
+			changes = append(changes, newComponentChange(key, ChangeRemoved, componentVersion(f), ""))
+		}
+	}
+	for key, t := range toComponents {
+		f, ok := fromComponents[key]
+		if !ok {
+			changes = append(changes, newComponentChange(key, ChangeAdded, "", componentVersion(t)))
+			continue
+		}
+		if fv, tv := componentVersion(f), componentVersion(t); fv != tv {
+			changes = append(changes, newComponentChange(key, ChangeVersion, fv, tv))
+		}
+		fromImages := componentImages(f)
+		toImages := componentImages(t)
+		for name := range fromImages {
+			if _, ok := toImages[name]; !ok {
+				toImages[name] = ""
+			}
+		}
+		for name, ti := range toImages {
+			if fi := fromImages[name]; fi != ti {
+				change := newComponentChange(key, ChangeImage, fi, ti)
+				change.Container = name
+				changes = append(changes, change)
+			}
+		}
+		if f.Status != nil && t.Status != nil && f.Status.Desired != t.Status.Desired {
+			changes = append(changes, newComponentChange(
+				key,
+				ChangeReplicas,
+				fmt.Sprintf("%d", f.Status.Desired),
+				fmt.Sprintf("%d", t.Status.Desired),
+			))
+		}
+	}
+	// map iteration is random, so the changes are sorted to keep diffs stable
+	sort.Sort(byComponent(changes))
+	return changes
+}
+
+// DiffDoctorReports returns the changes between the from and to doctor reports: the changes to their
+// components, the warning events that are new in to, and the nodes that were added or removed.
+// Reports should be scrubbed with the same rules, so that hashed names can be compared
+func DiffDoctorReports(from, to models.DoctorInfo) DoctorDiff {
+	diff := DoctorDiff{Components: []ComponentChange{}}
+	if from.Workflow != nil && to.Workflow != nil {
+		diff.Components = DiffClusters(*from.Workflow, *to.Workflow)
+	}
+	diff.Events = diffWarningEvents(from, to)
+	diff.Nodes = []NodeChange{}
+	fromNodes := doctorNodeNames(from)
+	toNodes := doctorNodeNames(to)
+	for name := range fromNodes {
+		if !toNodes[name] {
+			diff.Nodes = append(diff.Nodes, NodeChange{Name: name, Change: ChangeRemoved})
+		}
+	}
+	for name := range toNodes {
+		if !fromNodes[name] {
+			diff.Nodes = append(diff.Nodes, NodeChange{Name: name, Change: ChangeAdded})
+		}
+	}
+	sort.Sort(byNode(diff.Nodes))
+	return diff
+}
+
+// WriteText writes a human readable form of d to w
+func (d DoctorDiff) WriteText(w io.Writer) error {
+	lines := []string{fmt.Sprintf("Changes from %s to %s", d.From, d.To)}
+	if len(d.Components) == 0 && len(d.Events) == 0 && len(d.Nodes) == 0 {
+		lines = append(lines, "No changes")
+	}
+	if len(d.Components) > 0 {
+		lines = append(lines, "", "Components:")
+	}
+	for _, c := range d.Components {
+		name := strings.Join([]string{c.Namespace, c.Type, c.Name}, "/")
+		switch c.Change {
+		case ChangeAdded:
+			lines = append(lines, fmt.Sprintf("  + %s %s", name, c.To))
+		case ChangeRemoved:
+			lines = append(lines, fmt.Sprintf("  - %s %s", name, c.From))
+		case ChangeImage:
+			lines = append(lines, fmt.Sprintf("  ~ %s container %s image: %s -> %s", name, c.Container, textOrNone(c.From), textOrNone(c.To)))
+		default:
+			lines = append(lines, fmt.Sprintf("  ~ %s %s: %s -> %s", name, c.Change, textOrNone(c.From), textOrNone(c.To)))
+		}
+	}
+	if len(d.Nodes) > 0 {
+		lines = append(lines, "", "Nodes:")
+	}
+	for _, n := range d.Nodes {
+		sign := "+"
+		if n.Change == ChangeRemoved {
+			sign = "-"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s", sign, n.Name))
+	}
+	if len(d.Events) > 0 {
+		lines = append(lines, "", "New warning events:")
+	}
+	for _, e := range d.Events {
+		lines = append(lines, fmt.Sprintf("  %s/%s/%s %s (x%d): %s", e.Namespace, e.Kind, e.Name, e.Reason, e.Count, e.Message))
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func textOrNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// componentKey identifies a component across snapshots
+type componentKey struct {
+	namespace, componentType, name string
+}
+
+func componentsByKey(cluster models.Cluster) map[componentKey]*models.ComponentVersion {
+	ret := make(map[componentKey]*models.ComponentVersion)
+	for _, cv := range cluster.Components {
+		if cv == nil || cv.Component == nil {
+			continue
+		}
+		key := componentKey{name: cv.Component.Name}
+		if cv.Component.Namespace != nil {
+			key.namespace = *cv.Component.Namespace
+		}
+		if cv.Component.Type != nil {
+			key.componentType = *cv.Component.Type
+		}
+		ret[key] = cv
+	}
+	return ret
+}
+
+func newComponentChange(key componentKey, change, from, to string) ComponentChange {
+	return ComponentChange{
+		Namespace: key.namespace,
+		Type:      key.componentType,
+		Name:      key.name,
+		Change:    change,
+		From:      from,
+		To:        to,
+	}
+}
+
+func componentVersion(cv *models.ComponentVersion) string {
+	if cv.Version == nil {
+		return ""
+	}
+	return cv.Version.Version
+}
+
+// componentImages returns the image of each of cv's containers, by container name. Images are
+// pinned to their digest where it's known, so that a retagged image is reported as a change
+func componentImages(cv *models.ComponentVersion) map[string]string {
+	ret := make(map[string]string)
+	if cv.Version == nil || cv.Version.Data == nil {
+		return ret
+	}
+	for _, c := range cv.Version.Data.Containers {
+		if c == nil {
+			continue
+		}
+		image := c.Image
+		if c.Digest != "" {
+			image += "@" + c.Digest
+		}
+		ret[c.Name] = image
+	}
+	return ret
+}
+
+// diffWarningEvents returns the warning events in to that aren't in from, or that recurred since
+func diffWarningEvents(from, to models.DoctorInfo) []EventChange {
+	fromCounts := make(map[string]int64)
+	for _, e := range doctorWarningEvents(from) {
+		fromCounts[e.Namespace+"/"+e.Name] = eventCount(e)
+	}
+	changes := []EventChange{}
+	for _, e := range doctorWarningEvents(to) {
+		count := eventCount(e) - fromCounts[e.Namespace+"/"+e.Name]
+		if count <= 0 {
+			continue
+		}
+		changes = append(changes, EventChange{
+			Namespace: e.Namespace,
+			Kind:      e.InvolvedObject.Kind,
+			Name:      e.InvolvedObject.Name,
+			Reason:    e.Reason,
+			Message:   e.Message,
+			Count:     count,
+		})
+	}
+	return changes
+}
+
+// doctorWarningEvents returns the warning events of every namespace in doctor. The namespace of each
+// event is set from the namespace it's reported in
+func doctorWarningEvents(doctor models.DoctorInfo) []api.Event {
+	var ret []api.Event
+	for _, ns := range doctor.Namespaces {
+		if ns == nil {
+			continue
+		}
+		for _, res := range ns.Events {
+			var e api.Event
+			if err := decodeResource(res, &e); err != nil || e.Type != warningEventType {
+				continue
+			}
+			e.Namespace = ns.Name
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// eventCount returns the number of times e occurred, which is at least 1
+func eventCount(e api.Event) int64 {
+	if e.Count < 1 {
+		return 1
+	}
+	return int64(e.Count)
+}
+
+// doctorNodeNames returns the names of the nodes in doctor, from its node summaries, or from its node
+// resources if it has no summaries
+func doctorNodeNames(doctor models.DoctorInfo) map[string]bool {
+	ret := make(map[string]bool)
+	for _, s := range doctor.NodeSummaries {
+		if s != nil {
+			ret[s.Name] = true
+		}
+	}
+	if len(ret) > 0 {
+		return ret
+	}
+	for _, res := range doctor.Nodes {
+		var node api.Node
+		if err := decodeResource(res, &node); err == nil && node.Name != "" {
+			ret[node.Name] = true
+		}
+	}
+	return ret
+}
+
+// decodeResource decodes the Data of res into obj. Data is the k8s object itself in a report that's
+// just been collected, and its JSON representation in a scrubbed or stored report
+func decodeResource(res *models.K8sResource, obj interface{}) error {
+	if res == nil {
+		return fmt.Errorf("no resource")
+	}
+	b, err := json.Marshal(res.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, obj)
+}
+
+type byComponent []ComponentChange
+
+func (b byComponent) Len() int      { return len(b) }
+func (b byComponent) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byComponent) Less(i, j int) bool {
+	if b[i].Namespace != b[j].Namespace {
+		return b[i].Namespace < b[j].Namespace
+	}
+	if b[i].Type != b[j].Type {
+		return b[i].Type < b[j].Type
+	}
+	if b[i].Name != b[j].Name {
+		return b[i].Name < b[j].Name
+	}
+	if b[i].Change != b[j].Change {
+		return b[i].Change < b[j].Change
+	}
+	return b[i].Container < b[j].Container
+}
+
+type byNode []NodeChange
+
+func (b byNode) Len() int      { return len(b) }
+func (b byNode) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byNode) Less(i, j int) bool {
+	if b[i].Change != b[j].Change {
+		return b[i].Change < b[j].Change
+	}
+	return b[i].Name < b[j].Name
+}
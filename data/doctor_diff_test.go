@@ -0,0 +1,116 @@
+package data
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func getTestComponentVersion(name, version, image string, desired int64) *models.ComponentVersion {
+	namespace := "deis"
+	componentType := deploymentType
+	return &models.ComponentVersion{
+		Component: &models.Component{Name: name, Namespace: &namespace, Type: &componentType},
+		Status:    &models.ComponentStatus{Desired: desired},
+		Version: &models.Version{
+			Version: version,
+			Data:    &models.VersionData{Containers: []*models.ContainerImage{{Name: name, Image: image}}},
+		},
+	}
+}
+
+func getTestWarningEvent(name, reason string, count int) *models.K8sResource {
+	return &models.K8sResource{Data: &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: name},
+		InvolvedObject: api.ObjectReference{Kind: "Pod", Name: "deis-router-1"},
+		Type:           warningEventType,
+		Reason:         reason,
+		Count:          count,
+	}}
+}
+
+func TestDiffClusters(t *testing.T) {
+	from := models.Cluster{Components: []*models.ComponentVersion{
+		getTestComponentVersion("deis-router", "v2.2.0", "quay.io/deis/router:v2.2.0", 1),
+		getTestComponentVersion("deis-builder", "v2.2.0", "quay.io/deis/builder:v2.2.0", 1),
+		getTestComponentVersion("deis-registry", "v2.2.0", "quay.io/deis/registry:v2.2.0", 1),
+	}}
+	to := models.Cluster{Components: []*models.ComponentVersion{
+		getTestComponentVersion("deis-router", "v2.3.0", "quay.io/deis/router:v2.3.0", 3),
+		getTestComponentVersion("deis-registry", "v2.2.0", "quay.io/deis/registry:v2.2.0", 1),
+		getTestComponentVersion("deis-monitor", "v2.3.0", "quay.io/deis/monitor:v2.3.0", 1),
+	}}
+	changes := DiffClusters(from, to)
+	assert.Equal(t, len(changes), 5, "number of changes")
+	expected := []ComponentChange{
+		{Name: "deis-builder", Change: ChangeRemoved, From: "v2.2.0"},
+		{Name: "deis-monitor", Change: ChangeAdded, To: "v2.3.0"},
+		{Name: "deis-router", Change: ChangeImage, Container: "deis-router", From: "quay.io/deis/router:v2.2.0", To: "quay.io/deis/router:v2.3.0"},
+		{Name: "deis-router", Change: ChangeReplicas, From: "1", To: "3"},
+		{Name: "deis-router", Change: ChangeVersion, From: "v2.2.0", To: "v2.3.0"},
+	}
+	for i, change := range changes {
+		expected[i].Namespace = "deis"
+		expected[i].Type = deploymentType
+		assert.Equal(t, change, expected[i], "change")
+	}
+	assert.Equal(t, len(DiffClusters(to, to)), 0, "number of changes between identical clusters")
+}
+
+func TestDiffDoctorReports(t *testing.T) {
+	from := models.DoctorInfo{
+		Namespaces: []*models.Namespace{{
+			Name:   "deis",
+			Events: []*models.K8sResource{getTestWarningEvent("deis-router-1.a", "BackOff", 2)},
+		}},
+		NodeSummaries: []*models.NodeSummary{{Name: "node-1"}, {Name: "node-2"}},
+	}
+	to := models.DoctorInfo{
+		Namespaces: []*models.Namespace{{
+			Name: "deis",
+			Events: []*models.K8sResource{
+				getTestWarningEvent("deis-router-1.a", "BackOff", 5),
+				getTestWarningEvent("deis-router-1.b", "FailedMount", 1),
+				// an event that's not a warning
+				{Data: &api.Event{ObjectMeta: api.ObjectMeta{Name: "deis-router-1.c"}, Type: "Normal"}},
+			},
+		}},
+		// the nodes of a stored report are JSON, rather than k8s objects
+		Nodes: []*models.K8sResource{
+			{Data: map[string]interface{}{"metadata": map[string]interface{}{"name": "node-2"}}},
+			{Data: map[string]interface{}{"metadata": map[string]interface{}{"name": "node-3"}}},
+		},
+	}
+	diff := DiffDoctorReports(from, to)
+	assert.Equal(t, len(diff.Components), 0, "number of component changes")
+	assert.Equal(t, len(diff.Events), 2, "number of new warning events")
+	assert.Equal(t, diff.Events[0].Reason, "BackOff", "recurring event reason")
+	assert.Equal(t, diff.Events[0].Count, int64(3), "recurring event count")
+	assert.Equal(t, diff.Events[0].Namespace, "deis", "event namespace")
+	assert.Equal(t, diff.Events[1].Reason, "FailedMount", "new event reason")
+	assert.Equal(t, diff.Nodes, []NodeChange{
+		{Name: "node-3", Change: ChangeAdded},
+		{Name: "node-1", Change: ChangeRemoved},
+	}, "node changes")
+
+	diff.From = "from-uuid"
+	diff.To = "current"
+	var buf bytes.Buffer
+	assert.NoErr(t, diff.WriteText(&buf))
+	text := buf.String()
+	for _, line := range []string{
+		"Changes from from-uuid to current",
+		"  + node-3",
+		"  - node-1",
+		"  deis/Pod/deis-router-1 BackOff (x3): ",
+	} {
+		assert.True(t, strings.Contains(text, line+"\n"), "diff text doesn't contain %q:\n%s", line, text)
+	}
+	buf.Reset()
+	assert.NoErr(t, DoctorDiff{From: "a", To: "b"}.WriteText(&buf))
+	assert.True(t, strings.Contains(buf.String(), "No changes"), "empty diff text was %q", buf.String())
+}
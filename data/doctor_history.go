@@ -0,0 +1,343 @@
+package data
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	apierrors "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/labels"
+)
+
+const (
+	doctorReportSecretPrefix = "deis-workflow-manager-doctor-"
+	doctorReportLabel        = "workflow-manager.deis.io/doctor-report"
+	doctorReportKey          = "report"
+	doctorCreatedAtKey       = "created-at"
+	doctorPublishedKey       = "published"
+	// the most data that a secret can hold
+	maxSecretBytes = 1 << 20
+	// the file that lists the reports kept in a doctor history directory
+	doctorHistoryIndex = "index.json"
+)
+
+// ErrDoctorReportNotFound is returned by a DoctorHistory for a report that it doesn't keep
+var ErrDoctorReportNotFound = errors.New("doctor report not found")
+
+// DoctorReportEntry describes a doctor report that's kept in a DoctorHistory
+type DoctorReportEntry struct {
+	UUID      string    `json:"uuid"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Published is true if the report was submitted to the doctor API
+	Published bool `json:"published"`
+}
+
+// DoctorHistory is an interface for keeping the most recent doctor reports
+type DoctorHistory interface {
+	// Save keeps doctor, described by entry, and drops the oldest reports beyond the history's size
+	Save(entry DoctorReportEntry, doctor models.DoctorInfo) error
+	// List returns the entries of the kept reports, newest first
+	List() ([]DoctorReportEntry, error)
+	// Get returns the kept report with the given UUID and its entry. Returns
+	// ErrDoctorReportNotFound if the report isn't kept
+	Get(uid string) (DoctorReportEntry, models.DoctorInfo, error)
+}
+
+// ConfiguredDoctorHistory returns the DoctorHistory configured in config.Spec: a directory if
+// DoctorHistoryDir is set, and secrets, managed with secrets, otherwise. Returns nil if the
+// history is disabled
+func ConfiguredDoctorHistory(secrets k8s.KubeSecretStore) (DoctorHistory, error) {
+	if config.Spec.DoctorHistorySize <= 0 {
+		return nil, nil
+	}
+	if config.Spec.DoctorHistoryDir != "" {
+		return NewDoctorHistoryFromDir(config.Spec.DoctorHistoryDir, config.Spec.DoctorHistorySize)
+	}
+	return NewDoctorHistoryFromSecrets(secrets, config.Spec.DoctorHistorySize), nil
+}
+
+// secretDoctorHistory fulfills the DoctorHistory interface using one kubernetes secret per report
+type secretDoctorHistory struct {
+	mut     *sync.Mutex
+	secrets k8s.KubeSecretStore
+	size    int
+}
+
+// NewDoctorHistoryFromSecrets returns a new DoctorHistory that keeps up to size reports, each in a
+// labeled secret next to the deis-workflow-manager secret. A secret holds at most 1MiB, so reports
+// that are larger once compressed can't be kept
+func NewDoctorHistoryFromSecrets(secrets k8s.KubeSecretStore, size int) DoctorHistory {
+	return &secretDoctorHistory{mut: new(sync.Mutex), secrets: secrets, size: size}
+}
+
+// Save is the DoctorHistory interface implementation
+func (s *secretDoctorHistory) Save(entry DoctorReportEntry, doctor models.DoctorInfo) error {
+	report, err := encodeDoctorReport(doctor)
+	if err != nil {
+		return err
+	}
+	if len(report) > maxSecretBytes {
+		return fmt.Errorf(
+			"doctor report %s is %d bytes compressed, more than a secret can hold",
+			entry.UUID,
+			len(report),
+		)
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	secret := new(api.Secret)
+	secret.Name = doctorReportSecretPrefix + entry.UUID
+	secret.Labels = map[string]string{doctorReportLabel: "true"}
+	secret.Data = map[string][]byte{
+		doctorReportKey:    report,
+		doctorCreatedAtKey: []byte(entry.CreatedAt.UTC().Format(time.RFC3339Nano)),
+		doctorPublishedKey: []byte(strconv.FormatBool(entry.Published)),
+	}
+	if _, err := s.secrets.Create(secret); err != nil {
+		return err
+	}
+	entries, err := s.list()
+	if err != nil {
+		return err
+	}
+	for _, e := range prunedDoctorEntries(entries, s.size) {
+		if err := s.secrets.Delete(doctorReportSecretPrefix + e.UUID); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List is the DoctorHistory interface implementation
+func (s *secretDoctorHistory) List() ([]DoctorReportEntry, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.list()
+}
+
+func (s *secretDoctorHistory) list() ([]DoctorReportEntry, error) {
+	secrets, err := s.secrets.List(api.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{doctorReportLabel: "true"}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]DoctorReportEntry, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		entry, err := secretDoctorEntry(secret)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sort.Sort(byCreatedAt(entries))
+	return entries, nil
+}
+
+// Get is the DoctorHistory interface implementation
+func (s *secretDoctorHistory) Get(uid string) (DoctorReportEntry, models.DoctorInfo, error) {
+	secret, err := s.secrets.Get(doctorReportSecretPrefix + uid)
+	if apierrors.IsNotFound(err) {
+		return DoctorReportEntry{}, models.DoctorInfo{}, ErrDoctorReportNotFound
+	}
+	if err != nil {
+		return DoctorReportEntry{}, models.DoctorInfo{}, err
+	}
+	entry, err := secretDoctorEntry(*secret)
+	if err != nil {
+		return DoctorReportEntry{}, models.DoctorInfo{}, err
+	}
+	doctor, err := decodeDoctorReport(secret.Data[doctorReportKey])
+	return entry, doctor, err
+}
+
+// secretDoctorEntry returns the entry of the report kept in secret
+func secretDoctorEntry(secret api.Secret) (DoctorReportEntry, error) {
+	createdAt, err := time.Parse(time.RFC3339Nano, string(secret.Data[doctorCreatedAtKey]))
+	if err != nil {
+		return DoctorReportEntry{}, err
+	}
+	return DoctorReportEntry{
+		UUID:      strings.TrimPrefix(secret.Name, doctorReportSecretPrefix),
+		CreatedAt: createdAt,
+		Published: string(secret.Data[doctorPublishedKey]) == "true",
+	}, nil
+}
+
+// dirDoctorHistory fulfills the DoctorHistory interface using a directory, such as the mount point
+// of a persistent volume. Each report is kept in its own file, and the kept reports are listed in
+// an index file
+type dirDoctorHistory struct {
+	mut  *sync.Mutex
+	dir  string
+	size int
+}
+
+// NewDoctorHistoryFromDir returns a new DoctorHistory that keeps up to size reports in dir, which is
+// created if it doesn't exist
+func NewDoctorHistoryFromDir(dir string, size int) (DoctorHistory, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &dirDoctorHistory{mut: new(sync.Mutex), dir: dir, size: size}, nil
+}
+
+// Save is the DoctorHistory interface implementation
+func (d *dirDoctorHistory) Save(entry DoctorReportEntry, doctor models.DoctorInfo) error {
+	report, err := encodeDoctorReport(doctor)
+	if err != nil {
+		return err
+	}
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if err := d.writeFile(d.reportFile(entry.UUID), report); err != nil {
+		return err
+	}
+	entries, err := d.list()
+	if err != nil {
+		return err
+	}
+	entries = append(entries, entry)
+	sort.Sort(byCreatedAt(entries))
+	pruned := prunedDoctorEntries(entries, d.size)
+	index, err := json.Marshal(entries[:len(entries)-len(pruned)])
+	if err != nil {
+		return err
+	}
+	// the index is written before the pruned reports are removed, so that it never lists a report
+	// whose file is gone
+	if err := d.writeFile(doctorHistoryIndex, index); err != nil {
+		return err
+	}
+	for _, e := range pruned {
+		if err := os.Remove(filepath.Join(d.dir, d.reportFile(e.UUID))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List is the DoctorHistory interface implementation
+func (d *dirDoctorHistory) List() ([]DoctorReportEntry, error) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.list()
+}
+
+func (d *dirDoctorHistory) list() ([]DoctorReportEntry, error) {
+	b, err := ioutil.ReadFile(filepath.Join(d.dir, doctorHistoryIndex))
+	if os.IsNotExist(err) {
+		return []DoctorReportEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []DoctorReportEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Get is the DoctorHistory interface implementation. Only the reports listed in the index are
+// read, so uid can't name any other file
+func (d *dirDoctorHistory) Get(uid string) (DoctorReportEntry, models.DoctorInfo, error) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	entries, err := d.list()
+	if err != nil {
+		return DoctorReportEntry{}, models.DoctorInfo{}, err
+	}
+	for _, entry := range entries {
+		if entry.UUID != uid {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(d.dir, d.reportFile(uid)))
+		if err != nil {
+			return DoctorReportEntry{}, models.DoctorInfo{}, err
+		}
+		doctor, err := decodeDoctorReport(b)
+		return entry, doctor, err
+	}
+	return DoctorReportEntry{}, models.DoctorInfo{}, ErrDoctorReportNotFound
+}
+
+func (d *dirDoctorHistory) reportFile(uid string) string {
+	return uid + ".json.gz"
+}
+
+// writeFile replaces the file name in d's directory with b. b is written to a temporary file that's
+// then renamed, so that a failed write doesn't leave a partial file behind
+func (d *dirDoctorHistory) writeFile(name string, b []byte) error {
+	tmp, err := ioutil.TempFile(d.dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(d.dir, name))
+}
+
+// prunedDoctorEntries returns the entries beyond the newest size entries in entries, which must be
+// sorted newest first
+func prunedDoctorEntries(entries []DoctorReportEntry, size int) []DoctorReportEntry {
+	if len(entries) <= size {
+		return nil
+	}
+	return entries[size:]
+}
+
+// encodeDoctorReport returns the gzipped JSON representation of doctor
+func encodeDoctorReport(doctor models.DoctorInfo) ([]byte, error) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gzw).Encode(doctor); err != nil {
+		return nil, err
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeDoctorReport returns the report encoded in b by encodeDoctorReport
+func decodeDoctorReport(b []byte) (models.DoctorInfo, error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return models.DoctorInfo{}, err
+	}
+	defer gzr.Close()
+	var doctor models.DoctorInfo
+	if err := json.NewDecoder(gzr).Decode(&doctor); err != nil {
+		return models.DoctorInfo{}, err
+	}
+	return doctor, nil
+}
+
+// byCreatedAt sorts doctor report entries newest first
+type byCreatedAt []DoctorReportEntry
+
+func (b byCreatedAt) Len() int           { return len(b) }
+func (b byCreatedAt) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byCreatedAt) Less(i, j int) bool { return b[i].CreatedAt.After(b[j].CreatedAt) }
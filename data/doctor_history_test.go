@@ -0,0 +1,88 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/labels"
+)
+
+// List and Delete extend mockSecrets to fulfill the k8s.KubeSecretStore interface
+func (m *mockSecrets) List(opts api.ListOptions) (*api.SecretList, error) {
+	list := &api.SecretList{}
+	for _, sec := range m.secrets {
+		if opts.LabelSelector == nil || opts.LabelSelector.Matches(labels.Set(sec.Labels)) {
+			list.Items = append(list.Items, *sec)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockSecrets) Delete(name string) error {
+	delete(m.secrets, name)
+	return nil
+}
+
+// testDoctorHistory saves 3 reports to a history of size 2, and checks that the oldest is dropped
+func testDoctorHistory(t *testing.T, history DoctorHistory) {
+	now := time.Now().UTC()
+	uids := []string{
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b811-9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b812-9dad-11d1-80b4-00c04fd430c8",
+	}
+	for i, uid := range uids {
+		entry := DoctorReportEntry{UUID: uid, CreatedAt: now.Add(time.Duration(i) * time.Minute), Published: i == 1}
+		doctor := models.DoctorInfo{Workflow: &models.Cluster{ID: uid}}
+		assert.NoErr(t, history.Save(entry, doctor))
+	}
+	entries, err := history.List()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(entries), 2, "number of kept reports")
+	assert.Equal(t, entries[0].UUID, uids[2], "newest report UUID")
+	assert.Equal(t, entries[1].UUID, uids[1], "second newest report UUID")
+	assert.True(t, entries[1].Published, "published report wasn't listed as published")
+	assert.True(t, entries[1].CreatedAt.Equal(now.Add(time.Minute)), "creation time was %s", entries[1].CreatedAt)
+
+	entry, doctor, err := history.Get(uids[1])
+	assert.NoErr(t, err)
+	assert.Equal(t, entry.UUID, uids[1], "report UUID")
+	assert.Equal(t, doctor.Workflow.ID, uids[1], "report cluster ID")
+	_, _, err = history.Get(uids[0])
+	assert.Equal(t, err, ErrDoctorReportNotFound, "error for a dropped report")
+}
+
+func TestSecretDoctorHistory(t *testing.T) {
+	secrets := &mockSecrets{secrets: map[string]*api.Secret{
+		// an unrelated secret, which must not be listed or dropped
+		wfmSecretName: {ObjectMeta: api.ObjectMeta{Name: wfmSecretName}},
+	}}
+	testDoctorHistory(t, NewDoctorHistoryFromSecrets(secrets, 2))
+	assert.Equal(t, len(secrets.secrets), 3, "number of secrets")
+	_, ok := secrets.secrets[wfmSecretName]
+	assert.True(t, ok, "unrelated secret was deleted")
+}
+
+func TestDirDoctorHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doctor")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+	history, err := NewDoctorHistoryFromDir(filepath.Join(dir, "history"), 2)
+	assert.NoErr(t, err)
+	entries, err := history.List()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(entries), 0, "number of reports in an empty history")
+	testDoctorHistory(t, history)
+	files, err := ioutil.ReadDir(filepath.Join(dir, "history"))
+	assert.NoErr(t, err)
+	// the index and the 2 kept reports
+	assert.Equal(t, len(files), 3, "number of files")
+	_, _, err = history.Get("../index")
+	assert.Equal(t, err, ErrDoctorReportNotFound, "error for a path outside the history")
+}
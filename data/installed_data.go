@@ -1,99 +1,205 @@
 package data
 
 import (
-	"encoding/json"
-
 	"github.com/deis/workflow-manager/k8s"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/labels"
 )
 
-var (
+// the component types of the workloads that make up a cluster. StatefulSets and CronJobs are
+// missing: they're served by the apps/v1beta1 and batch/v2alpha1 APIs, which the vendored k8s 1.2
+// client doesn't support
+const (
 	daemonSetType  = "Daemon Set"
 	deploymentType = "Deployment"
+	jobType        = "Job"
+	replicaSetType = "Replica Set"
 	rcType         = "Replication Controller"
 )
 
 const versionAnnotation = "component.deis.io/version"
 
+// deploymentHashLabel is the label that the Deployment controller adds to every ReplicaSet it creates
+const deploymentHashLabel = "pod-template-hash"
+
 // InstalledData is an interface for managing installed cluster metadata
 type InstalledData interface {
 	// will have a Get method to retrieve installed data
-	Get() ([]byte, error)
+	Get() (models.Cluster, error)
 }
 
 // InstalledDeisData fulfills the InstalledData interface
 type installedDeisData struct {
-	k8sResources *k8s.ResourceInterfaceNamespaced
+	components k8s.ComponentLister
 }
 
-// NewInstalledDeisData returns a new InstalledDeisData using rcl as the rc.Lister implementation
-func NewInstalledDeisData(ri *k8s.ResourceInterfaceNamespaced) InstalledData {
-	return &installedDeisData{k8sResources: ri}
+// NewInstalledDeisData returns a new InstalledDeisData using components as the k8s.ComponentLister
+// implementation. Pass a *k8s.Inventory to avoid calling the k8s API on every Get
+func NewInstalledDeisData(components k8s.ComponentLister) InstalledData {
+	return &installedDeisData{components: components}
 }
 
 // Get method for InstalledDeisData
-func (g *installedDeisData) Get() ([]byte, error) {
+func (g *installedDeisData) Get() (models.Cluster, error) {
 	var cluster models.Cluster
-	deployments, err := k8s.GetDeployments(g.k8sResources.Deployments())
+	pods, err := g.components.Pods()
+	if err != nil {
+		return models.Cluster{}, err
+	}
+	deployments, err := g.components.Deployments()
 	if err != nil {
-		return nil, err
+		return models.Cluster{}, err
 	}
 	for _, deployment := range deployments {
-		component := models.ComponentVersion{
-			Component: &models.Component{
-				Name: deployment.Name,
-				Type: &deploymentType,
-			},
-			Version: &models.Version{
-				Version: deployment.Annotations[versionAnnotation],
-				Data: &models.VersionData{
-					Image: &deployment.Spec.Template.Spec.Containers[0].Image,
-				},
-			},
+		selector, err := unversioned.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return models.Cluster{}, err
 		}
-		cluster.Components = append(cluster.Components, &component)
+		deploymentPods := selectPods(pods, deployment.Namespace, selector)
+		cluster.Components = append(cluster.Components, newComponentVersion(
+			deployment.ObjectMeta,
+			deploymentType,
+			deployment.Spec.Template,
+			deploymentPods,
+			deploymentStatus(deployment, deploymentPods),
+		))
 	}
-	daemonSets, err := k8s.GetDaemonSets(g.k8sResources.DaemonSets())
+	daemonSets, err := g.components.DaemonSets()
 	if err != nil {
-		return nil, err
+		return models.Cluster{}, err
 	}
 	for _, daemonSet := range daemonSets {
-		component := models.ComponentVersion{
-			Component: &models.Component{
-				Name: daemonSet.Name,
-				Type: &daemonSetType,
-			},
-			Version: &models.Version{
-				Version: daemonSet.Annotations[versionAnnotation],
-				Data: &models.VersionData{
-					Image: &daemonSet.Spec.Template.Spec.Containers[0].Image,
-				},
-			},
+		selector, err := unversioned.LabelSelectorAsSelector(daemonSet.Spec.Selector)
+		if err != nil {
+			return models.Cluster{}, err
 		}
-		cluster.Components = append(cluster.Components, &component)
+		daemonSetPods := selectPods(pods, daemonSet.Namespace, selector)
+		cluster.Components = append(cluster.Components, newComponentVersion(
+			daemonSet.ObjectMeta,
+			daemonSetType,
+			daemonSet.Spec.Template,
+			daemonSetPods,
+			daemonSetStatus(daemonSet, daemonSetPods),
+		))
 	}
-	replicationControllers, err := k8s.GetReplicationControllers(g.k8sResources.ReplicationControllers())
+	replicaSets, err := g.components.ReplicaSets()
 	if err != nil {
-		return nil, err
+		return models.Cluster{}, err
+	}
+	for _, replicaSet := range replicaSets {
+		// the replica sets managed by deployments are already accounted for by their deployment
+		if ownedByDeployment(replicaSet, deployments) {
+			continue
+		}
+		selector, err := unversioned.LabelSelectorAsSelector(replicaSet.Spec.Selector)
+		if err != nil {
+			return models.Cluster{}, err
+		}
+		replicaSetPods := selectPods(pods, replicaSet.Namespace, selector)
+		cluster.Components = append(cluster.Components, newComponentVersion(
+			replicaSet.ObjectMeta,
+			replicaSetType,
+			replicaSet.Spec.Template,
+			replicaSetPods,
+			replicasStatus(int64(replicaSet.Spec.Replicas), replicaSetPods),
+		))
+	}
+	replicationControllers, err := g.components.ReplicationControllers()
+	if err != nil {
+		return models.Cluster{}, err
 	}
 	for _, rc := range replicationControllers {
-		component := models.ComponentVersion{
-			Component: &models.Component{
-				Name: rc.Name,
-				Type: &rcType,
-			},
-			Version: &models.Version{
-				Version: rc.Annotations[versionAnnotation],
-				Data: &models.VersionData{
-					Image: &rc.Spec.Template.Spec.Containers[0].Image,
-				},
-			},
+		var template api.PodTemplateSpec
+		if rc.Spec.Template != nil {
+			template = *rc.Spec.Template
 		}
-		cluster.Components = append(cluster.Components, &component)
+		rcPods := selectPods(pods, rc.Namespace, labels.SelectorFromSet(labels.Set(rc.Spec.Selector)))
+		cluster.Components = append(cluster.Components, newComponentVersion(
+			rc.ObjectMeta,
+			rcType,
+			template,
+			rcPods,
+			replicasStatus(int64(rc.Spec.Replicas), rcPods),
+		))
 	}
-	js, err := json.Marshal(cluster)
+	jobs, err := g.components.Jobs()
 	if err != nil {
-		return []byte{}, err
+		return models.Cluster{}, err
+	}
+	for _, job := range jobs {
+		selector, err := unversioned.LabelSelectorAsSelector(job.Spec.Selector)
+		if err != nil {
+			return models.Cluster{}, err
+		}
+		jobPods := selectPods(pods, job.Namespace, selector)
+		cluster.Components = append(cluster.Components, newComponentVersion(
+			job.ObjectMeta,
+			jobType,
+			job.Spec.Template,
+			jobPods,
+			jobStatus(job, jobPods),
+		))
+	}
+	return cluster, nil
+}
+
+// ownedByDeployment returns true if replicaSet was created by a Deployment: if it carries the
+// Deployment controller's hash label, or if its labels are matched by the selector of one of
+// deployments in the same namespace. The label is what matters, since deployments only holds the
+// Deployments that are components, and a ReplicaSet of an excluded Deployment isn't a component
+// either
+func ownedByDeployment(replicaSet extensions.ReplicaSet, deployments []extensions.Deployment) bool {
+	if _, ok := replicaSet.Labels[deploymentHashLabel]; ok {
+		return true
+	}
+	for _, deployment := range deployments {
+		if deployment.Namespace != replicaSet.Namespace {
+			continue
+		}
+		selector, err := unversioned.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		if !selector.Empty() && selector.Matches(labels.Set(replicaSet.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// newComponentVersion returns the models.ComponentVersion of the component described by meta, of
+// type componentType, whose pods are created from template. pods are the component's pods, which
+// are compared to template to determine whether each container is up to date. status is the live
+// status of the component's workload
+func newComponentVersion(
+	meta api.ObjectMeta,
+	componentType string,
+	template api.PodTemplateSpec,
+	pods []api.Pod,
+	status *models.ComponentStatus,
+) *models.ComponentVersion {
+	containers := containerImages(template, pods)
+	var namespace *string
+	if meta.Namespace != "" {
+		namespace = &meta.Namespace
+	}
+	return &models.ComponentVersion{
+		Component: &models.Component{
+			Name:      meta.Name,
+			Namespace: namespace,
+			Type:      &componentType,
+		},
+		Status: status,
+		Version: &models.Version{
+			Version: meta.Annotations[versionAnnotation],
+			Train:   ComponentTrain(meta.Name, meta.Annotations),
+			Data: &models.VersionData{
+				Image:      firstImage(containers),
+				Containers: containers,
+			},
+		},
 	}
-	return js, nil
 }
@@ -3,25 +3,230 @@ package data
 import (
 	"testing"
 
+	"github.com/arschles/assert"
 	"github.com/deis/workflow-manager/k8s"
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/api/testapi"
+	"k8s.io/kubernetes/pkg/api/unversioned"
 	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/client/unversioned/testclient/simple"
 )
 
 const namespace = "deis"
 
+// Creating a novel mock struct that fulfills the k8s.ComponentLister interface
+type mockComponentLister struct {
+	deployments []extensions.Deployment
+	daemonSets  []extensions.DaemonSet
+	replicaSets []extensions.ReplicaSet
+	rcs         []api.ReplicationController
+	jobs        []extensions.Job
+	pods        []api.Pod
+}
+
+func (m mockComponentLister) Deployments() ([]extensions.Deployment, error) {
+	return m.deployments, nil
+}
+
+func (m mockComponentLister) DaemonSets() ([]extensions.DaemonSet, error) {
+	return m.daemonSets, nil
+}
+
+func (m mockComponentLister) ReplicaSets() ([]extensions.ReplicaSet, error) {
+	return m.replicaSets, nil
+}
+
+func (m mockComponentLister) ReplicationControllers() ([]api.ReplicationController, error) {
+	return m.rcs, nil
+}
+
+func (m mockComponentLister) Jobs() ([]extensions.Job, error) {
+	return m.jobs, nil
+}
+
+func (m mockComponentLister) Pods() ([]api.Pod, error) {
+	return m.pods, nil
+}
+
 func TestInstalledDeisData(t *testing.T) {
 	client := getK8sClient(t)
 	k := k8s.NewResourceInterfaceNamespaced(client, namespace)
-	installedData := installedDeisData{
-		k8sResources: k,
-	}
+	installedData := NewInstalledDeisData(k8s.NewComponentLister(k))
 	_, _ = installedData.Get()
 	//TODO: we need to create a fake client interface that is a union of api+extension fake clients
 }
 
+func TestInstalledDeisDataFromLister(t *testing.T) {
+	lister := mockComponentLister{
+		deployments: []extensions.Deployment{
+			getTestDeployment("deis-builder", "builder-image"),
+			getTestDeployment("deis-controller", "controller-image"),
+		},
+	}
+	cluster, err := NewInstalledDeisData(lister).Get()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(cluster.Components), 2, "number of components")
+	assert.Equal(t, cluster.Components[0].Component.Name, "deis-builder", "first component name")
+	assert.Equal(t, *cluster.Components[0].Version.Data.Image, "builder-image", "first component image")
+	assert.Equal(t, cluster.Components[1].Component.Name, "deis-controller", "second component name")
+	assert.Equal(t, *cluster.Components[1].Version.Data.Image, "controller-image", "second component image")
+}
+
+func TestInstalledDeisDataContainers(t *testing.T) {
+	router := getTestDeployment("deis-router", "quay.io/deis/router:v2.3.0")
+	router.Spec.Template.Spec.Containers = append(
+		router.Spec.Template.Spec.Containers,
+		api.Container{Name: "sidecar", Image: "quay.io/deis/sidecar:v1.0.0"},
+	)
+	router.Spec.Replicas = 1
+	empty := getTestDeployment("deis-empty", "")
+	empty.Spec.Template.Spec.Containers = nil
+	lister := mockComponentLister{
+		deployments: []extensions.Deployment{router, empty},
+		pods: []api.Pod{
+			getTestPod("deis-router", "quay.io/deis/router:v2.3.0", "docker-pullable://quay.io/deis/router@sha256:abc"),
+			// a pod that hasn't been rolled out yet
+			getTestPod("deis-router", "quay.io/deis/router:v2.2.0", "docker-pullable://quay.io/deis/router@sha256:def"),
+		},
+	}
+	cluster, err := NewInstalledDeisData(lister).Get()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(cluster.Components), 2, "number of components")
+	containers := cluster.Components[0].Version.Data.Containers
+	assert.Equal(t, len(containers), 2, "number of router containers")
+	assert.Equal(t, containers[0].Name, "deis-router", "router container name")
+	assert.Equal(t, containers[0].Tag, "v2.3.0", "router container tag")
+	assert.Equal(t, containers[0].Digest, "sha256:abc", "router container digest")
+	assert.False(t, *containers[0].UpToDate, "half rolled out router container was up to date")
+	assert.Equal(t, containers[1].Name, "sidecar", "sidecar container name")
+	assert.False(t, *containers[1].UpToDate, "sidecar container missing from pods was up to date")
+	assert.Equal(t, cluster.Components[0].Status.Desired, int64(1), "router desired replicas")
+	assert.False(t, cluster.Components[0].Status.Healthy, "router without ready pods was healthy")
+	// a component without containers must not panic, and has no image
+	assert.True(t, cluster.Components[1].Version.Data.Image == nil, "image of a component without containers")
+	assert.Equal(t, len(cluster.Components[1].Version.Data.Containers), 0, "number of containers")
+}
+
+func TestInstalledDeisDataReplicaSets(t *testing.T) {
+	registry := getTestDeployment("deis-registry", "quay.io/deis/registry:v2.2.0")
+	// a replica set created by the registry deployment, and a bare one
+	managed := getTestReplicaSet("deis-registry-1234", "deis-registry", "quay.io/deis/registry:v2.2.0")
+	bare := getTestReplicaSet("deis-registry-proxy", "deis-registry-proxy", "quay.io/deis/registry-proxy:v1.0.0")
+	lister := mockComponentLister{
+		deployments: []extensions.Deployment{registry},
+		replicaSets: []extensions.ReplicaSet{managed, bare},
+		pods:        []api.Pod{getTestPod("deis-registry-proxy", "quay.io/deis/registry-proxy:v1.0.0", "docker://sha256:abc")},
+	}
+	cluster, err := NewInstalledDeisData(lister).Get()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(cluster.Components), 2, "number of components")
+	assert.Equal(t, cluster.Components[0].Component.Name, "deis-registry", "deployment component name")
+	assert.Equal(t, cluster.Components[1].Component.Name, "deis-registry-proxy", "replica set component name")
+	assert.Equal(t, *cluster.Components[1].Component.Type, replicaSetType, "replica set component type")
+	assert.Equal(t, *cluster.Components[1].Version.Data.Image, "quay.io/deis/registry-proxy:v1.0.0", "replica set component image")
+	assert.Equal(t, cluster.Components[1].Version.Data.Containers[0].Digest, "sha256:abc", "replica set container digest")
+}
+
+func TestInstalledDeisDataIgnoredDeploymentReplicaSets(t *testing.T) {
+	registry := getTestDeployment("deis-registry", "quay.io/deis/registry:v2.2.0")
+	registry.Annotations = map[string]string{k8s.IgnoreAnnotation: "true"}
+	// the replica set of the ignored deployment carries neither its labels nor its annotations
+	managed := getTestReplicaSet("deis-registry-1234", "deis-registry-v2", "quay.io/deis/registry:v2.2.0")
+	managed.Labels[deploymentHashLabel] = "1234"
+	filter := k8s.ComponentFilter{}
+	var deployments []extensions.Deployment
+	for _, d := range []extensions.Deployment{registry} {
+		if filter.Matches(d.ObjectMeta) {
+			deployments = append(deployments, d)
+		}
+	}
+	lister := mockComponentLister{
+		deployments: deployments,
+		replicaSets: []extensions.ReplicaSet{managed},
+	}
+	cluster, err := NewInstalledDeisData(lister).Get()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(cluster.Components), 0, "number of components")
+}
+
+func TestInstalledDeisDataJobs(t *testing.T) {
+	job := extensions.Job{
+		ObjectMeta: api.ObjectMeta{
+			Name:        "deis-database-backup",
+			Annotations: map[string]string{versionAnnotation: "v2.2.0"},
+		},
+		Spec: extensions.JobSpec{
+			Selector: &unversioned.LabelSelector{MatchLabels: map[string]string{"app": "deis-database-backup"}},
+			Template: api.PodTemplateSpec{
+				Spec: api.PodSpec{Containers: []api.Container{{Name: "deis-database-backup", Image: "quay.io/deis/postgres:v2.2.0"}}},
+			},
+		},
+	}
+	cluster, err := NewInstalledDeisData(mockComponentLister{jobs: []extensions.Job{job}}).Get()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(cluster.Components), 1, "number of components")
+	assert.Equal(t, cluster.Components[0].Component.Name, "deis-database-backup", "job component name")
+	assert.Equal(t, *cluster.Components[0].Component.Type, jobType, "job component type")
+	assert.Equal(t, cluster.Components[0].Version.Version, "v2.2.0", "job component version")
+	assert.Equal(t, *cluster.Components[0].Version.Data.Image, "quay.io/deis/postgres:v2.2.0", "job component image")
+}
+
+func TestInstalledDeisDataNamespaces(t *testing.T) {
+	deisRouter := getTestDeployment("deis-router", "quay.io/deis/router:v2.3.0")
+	deisRouter.Namespace = "deis"
+	appRouter := getTestDeployment("deis-router", "quay.io/deis/router:v2.2.0")
+	appRouter.Namespace = "my-app"
+	pod := getTestPod("deis-router", "quay.io/deis/router:v2.2.0", "docker://sha256:abc")
+	pod.Namespace = "my-app"
+	cluster, err := NewInstalledDeisData(mockComponentLister{
+		deployments: []extensions.Deployment{deisRouter, appRouter},
+		pods:        []api.Pod{pod},
+	}).Get()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(cluster.Components), 2, "number of components")
+	assert.Equal(t, *cluster.Components[0].Component.Namespace, "deis", "first component namespace")
+	assert.Equal(t, *cluster.Components[1].Component.Namespace, "my-app", "second component namespace")
+	// the pod in the my-app namespace doesn't belong to the deis router
+	assert.Equal(t, cluster.Components[0].Version.Data.Containers[0].Digest, "", "deis router digest")
+	assert.Equal(t, cluster.Components[1].Version.Data.Containers[0].Digest, "sha256:abc", "my-app router digest")
+}
+
+func getTestReplicaSet(name, app, image string) extensions.ReplicaSet {
+	return extensions.ReplicaSet{
+		ObjectMeta: api.ObjectMeta{Name: name, Labels: map[string]string{"app": app}},
+		Spec: extensions.ReplicaSetSpec{
+			Selector: &unversioned.LabelSelector{MatchLabels: map[string]string{"app": app}},
+			Template: api.PodTemplateSpec{
+				ObjectMeta: api.ObjectMeta{Labels: map[string]string{"app": app}},
+				Spec:       api.PodSpec{Containers: []api.Container{{Name: app, Image: image}}},
+			},
+		},
+	}
+}
+
+func getTestDeployment(name, image string) extensions.Deployment {
+	return extensions.Deployment{
+		ObjectMeta: api.ObjectMeta{Name: name},
+		Spec: extensions.DeploymentSpec{
+			Selector: &unversioned.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			Template: api.PodTemplateSpec{
+				ObjectMeta: api.ObjectMeta{Labels: map[string]string{"app": name}},
+				Spec:       api.PodSpec{Containers: []api.Container{{Name: name, Image: image}}},
+			},
+		},
+	}
+}
+
+func getTestPod(app, image, imageID string) api.Pod {
+	return api.Pod{
+		ObjectMeta: api.ObjectMeta{Labels: map[string]string{"app": app}},
+		Spec:       api.PodSpec{Containers: []api.Container{{Name: app, Image: image}}},
+		Status: api.PodStatus{
+			ContainerStatuses: []api.ContainerStatus{{Name: app, Image: image, ImageID: imageID}},
+		},
+	}
+}
+
 func getK8sClient(t *testing.T) *simple.Client {
 	c := &simple.Client{
 		Request: simple.Request{
@@ -0,0 +1,361 @@
+package data
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+// the names of the rules recorded in a report's redactions
+const (
+	envValueRule    = "env-value"
+	keyPatternRule  = "key-pattern"
+	addressHashRule = "address-hash"
+)
+
+// redactedValue replaces the values that are dropped from a report
+const redactedValue = "[REDACTED]"
+
+// addressKeys are the keys of the k8s resource fields that hold an IP address or a hostname
+var addressKeys = map[string]bool{
+	"address":                true,
+	"clusterIP":              true,
+	"externalIPs":            true,
+	"externalName":           true,
+	"host":                   true,
+	"hostIP":                 true,
+	"hostname":               true,
+	"ip":                     true,
+	"kubernetes.io/hostname": true,
+	"loadBalancerIP":         true,
+	"nodeName":               true,
+	"podCIDR":                true,
+	"podIP":                  true,
+}
+
+// logFieldRegexp matches the key=value and key: value pairs in log lines, including JSON fields.
+// The submatches are the key's opening quote, the key, the separator and the value
+var logFieldRegexp = regexp.MustCompile(`("?)([A-Za-z_][\w.-]*)("?[ \t]*[:=][ \t]*"?)([^\s",}&?]+)`)
+
+// ipv4Regexp matches the IPv4 addresses embedded in free form strings, such as event messages
+var ipv4Regexp = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+
+// RedactionRules configures how a doctor report is scrubbed before it leaves the cluster
+type RedactionRules struct {
+	// DropEnvValues replaces the value of every container environment variable
+	DropEnvValues bool
+	// KeyPatterns are matched against the keys of every field, including annotations and labels,
+	// and the names of environment variables. The values of the fields that match are replaced
+	KeyPatterns []*regexp.Regexp
+	// HashAddresses replaces IP addresses and hostnames with a keyed hash, so that they can still be
+	// correlated within a report, and across reports
+	HashAddresses bool
+	// HashKey is the key that addresses are hashed with. It's required if HashAddresses is set
+	HashKey RedactionKey
+}
+
+// NewRedactionRules returns RedactionRules, parsing keyPatterns as a comma separated list of
+// regular expressions
+func NewRedactionRules(dropEnvValues bool, keyPatterns string, hashAddresses bool) (RedactionRules, error) {
+	rules := RedactionRules{DropEnvValues: dropEnvValues, HashAddresses: hashAddresses}
+	for _, pattern := range strings.Split(keyPatterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return RedactionRules{}, fmt.Errorf("invalid redaction key pattern %q (%s)", pattern, err)
+		}
+		rules.KeyPatterns = append(rules.KeyPatterns, re)
+	}
+	return rules, nil
+}
+
+// ConfiguredRedactionRules returns the RedactionRules configured in config.Spec, which hash
+// addresses with hashKey
+func ConfiguredRedactionRules(hashKey RedactionKey) (RedactionRules, error) {
+	rules, err := NewRedactionRules(
+		config.Spec.RedactEnvValues,
+		config.Spec.RedactKeyPatterns,
+		config.Spec.RedactHashAddresses,
+	)
+	if err != nil {
+		return RedactionRules{}, err
+	}
+	rules.HashKey = hashKey
+	return rules, nil
+}
+
+// matchesKey returns true if key matches one of r's key patterns
+func (r RedactionRules) matchesKey(key string) bool {
+	for _, re := range r.KeyPatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactDoctorInfo scrubs the k8s resources, node summaries, logs and findings in doctor according
+// to rules, and appends the path of every field it changed to doctor.Redactions. Addresses are
+// hashed with rules.HashKey, which is never included in the report. Every resource's Data is
+// replaced with its scrubbed JSON representation
+func RedactDoctorInfo(doctor *models.DoctorInfo, rules RedactionRules) error {
+	r := &redactor{rules: rules}
+	if rules.HashAddresses {
+		if rules.HashKey == nil {
+			return errNoRedactionKey
+		}
+		key, err := rules.HashKey.Get()
+		if err != nil {
+			return fmt.Errorf("getting the redaction key (%s)", err)
+		}
+		r.hashKey = key
+	}
+	for i, node := range doctor.Nodes {
+		if err := r.redactResource(fmt.Sprintf("nodes[%d]", i), node, true); err != nil {
+			return err
+		}
+	}
+	for i, summary := range doctor.NodeSummaries {
+		if summary != nil && r.rules.HashAddresses {
+			summary.Name = r.hash(summary.Name)
+			r.record(fmt.Sprintf("nodeSummaries[%d].name", i), addressHashRule)
+		}
+	}
+	for _, ns := range doctor.Namespaces {
+		if ns == nil {
+			continue
+		}
+		for kind, resources := range map[string][]*models.K8sResource{
+			"daemonSets":             ns.DaemonSets,
+			"deployments":            ns.Deployments,
+			"events":                 ns.Events,
+			"pods":                   ns.Pods,
+			"replicaSets":            ns.ReplicaSets,
+			"replicationControllers": ns.ReplicationControllers,
+			"services":               ns.Services,
+		} {
+			for i, res := range resources {
+				path := fmt.Sprintf("namespaces[%s].%s[%d]", ns.Name, kind, i)
+				if err := r.redactResource(path, res, false); err != nil {
+					return err
+				}
+			}
+		}
+		for i, l := range ns.Logs {
+			if l != nil {
+				l.Log = r.redactText(fmt.Sprintf("namespaces[%s].logs[%d].log", ns.Name, i), l.Log)
+			}
+		}
+	}
+	for i, f := range doctor.Findings {
+		if f == nil {
+			continue
+		}
+		path := fmt.Sprintf("findings[%d]", i)
+		// node names are hostnames
+		if f.Kind == "Node" && r.rules.HashAddresses {
+			f.Name = r.hash(f.Name)
+			r.record(path+".name", addressHashRule)
+		}
+		f.Message = r.redactText(path+".message", f.Message)
+	}
+	sort.Sort(redactionsByPath(r.redactions))
+	doctor.Redactions = append(doctor.Redactions, r.redactions...)
+	return nil
+}
+
+// redactor scrubs k8s resources, recording what it scrubs
+type redactor struct {
+	rules      RedactionRules
+	hashKey    []byte
+	redactions []*models.Redaction
+}
+
+// redactResource scrubs res.Data. Node names are hostnames, so they're hashed too if isNode is true
+func (r *redactor) redactResource(path string, res *models.K8sResource, isNode bool) error {
+	if res == nil || res.Data == nil {
+		return nil
+	}
+	b, err := json.Marshal(res.Data)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var obj interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return err
+	}
+	if meta, ok := nestedMap(obj, "metadata"); ok && isNode && r.rules.HashAddresses {
+		if name, ok := meta["name"].(string); ok {
+			meta["name"] = r.hash(name)
+			r.record(path+".data.metadata.name", addressHashRule)
+		}
+		// the self link embeds the node name
+		delete(meta, "selfLink")
+	}
+	res.Data = r.walk(path+".data", obj)
+	return nil
+}
+
+// walk returns v with its fields scrubbed
+func (r *redactor) walk(path string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(val) {
+			childPath := path + "." + k
+			child := val[k]
+			switch {
+			case k == "env":
+				val[k] = r.redactEnv(childPath, child)
+			case isScalar(child) && r.rules.matchesKey(k):
+				val[k] = redactedValue
+				r.record(childPath, keyPatternRule)
+			case r.rules.HashAddresses && addressKeys[k]:
+				val[k] = r.hashAddresses(childPath, child)
+			default:
+				val[k] = r.walk(childPath, child)
+			}
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = r.walk(fmt.Sprintf("%s[%d]", path, i), child)
+		}
+		return val
+	case string:
+		if r.rules.HashAddresses && ipv4Regexp.MatchString(val) {
+			r.record(path, addressHashRule)
+			return ipv4Regexp.ReplaceAllStringFunc(val, r.hash)
+		}
+		return val
+	}
+	return v
+}
+
+// redactText scrubs free form text, such as a container's log. Values of key=value pairs whose keys
+// match a key pattern are replaced, and IPv4 addresses are hashed
+func (r *redactor) redactText(path string, text string) string {
+	if len(r.rules.KeyPatterns) > 0 {
+		redacted := false
+		text = logFieldRegexp.ReplaceAllStringFunc(text, func(field string) string {
+			m := logFieldRegexp.FindStringSubmatch(field)
+			if !r.rules.matchesKey(m[2]) {
+				return field
+			}
+			redacted = true
+			return m[1] + m[2] + m[3] + redactedValue
+		})
+		if redacted {
+			r.record(path, keyPatternRule)
+		}
+	}
+	if r.rules.HashAddresses && ipv4Regexp.MatchString(text) {
+		text = ipv4Regexp.ReplaceAllStringFunc(text, r.hash)
+		r.record(path, addressHashRule)
+	}
+	return text
+}
+
+// redactEnv scrubs a container's environment variables
+func (r *redactor) redactEnv(path string, v interface{}) interface{} {
+	vars, ok := v.([]interface{})
+	if !ok {
+		return r.walk(path, v)
+	}
+	for i, item := range vars {
+		envVar, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		valuePath := fmt.Sprintf("%s[%d].value", path, i)
+		if _, hasValue := envVar["value"]; hasValue {
+			name, _ := envVar["name"].(string)
+			if r.rules.DropEnvValues {
+				envVar["value"] = redactedValue
+				r.record(valuePath, envValueRule)
+				continue
+			} else if r.rules.matchesKey(name) {
+				envVar["value"] = redactedValue
+				r.record(valuePath, keyPatternRule)
+				continue
+			}
+		}
+		vars[i] = r.walk(fmt.Sprintf("%s[%d]", path, i), envVar)
+	}
+	return vars
+}
+
+// hashAddresses hashes an address field, which is either a string or a list of strings
+func (r *redactor) hashAddresses(path string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if val == "" {
+			return val
+		}
+		r.record(path, addressHashRule)
+		return r.hash(val)
+	case []interface{}:
+		for i, item := range val {
+			val[i] = r.hashAddresses(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+		return val
+	}
+	return r.walk(path, v)
+}
+
+// hash returns a keyed hash of s
+func (r *redactor) hash(s string) string {
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(s))
+	return "hash:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+func (r *redactor) record(path, rule string) {
+	r.redactions = append(r.redactions, &models.Redaction{Path: path, Rule: rule})
+}
+
+// nestedMap returns the map at key in obj, if obj is a map
+func nestedMap(obj interface{}, key string) (map[string]interface{}, bool) {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	nested, ok := m[key].(map[string]interface{})
+	return nested, ok
+}
+
+// isScalar returns true if v is a JSON string, number or boolean
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, json.Number, bool:
+		return true
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type redactionsByPath []*models.Redaction
+
+func (r redactionsByPath) Len() int           { return len(r) }
+func (r redactionsByPath) Less(i, j int) bool { return r[i].Path < r[j].Path }
+func (r redactionsByPath) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
@@ -0,0 +1,91 @@
+package data
+
+import (
+	"crypto/rand"
+	"errors"
+	"sync"
+
+	"github.com/deis/workflow-manager/k8s"
+	"k8s.io/kubernetes/pkg/api"
+	apierrors "k8s.io/kubernetes/pkg/api/errors"
+)
+
+// redactionKeyBytes is the length of a generated redaction key
+const redactionKeyBytes = 32
+
+// errNoRedactionKey is returned by RedactDoctorInfo if addresses are to be hashed without a key
+var errNoRedactionKey = errors.New("no redaction key to hash addresses with")
+
+// RedactionKey is an interface for managing the key that addresses are hashed with in doctor
+// reports. The key must never be included in a report, or the hashes could be reversed by brute
+// force, and must be stable, so that the hashes of the same address match across reports
+type RedactionKey interface {
+	// Get returns the key
+	Get() ([]byte, error)
+}
+
+// redactionKeyFromPersistentStorage fulfills the RedactionKey interface by keeping the key in the
+// deis-workflow-manager secret, next to the cluster ID
+type redactionKeyFromPersistentStorage struct {
+	rwm     *sync.RWMutex
+	cache   []byte
+	secrets k8s.KubeSecretGetterCreatorUpdater
+}
+
+// NewRedactionKeyFromPersistentStorage returns a new RedactionKey that uses secrets to get the key
+// from the deis-workflow-manager secret. A random key is generated and stored there the first time
+// it's needed, and is cached in memory after that
+func NewRedactionKeyFromPersistentStorage(secrets k8s.KubeSecretGetterCreatorUpdater) RedactionKey {
+	return &redactionKeyFromPersistentStorage{
+		rwm:     new(sync.RWMutex),
+		secrets: secrets,
+	}
+}
+
+// Get is the RedactionKey interface implementation
+func (r *redactionKeyFromPersistentStorage) Get() ([]byte, error) {
+	r.rwm.RLock()
+	key := r.cache
+	r.rwm.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+	r.rwm.Lock()
+	defer r.rwm.Unlock()
+	if r.cache != nil {
+		return r.cache, nil
+	}
+	secret, err := r.secrets.Get(wfmSecretName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err != nil {
+		secret = nil
+	}
+	if secret != nil && len(secret.Data[redactionKeySecretKey]) > 0 {
+		r.cache = secret.Data[redactionKeySecretKey]
+		return r.cache, nil
+	}
+	key = make([]byte, redactionKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		newSecret := new(api.Secret)
+		newSecret.Name = wfmSecretName
+		newSecret.Data = map[string][]byte{redactionKeySecretKey: key}
+		secret, err = r.secrets.Create(newSecret)
+	} else {
+		// the secret holds the cluster ID too, which is kept
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		secret.Data[redactionKeySecretKey] = key
+		secret, err = r.secrets.Update(secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.cache = secret.Data[redactionKeySecretKey]
+	return r.cache, nil
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func TestRedactionKeyFromPersistentStorage(t *testing.T) {
+	secrets := &mockSecrets{secrets: map[string]*api.Secret{
+		wfmSecretName: {
+			ObjectMeta: api.ObjectMeta{Name: wfmSecretName},
+			Data:       map[string][]byte{clusterIDSecretKey: []byte("cluster-id")},
+		},
+	}}
+	key, err := NewRedactionKeyFromPersistentStorage(secrets).Get()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(key), redactionKeyBytes, "key length")
+	stored := secrets.secrets[wfmSecretName].Data
+	assert.True(t, bytes.Equal(stored[redactionKeySecretKey], key), "key wasn't stored")
+	assert.Equal(t, string(stored[clusterIDSecretKey]), "cluster-id", "cluster ID")
+
+	// the stored key is used after a restart
+	again, err := NewRedactionKeyFromPersistentStorage(secrets).Get()
+	assert.NoErr(t, err)
+	assert.True(t, bytes.Equal(again, key), "key changed after a restart")
+	assert.Equal(t, secrets.updates, 1, "number of secret updates")
+}
+
+func TestRedactionKeyFromPersistentStorageCreate(t *testing.T) {
+	secrets := &mockSecrets{secrets: map[string]*api.Secret{}}
+	key, err := NewRedactionKeyFromPersistentStorage(secrets).Get()
+	assert.NoErr(t, err)
+	assert.True(t, bytes.Equal(secrets.secrets[wfmSecretName].Data[redactionKeySecretKey], key), "key wasn't stored")
+}
@@ -0,0 +1,160 @@
+package data
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+// Creating a novel mock struct that fulfills the RedactionKey interface
+type testRedactionKey []byte
+
+func (k testRedactionKey) Get() ([]byte, error) {
+	return k, nil
+}
+
+func getTestDoctorInfo() models.DoctorInfo {
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			Name: "deis-database-1",
+			Annotations: map[string]string{
+				"kubectl.kubernetes.io/last-applied-configuration": `{"spec":{}}`,
+				"deis.io/owner": "ops",
+			},
+		},
+		Spec: api.PodSpec{
+			NodeName: "node-1.internal",
+			Containers: []api.Container{{
+				Name: "deis-database",
+				Env: []api.EnvVar{
+					{Name: "DATABASE_PASSWORD", Value: "hunter2"},
+					{Name: "DATABASE_NAME", Value: "deis"},
+				},
+			}},
+		},
+		Status: api.PodStatus{PodIP: "10.2.3.4", HostIP: "192.168.0.10"},
+	}
+	event := &api.Event{Message: "Pulled image on 192.168.0.10"}
+	node := &api.Node{ObjectMeta: api.ObjectMeta{Name: "node-1.internal"}}
+	return models.DoctorInfo{
+		Workflow: &models.Cluster{ID: "cluster-id"},
+		Nodes:    []*models.K8sResource{{Data: node}},
+		Namespaces: []*models.Namespace{{
+			Name:   "deis",
+			Pods:   []*models.K8sResource{{Data: pod}},
+			Events: []*models.K8sResource{{Data: event}},
+		}},
+		Findings: []*models.Finding{{
+			Check:    "node-pressure",
+			Severity: "critical",
+			Kind:     "Node",
+			Name:     "node-1.internal",
+			Message:  "node is not ready (kubelet on 192.168.0.10 stopped posting status)",
+		}},
+	}
+}
+
+func TestRedactDoctorInfo(t *testing.T) {
+	rules, err := NewRedactionRules(true, "(?i)passw, last-applied-configuration", true)
+	assert.NoErr(t, err)
+	rules.HashKey = testRedactionKey("redaction-key")
+	doctor := getTestDoctorInfo()
+	assert.NoErr(t, RedactDoctorInfo(&doctor, rules))
+	b, err := json.Marshal(doctor)
+	assert.NoErr(t, err)
+	report := string(b)
+	for _, secret := range []string{"hunter2", `"DATABASE_NAME","value":"deis"`, "10.2.3.4", "192.168.0.10", "node-1.internal", `{\"spec\":{}}`} {
+		assert.False(t, strings.Contains(report, secret), "report contained %s", secret)
+	}
+	assert.True(t, strings.Contains(report, `"deis.io/owner":"ops"`), "unmatched annotation was scrubbed")
+	// the same address hashes to the same value everywhere in the report
+	pod := doctor.Namespaces[0].Pods[0].Data.(map[string]interface{})
+	hostIP := pod["status"].(map[string]interface{})["hostIP"].(string)
+	event := doctor.Namespaces[0].Events[0].Data.(map[string]interface{})
+	assert.Equal(t, event["message"], "Pulled image on "+hostIP, "event message")
+
+	rulesByPath := make(map[string]string)
+	for _, r := range doctor.Redactions {
+		rulesByPath[r.Path] = r.Rule
+	}
+	assert.Equal(t, rulesByPath["namespaces[deis].pods[0].data.spec.containers[0].env[0].value"], envValueRule, "env value rule")
+	assert.Equal(t, rulesByPath["namespaces[deis].pods[0].data.metadata.annotations.kubectl.kubernetes.io/last-applied-configuration"], keyPatternRule, "annotation rule")
+	assert.Equal(t, rulesByPath["namespaces[deis].pods[0].data.status.podIP"], addressHashRule, "pod IP rule")
+	assert.Equal(t, rulesByPath["nodes[0].data.metadata.name"], addressHashRule, "node name rule")
+	assert.Equal(t, rulesByPath["findings[0].name"], addressHashRule, "finding node name rule")
+	assert.Equal(t, rulesByPath["findings[0].message"], addressHashRule, "finding message rule")
+}
+
+func TestRedactEnvByKeyPattern(t *testing.T) {
+	// with env values kept, only the values of variables whose names match a pattern are dropped
+	rules, err := NewRedactionRules(false, "(?i)passw", false)
+	assert.NoErr(t, err)
+	doctor := getTestDoctorInfo()
+	assert.NoErr(t, RedactDoctorInfo(&doctor, rules))
+	b, err := json.Marshal(doctor)
+	assert.NoErr(t, err)
+	report := string(b)
+	assert.False(t, strings.Contains(report, "hunter2"), "report contained the password")
+	assert.True(t, strings.Contains(report, "10.2.3.4"), "report didn't contain the pod IP")
+	assert.True(t, strings.Contains(report, `"DATABASE_NAME","value":"deis"`), "report didn't contain the database name")
+}
+
+func TestRedactLogs(t *testing.T) {
+	rules, err := NewRedactionRules(true, "(?i)passw,(?i)token", true)
+	assert.NoErr(t, err)
+	rules.HashKey = testRedactionKey("redaction-key")
+	doctor := getTestDoctorInfo()
+	doctor.Namespaces[0].Logs = []*models.ContainerLog{{
+		Pod:       "deis-database-1",
+		Container: "deis-database",
+		Log: strings.Join([]string{
+			"connecting to 10.2.3.4:5432 with password=hunter2",
+			`{"msg":"authenticated","token":"abc123","user":"deis"}`,
+			"checkpoint complete",
+		}, "\n"),
+	}}
+	assert.NoErr(t, RedactDoctorInfo(&doctor, rules))
+	log := doctor.Namespaces[0].Logs[0].Log
+	for _, secret := range []string{"hunter2", "abc123", "10.2.3.4"} {
+		assert.False(t, strings.Contains(log, secret), "log contained %s", secret)
+	}
+	for _, kept := range []string{"password=" + redactedValue, `"token":"` + redactedValue + `"`, `"user":"deis"`, "checkpoint complete"} {
+		assert.True(t, strings.Contains(log, kept), "log didn't contain %s", kept)
+	}
+	var logRules []string
+	for _, r := range doctor.Redactions {
+		if r.Path == "namespaces[deis].logs[0].log" {
+			logRules = append(logRules, r.Rule)
+		}
+	}
+	assert.Equal(t, len(logRules), 2, "number of log redactions")
+}
+
+func TestRedactAddressesKey(t *testing.T) {
+	rules := RedactionRules{HashAddresses: true}
+	doctor := getTestDoctorInfo()
+	assert.Equal(t, RedactDoctorInfo(&doctor, rules), errNoRedactionKey, "error without a key")
+
+	// the cluster ID is published in the report, so it must not be the key
+	clusterIDRules := RedactionRules{HashAddresses: true, HashKey: testRedactionKey("cluster-id")}
+	doctor = getTestDoctorInfo()
+	assert.NoErr(t, RedactDoctorInfo(&doctor, clusterIDRules))
+	clusterIDHash := doctor.Findings[0].Name
+	rules.HashKey = testRedactionKey("redaction-key")
+	doctor = getTestDoctorInfo()
+	assert.NoErr(t, RedactDoctorInfo(&doctor, rules))
+	assert.True(t, doctor.Findings[0].Name != clusterIDHash, "address was hashed with the cluster ID")
+	// the same key hashes addresses the same way in every report
+	again := getTestDoctorInfo()
+	assert.NoErr(t, RedactDoctorInfo(&again, rules))
+	assert.Equal(t, again.Findings[0].Name, doctor.Findings[0].Name, "node name hash")
+}
+
+func TestNewRedactionRulesInvalidPattern(t *testing.T) {
+	_, err := NewRedactionRules(true, "(?i)passw,[", true)
+	assert.True(t, err != nil, "expected an error for an invalid pattern")
+}
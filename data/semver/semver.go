@@ -0,0 +1,247 @@
+// Package semver implements parsing and precedence rules for the semantic version strings used
+// to tag Deis Workflow components (see http://semver.org). In addition to strict semver, it
+// accepts a leading "v" ("v2.1.0"), missing minor and patch numbers ("v2-beta" is 2.0.0-beta),
+// and recognizes "git-<sha>" development builds
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const devPrefix = "git-"
+
+// ErrNotComparable is returned by Newest when one of its arguments is a development build,
+// which has no defined precedence relative to a released version
+type ErrNotComparable struct {
+	V1 string
+	V2 string
+}
+
+// Error is the error interface implementation
+func (e ErrNotComparable) Error() string {
+	return fmt.Sprintf("versions %s and %s are not comparable", e.V1, e.V2)
+}
+
+// Version is a parsed semantic version
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      []string
+	// Dev holds the commit identifier of a development build (e.g. "abc123" for "git-abc123").
+	// It is empty for released versions
+	Dev string
+	raw string
+}
+
+// Parse parses s into a Version. It returns an error if s is neither a semantic version nor a
+// development build identifier
+func Parse(s string) (Version, error) {
+	raw := s
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, devPrefix) {
+		sha := strings.TrimPrefix(s, devPrefix)
+		if sha == "" {
+			return Version{}, fmt.Errorf("invalid development version %q", raw)
+		}
+		return Version{Dev: sha, raw: raw}, nil
+	}
+	s = strings.TrimPrefix(s, "v")
+	if s == "" {
+		return Version{}, fmt.Errorf("invalid version %q", raw)
+	}
+	v := Version{raw: raw}
+	if i := strings.Index(s, "+"); i >= 0 {
+		build, err := splitIdentifiers(s[i+1:], false)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid build metadata in %q (%s)", raw, err)
+		}
+		v.Build = build
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		pre, err := splitIdentifiers(s[i+1:], true)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid prerelease in %q (%s)", raw, err)
+		}
+		v.Prerelease = pre
+		s = s[:i]
+	}
+	nums := strings.Split(s, ".")
+	if len(nums) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q (too many segments)", raw)
+	}
+	dst := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, num := range nums {
+		n, err := parseNumeric(num)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q (%s)", raw, err)
+		}
+		*dst[i] = n
+	}
+	return v, nil
+}
+
+// MustParse is like Parse, but panics if s cannot be parsed
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// IsDev returns true if v is a development build
+func (v Version) IsDev() bool {
+	return v.Dev != ""
+}
+
+// IsPrerelease returns true if v has prerelease identifiers, e.g. "2.0.0-beta2"
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// String returns the string that v was parsed from
+func (v Version) String() string {
+	return v.raw
+}
+
+// Compare returns -1, 0 or 1 if v has lower, equal or higher precedence than o, respectively.
+// Build metadata is ignored, as required by the semver spec. Development builds have equal
+// precedence to each other and lower precedence than any released version
+func (v Version) Compare(o Version) int {
+	switch {
+	case v.IsDev() && o.IsDev():
+		return 0
+	case v.IsDev():
+		return -1
+	case o.IsDev():
+		return 1
+	}
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// LessThan returns true if v has lower precedence than o
+func (v Version) LessThan(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+// Newest returns whichever of v1 and v2 has the higher precedence, or v1 if they are equal. It
+// returns an error if either can't be parsed, or ErrNotComparable if either is a development build
+func Newest(v1, v2 string) (string, error) {
+	p1, err := Parse(v1)
+	if err != nil {
+		return "", err
+	}
+	p2, err := Parse(v2)
+	if err != nil {
+		return "", err
+	}
+	if p1.IsDev() || p2.IsDev() {
+		return "", ErrNotComparable{V1: v1, V2: v2}
+	}
+	if p1.LessThan(p2) {
+		return v2, nil
+	}
+	return v1, nil
+}
+
+// comparePrerelease implements precedence for prerelease identifiers. A version without
+// prerelease identifiers has higher precedence than one with them
+func comparePrerelease(p1, p2 []string) int {
+	switch {
+	case len(p1) == 0 && len(p2) == 0:
+		return 0
+	case len(p1) == 0:
+		return 1
+	case len(p2) == 0:
+		return -1
+	}
+	for i := 0; i < len(p1) && i < len(p2); i++ {
+		if c := compareIdentifier(p1[i], p2[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(p1)), uint64(len(p2)))
+}
+
+// compareIdentifier compares two prerelease identifiers. Numeric identifiers are compared
+// numerically and have lower precedence than alphanumeric ones, which are compared lexically
+func compareIdentifier(id1, id2 string) int {
+	n1, err1 := strconv.ParseUint(id1, 10, 64)
+	n2, err2 := strconv.ParseUint(id2, 10, 64)
+	switch {
+	case err1 == nil && err2 == nil:
+		return compareUint(n1, n2)
+	case err1 == nil:
+		return -1
+	case err2 == nil:
+		return 1
+	}
+	return strings.Compare(id1, id2)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// splitIdentifiers splits a dot separated list of prerelease or build identifiers. If numeric
+// is true, numeric identifiers may not have leading zeroes
+func splitIdentifiers(s string, numeric bool) ([]string, error) {
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("empty identifier")
+		}
+		for _, r := range id {
+			if !isIdentifierRune(r) {
+				return nil, fmt.Errorf("invalid character %q in identifier %q", r, id)
+			}
+		}
+		if numeric && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return nil, fmt.Errorf("numeric identifier %q has a leading zero", id)
+		}
+	}
+	return ids, nil
+}
+
+func parseNumeric(s string) (uint64, error) {
+	if s == "" || !isNumeric(s) {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("%q has a leading zero", s)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifierRune(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '-'
+}
@@ -0,0 +1,79 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v2.10.3-beta.2+build.5")
+	assert.NoErr(t, err)
+	assert.Equal(t, v.Major, uint64(2), "major version")
+	assert.Equal(t, v.Minor, uint64(10), "minor version")
+	assert.Equal(t, v.Patch, uint64(3), "patch version")
+	assert.Equal(t, v.Prerelease, []string{"beta", "2"}, "prerelease identifiers")
+	assert.Equal(t, v.Build, []string{"build", "5"}, "build metadata")
+	assert.Equal(t, v.String(), "v2.10.3-beta.2+build.5", "string value")
+	assert.False(t, v.IsDev(), "release version reported as a development build")
+
+	v, err = Parse("v2-beta")
+	assert.NoErr(t, err)
+	assert.Equal(t, v.Major, uint64(2), "major version")
+	assert.Equal(t, v.Minor, uint64(0), "minor version")
+	assert.True(t, v.IsPrerelease(), "v2-beta should be a prerelease")
+
+	v, err = Parse("git-abc123")
+	assert.NoErr(t, err)
+	assert.True(t, v.IsDev(), "git-abc123 should be a development build")
+	assert.Equal(t, v.Dev, "abc123", "development build sha")
+
+	for _, invalid := range []string{"", "v", "git-", "1.2.3.4", "1.02.3", "1.2.3-", "1.2.3-beta..1", "1.2.3-01", "a.b.c", "1.2.3+bu!ld"} {
+		_, err := Parse(invalid)
+		assert.True(t, err != nil, "expected an error parsing "+invalid)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// each element has lower precedence than the one following it, per the examples in the semver spec
+	ordered := []string{
+		"git-abc123",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"2.0.0-beta2",
+		"v2.0.0",
+		"2.9.0",
+		"2.10.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		lower, higher := MustParse(ordered[i]), MustParse(ordered[i+1])
+		assert.True(t, lower.LessThan(higher), ordered[i]+" should be less than "+ordered[i+1])
+		assert.Equal(t, higher.Compare(lower), 1, "comparison of "+ordered[i+1]+" to "+ordered[i])
+	}
+	assert.Equal(t, MustParse("1.0.0+build.1").Compare(MustParse("v1.0.0+build.2")), 0, "build metadata comparison")
+	assert.Equal(t, MustParse("git-abc").Compare(MustParse("git-def")), 0, "development build comparison")
+}
+
+func TestNewest(t *testing.T) {
+	newest, err := Newest("2.9.0", "2.10.0")
+	assert.NoErr(t, err)
+	assert.Equal(t, newest, "2.10.0", "newest version")
+	newest, err = Newest("v2.0.0", "2.0.0-beta2")
+	assert.NoErr(t, err)
+	assert.Equal(t, newest, "v2.0.0", "newest version")
+	newest, err = Newest("1.0.0", "v1.0.0")
+	assert.NoErr(t, err)
+	assert.Equal(t, newest, "1.0.0", "newest of equal versions")
+	_, err = Newest("git-abc123", "2.0.0")
+	if _, ok := err.(ErrNotComparable); !ok {
+		t.Fatalf("expected ErrNotComparable, got %#v", err)
+	}
+	_, err = Newest("2.0.0", "latest")
+	assert.True(t, err != nil, "expected an error for an unparseable version")
+}
@@ -0,0 +1,101 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+const (
+	trainAnnotation = "component.deis.io/train"
+	stableTrain     = "stable"
+)
+
+// ParseComponentTrains parses a comma separated list of component=train pairs, as found in
+// config.Spec.ComponentTrains, into a map of component name to release train
+func ParseComponentTrains(s string) (map[string]string, error) {
+	trains := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid component train %q, expected component=train", pair)
+		}
+		trains[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return trains, nil
+}
+
+// ComponentTrain returns the release train that the named component follows. In order of
+// precedence, that's the component's entry in config.Spec.ComponentTrains, the train annotation on
+// the component's resource, config.Spec.ReleaseTrain and finally "stable"
+func ComponentTrain(component string, annotations map[string]string) string {
+	// malformed overrides are rejected at boot, so it's safe to ignore the error here
+	overrides, _ := ParseComponentTrains(config.Spec.ComponentTrains)
+	if train := overrides[component]; train != "" {
+		return train
+	}
+	if train := annotations[trainAnnotation]; train != "" {
+		return train
+	}
+	if config.Spec.ReleaseTrain != "" {
+		return config.Spec.ReleaseTrain
+	}
+	return stableTrain
+}
+
+// trainOf returns the release train of the given component version, or the cluster wide default
+// train if it doesn't have one
+func trainOf(cv models.ComponentVersion) string {
+	if cv.Version != nil && cv.Version.Train != "" {
+		return cv.Version.Train
+	}
+	name := ""
+	if cv.Component != nil {
+		name = cv.Component.Name
+	}
+	return ComponentTrain(name, nil)
+}
+
+// clusterTrains returns the release train that each component in cluster follows, keyed by the
+// component's namespacedName
+func clusterTrains(cluster models.Cluster) map[string]string {
+	trains := make(map[string]string, len(cluster.Components))
+	for _, component := range cluster.Components {
+		if component == nil || component.Component == nil {
+			continue
+		}
+		trains[namespacedName(*component.Component)] = trainOf(*component)
+	}
+	return trains
+}
+
+// componentNameTrains returns the release trains followed by the components of each name in
+// cluster. Components of the same name in different namespaces may follow different trains
+func componentNameTrains(cluster models.Cluster) map[string]map[string]bool {
+	trains := make(map[string]map[string]bool)
+	for _, component := range cluster.Components {
+		if component == nil || component.Component == nil {
+			continue
+		}
+		name := component.Component.Name
+		if trains[name] == nil {
+			trains[name] = make(map[string]bool)
+		}
+		trains[name][trainOf(*component)] = true
+	}
+	return trains
+}
+
+// namespacedName identifies component across the namespaces of a cluster
+func namespacedName(component models.Component) string {
+	if component.Namespace == nil || *component.Namespace == "" {
+		return component.Name
+	}
+	return *component.Namespace + "/" + component.Name
+}
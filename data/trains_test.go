@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+func TestParseComponentTrains(t *testing.T) {
+	trains, err := ParseComponentTrains("")
+	assert.NoErr(t, err)
+	assert.Equal(t, len(trains), 0, "number of trains")
+	trains, err = ParseComponentTrains(" deis-controller=beta , deis-router=stable,")
+	assert.NoErr(t, err)
+	assert.Equal(t, len(trains), 2, "number of trains")
+	assert.Equal(t, trains["deis-controller"], "beta", "controller train")
+	assert.Equal(t, trains["deis-router"], "stable", "router train")
+	_, err = ParseComponentTrains("deis-controller")
+	assert.True(t, err != nil, "expected an error for a pair without a train")
+	_, err = ParseComponentTrains("=beta")
+	assert.True(t, err != nil, "expected an error for a pair without a component")
+}
+
+func TestComponentTrain(t *testing.T) {
+	defer func(spec config.Specification) { config.Spec = spec }(config.Spec)
+	config.Spec.ReleaseTrain = ""
+	config.Spec.ComponentTrains = ""
+	assert.Equal(t, ComponentTrain("deis-controller", nil), stableTrain, "default train")
+	config.Spec.ReleaseTrain = "beta"
+	assert.Equal(t, ComponentTrain("deis-controller", nil), "beta", "cluster train")
+	annotations := map[string]string{trainAnnotation: "alpha"}
+	assert.Equal(t, ComponentTrain("deis-controller", annotations), "alpha", "annotated train")
+	config.Spec.ComponentTrains = "deis-controller=stable"
+	assert.Equal(t, ComponentTrain("deis-controller", annotations), stableTrain, "overridden train")
+	assert.Equal(t, ComponentTrain("deis-router", nil), "beta", "cluster train of another component")
+}
+
+func TestGetLatestVersionOnTrain(t *testing.T) {
+	av := &memoryAvailableVersions{}
+	av.Store([]models.ComponentVersion{
+		{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Train: "stable", Version: "2.9.0"}},
+		{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Train: "beta", Version: "2.10.0-beta1"}},
+	})
+	cluster := models.Cluster{
+		Components: []*models.ComponentVersion{
+			{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Train: "beta", Version: "2.9.0"}},
+		},
+	}
+	latest, err := GetLatestVersion(context.Background(), *cluster.Components[0].Component, cluster, av)
+	assert.NoErr(t, err)
+	assert.Equal(t, latest.Version, "2.10.0-beta1", "latest beta version")
+	cluster.Components[0].Version.Train = "stable"
+	latest, err = GetLatestVersion(context.Background(), *cluster.Components[0].Component, cluster, av)
+	assert.NoErr(t, err)
+	assert.Equal(t, latest.Version, "2.9.0", "latest stable version")
+	cluster.Components[0].Version.Train = "alpha"
+	_, err = GetLatestVersion(context.Background(), *cluster.Components[0].Component, cluster, av)
+	assert.True(t, err != nil, "expected an error for a train without versions")
+}
+
+func TestGetLatestVersionWithoutTrain(t *testing.T) {
+	av := &memoryAvailableVersions{}
+	// versions without a train are stable, wherever they are in the list
+	av.Store([]models.ComponentVersion{
+		{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Train: "beta", Version: "2.10.0-beta1"}},
+		{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Version: "2.9.0"}},
+	})
+	cluster := models.Cluster{
+		Components: []*models.ComponentVersion{
+			{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Train: "beta", Version: "2.9.0"}},
+		},
+	}
+	latest, err := GetLatestVersion(context.Background(), *cluster.Components[0].Component, cluster, av)
+	assert.NoErr(t, err)
+	assert.Equal(t, latest.Version, "2.10.0-beta1", "latest beta version")
+	cluster.Components[0].Version.Train = "stable"
+	latest, err = GetLatestVersion(context.Background(), *cluster.Components[0].Component, cluster, av)
+	assert.NoErr(t, err)
+	assert.Equal(t, latest.Version, "2.9.0", "latest stable version")
+
+	// a beta component doesn't resolve to a version without a train
+	av.Store([]models.ComponentVersion{
+		{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Version: "2.9.0"}},
+	})
+	cluster.Components[0].Version.Train = "beta"
+	_, err = GetLatestVersion(context.Background(), *cluster.Components[0].Component, cluster, av)
+	assert.True(t, err != nil, "expected an error for a beta component without beta versions")
+}
+
+func TestGetLatestVersionByNamespace(t *testing.T) {
+	av := &memoryAvailableVersions{}
+	av.Store([]models.ComponentVersion{
+		{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Train: "stable", Version: "2.9.0"}},
+		{Component: &models.Component{Name: "deis-controller"}, Version: &models.Version{Train: "beta", Version: "2.10.0-beta1"}},
+	})
+	deis, staging := "deis", "deis-staging"
+	// components of the same name in different namespaces follow their own trains
+	cluster := models.Cluster{
+		Components: []*models.ComponentVersion{
+			{Component: &models.Component{Name: "deis-controller", Namespace: &deis}, Version: &models.Version{Train: "stable", Version: "2.9.0"}},
+			{Component: &models.Component{Name: "deis-controller", Namespace: &staging}, Version: &models.Version{Train: "beta", Version: "2.9.0"}},
+		},
+	}
+	latest, err := GetLatestVersion(context.Background(), *cluster.Components[0].Component, cluster, av)
+	assert.NoErr(t, err)
+	assert.Equal(t, latest.Version, "2.9.0", "latest version in the deis namespace")
+	latest, err = GetLatestVersion(context.Background(), *cluster.Components[1].Component, cluster, av)
+	assert.NoErr(t, err)
+	assert.Equal(t, latest.Version, "2.10.0-beta1", "latest version in the deis-staging namespace")
+}
@@ -0,0 +1,334 @@
+package diagnostics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/labels"
+)
+
+// the k8s values that the checks look for
+const (
+	crashLoopBackOffReason = "CrashLoopBackOff"
+	errImagePullReason     = "ErrImagePull"
+	imagePullBackOffReason = "ImagePullBackOff"
+	warningEventType       = "Warning"
+	nodeOutOfDisk          = api.NodeConditionType("OutOfDisk")
+	nodeMemoryPressure     = api.NodeConditionType("MemoryPressure")
+	nodeDiskPressure       = api.NodeConditionType("DiskPressure")
+)
+
+// CrashLoopCheck reports the containers that are in CrashLoopBackOff
+type CrashLoopCheck struct{}
+
+// Name is the Check interface implementation
+func (c CrashLoopCheck) Name() string {
+	return "crash-loop"
+}
+
+// Run is the Check interface implementation
+func (c CrashLoopCheck) Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error) {
+	pods, err := getPods(k)
+	if err != nil {
+		return nil, err
+	}
+	var findings []*models.Finding
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if waiting := status.State.Waiting; waiting != nil && waiting.Reason == crashLoopBackOffReason {
+				findings = append(findings, &models.Finding{
+					Severity:    SeverityCritical,
+					Kind:        "Pod",
+					Name:        pod.Name,
+					Message:     fmt.Sprintf("container %s is crash looping, and has restarted %d times", status.Name, status.RestartCount),
+					Remediation: fmt.Sprintf("Check why the container exits with kubectl logs --previous %s -c %s", pod.Name, status.Name),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// ImagePullCheck reports the containers whose images can't be pulled
+type ImagePullCheck struct{}
+
+// Name is the Check interface implementation
+func (c ImagePullCheck) Name() string {
+	return "image-pull"
+}
+
+// Run is the Check interface implementation
+func (c ImagePullCheck) Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error) {
+	pods, err := getPods(k)
+	if err != nil {
+		return nil, err
+	}
+	var findings []*models.Finding
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			waiting := status.State.Waiting
+			if waiting == nil || (waiting.Reason != errImagePullReason && waiting.Reason != imagePullBackOffReason) {
+				continue
+			}
+			findings = append(findings, &models.Finding{
+				Severity:    SeverityCritical,
+				Kind:        "Pod",
+				Name:        pod.Name,
+				Message:     fmt.Sprintf("the image of container %s can't be pulled (%s)", status.Name, waiting.Reason),
+				Remediation: "Check that the image name and tag exist, that the registry is reachable from the node, and that the pod's image pull secrets are valid",
+			})
+		}
+	}
+	return findings, nil
+}
+
+// PendingPodCheck reports the pods that have been pending for longer than Threshold
+type PendingPodCheck struct {
+	Threshold time.Duration
+}
+
+// Name is the Check interface implementation
+func (c PendingPodCheck) Name() string {
+	return "pending-pod"
+}
+
+// Run is the Check interface implementation
+func (c PendingPodCheck) Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error) {
+	pods, err := getPods(k)
+	if err != nil {
+		return nil, err
+	}
+	var findings []*models.Finding
+	for _, pod := range pods {
+		if pod.Status.Phase != api.PodPending {
+			continue
+		}
+		age := now.Sub(pod.CreationTimestamp.Time)
+		if age <= c.Threshold {
+			continue
+		}
+		findings = append(findings, &models.Finding{
+			Severity:    SeverityWarning,
+			Kind:        "Pod",
+			Name:        pod.Name,
+			Message:     fmt.Sprintf("pod has been pending for %s", age-age%time.Second),
+			Remediation: fmt.Sprintf("Check the pod's events with kubectl describe pod %s for scheduling failures, such as insufficient CPU or memory", pod.Name),
+		})
+	}
+	return findings, nil
+}
+
+// DeploymentReplicasCheck reports the Deployments with fewer available replicas than desired
+type DeploymentReplicasCheck struct{}
+
+// Name is the Check interface implementation
+func (c DeploymentReplicasCheck) Name() string {
+	return "deployment-replicas"
+}
+
+// Run is the Check interface implementation
+func (c DeploymentReplicasCheck) Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error) {
+	deployments, err := getDeployments(k)
+	if err != nil {
+		return nil, err
+	}
+	var findings []*models.Finding
+	for _, d := range deployments {
+		if d.Status.AvailableReplicas >= d.Spec.Replicas {
+			continue
+		}
+		severity := SeverityWarning
+		if d.Status.AvailableReplicas == 0 {
+			severity = SeverityCritical
+		}
+		findings = append(findings, &models.Finding{
+			Severity:    severity,
+			Kind:        "Deployment",
+			Name:        d.Name,
+			Message:     fmt.Sprintf("%d of %d desired replicas are available", d.Status.AvailableReplicas, d.Spec.Replicas),
+			Remediation: "Check the findings for the Deployment's pods, and its events with kubectl describe deployment " + d.Name,
+		})
+	}
+	return findings, nil
+}
+
+// NodePressureCheck reports the nodes that aren't ready, are out of disk, or are under memory or
+// disk pressure
+type NodePressureCheck struct{}
+
+// Name is the Check interface implementation
+func (c NodePressureCheck) Name() string {
+	return "node-pressure"
+}
+
+// ClusterScoped is the ClusterCheck interface implementation
+func (c NodePressureCheck) ClusterScoped() bool {
+	return true
+}
+
+// Run is the Check interface implementation
+func (c NodePressureCheck) Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error) {
+	nodes, err := getNodes(k)
+	if err != nil {
+		return nil, err
+	}
+	var findings []*models.Finding
+	for _, node := range nodes {
+		for _, cond := range node.Status.Conditions {
+			finding := &models.Finding{Kind: "Node", Name: node.Name}
+			switch {
+			case cond.Type == api.NodeReady && cond.Status != api.ConditionTrue:
+				finding.Severity = SeverityCritical
+				finding.Message = "node is not ready"
+				finding.Remediation = "Check that the node's kubelet is running, and that it can reach the Kubernetes API"
+			case cond.Type == nodeOutOfDisk && cond.Status == api.ConditionTrue:
+				finding.Severity = SeverityCritical
+				finding.Message = "node is out of disk space"
+				finding.Remediation = "Free disk space on the node, e.g. by removing unused images"
+			case (cond.Type == nodeMemoryPressure || cond.Type == nodeDiskPressure) && cond.Status == api.ConditionTrue:
+				finding.Severity = SeverityWarning
+				finding.Message = fmt.Sprintf("node reports %s", cond.Type)
+				finding.Remediation = "Pods may be evicted from the node. Add capacity to the cluster, or lower the requests of the pods on the node"
+			default:
+				continue
+			}
+			if cond.Reason != "" {
+				finding.Message += fmt.Sprintf(" (%s)", cond.Reason)
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// EventBurstCheck reports the objects that had at least Threshold warning events with the same
+// reason in the last Window. An event's Count is cumulative since its FirstTimestamp, so it's only
+// counted in full if it first occurred in the window. Otherwise only its last occurrence is counted
+type EventBurstCheck struct {
+	Threshold int
+	Window    time.Duration
+}
+
+// Name is the Check interface implementation
+func (c EventBurstCheck) Name() string {
+	return "event-burst"
+}
+
+// Run is the Check interface implementation
+func (c EventBurstCheck) Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error) {
+	events, err := getEvents(k)
+	if err != nil {
+		return nil, err
+	}
+	type burstKey struct {
+		kind, name, reason string
+	}
+	counts := make(map[burstKey]int)
+	messages := make(map[burstKey]string)
+	for _, e := range events {
+		if e.Type != warningEventType || now.Sub(e.LastTimestamp.Time) > c.Window {
+			continue
+		}
+		key := burstKey{e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Reason}
+		count := 1
+		if !e.FirstTimestamp.IsZero() && now.Sub(e.FirstTimestamp.Time) <= c.Window && e.Count > 1 {
+			count = int(e.Count)
+		}
+		counts[key] += count
+		messages[key] = e.Message
+	}
+	var findings []*models.Finding
+	for key, count := range counts {
+		if count < c.Threshold {
+			continue
+		}
+		findings = append(findings, &models.Finding{
+			Severity:    SeverityWarning,
+			Kind:        key.kind,
+			Name:        key.name,
+			Message:     fmt.Sprintf("%d %s warning events in the last %s, most recently: %s", count, key.reason, c.Window, messages[key]),
+			Remediation: fmt.Sprintf("Check the %s's events with kubectl describe %s %s", key.kind, strings.ToLower(key.kind), key.name),
+		})
+	}
+	// map iteration is random, so the findings are sorted to keep reports stable
+	sort.Sort(byObject(findings))
+	return findings, nil
+}
+
+// ServiceEndpointsCheck reports the Services whose selector matches no ready pods, and which so
+// have no endpoints. Services without a selector manage their own endpoints, and aren't checked
+type ServiceEndpointsCheck struct{}
+
+// Name is the Check interface implementation
+func (c ServiceEndpointsCheck) Name() string {
+	return "service-endpoints"
+}
+
+// Run is the Check interface implementation
+func (c ServiceEndpointsCheck) Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error) {
+	services, err := getServices(k)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := getPods(k)
+	if err != nil {
+		return nil, err
+	}
+	var findings []*models.Finding
+	for _, svc := range services {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(labels.Set(svc.Spec.Selector))
+		hasEndpoints := false
+		for _, pod := range pods {
+			if selector.Matches(labels.Set(pod.Labels)) && podReady(pod) {
+				hasEndpoints = true
+				break
+			}
+		}
+		if hasEndpoints {
+			continue
+		}
+		findings = append(findings, &models.Finding{
+			Severity:    SeverityWarning,
+			Kind:        "Service",
+			Name:        svc.Name,
+			Message:     fmt.Sprintf("no ready pods match the service's selector %s", selector),
+			Remediation: "Check that the pods the Service routes to are running and ready, and that their labels match the selector",
+		})
+	}
+	return findings, nil
+}
+
+// podReady returns true if pod is running and has a true Ready condition
+func podReady(pod *api.Pod) bool {
+	if pod.Status.Phase != api.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == api.PodReady {
+			return cond.Status == api.ConditionTrue
+		}
+	}
+	return false
+}
+
+type byObject []*models.Finding
+
+func (b byObject) Len() int      { return len(b) }
+func (b byObject) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byObject) Less(i, j int) bool {
+	if b[i].Kind != b[j].Kind {
+		return b[i].Kind < b[j].Kind
+	}
+	if b[i].Name != b[j].Name {
+		return b[i].Name < b[j].Name
+	}
+	return b[i].Message < b[j].Message
+}
@@ -0,0 +1,186 @@
+package diagnostics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+)
+
+var testNow = time.Date(2016, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func getTestPod(name string, phase api.PodPhase, labels map[string]string, statuses ...api.ContainerStatus) api.Pod {
+	pod := api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			Name:              name,
+			Namespace:         "deis",
+			Labels:            labels,
+			CreationTimestamp: unversioned.NewTime(testNow.Add(-time.Hour)),
+		},
+		Status: api.PodStatus{Phase: phase, ContainerStatuses: statuses},
+	}
+	if phase == api.PodRunning {
+		pod.Status.Conditions = []api.PodCondition{{Type: api.PodReady, Status: api.ConditionTrue}}
+	}
+	return pod
+}
+
+func getWaitingStatus(container, reason string) api.ContainerStatus {
+	return api.ContainerStatus{
+		Name:         container,
+		RestartCount: 7,
+		State:        api.ContainerState{Waiting: &api.ContainerStateWaiting{Reason: reason}},
+	}
+}
+
+func TestCrashLoopCheck(t *testing.T) {
+	k := mockRunningK8sData{namespace: "deis", pods: []api.Pod{
+		getTestPod("deis-router-1", api.PodRunning, nil),
+		getTestPod("deis-builder-1", api.PodRunning, nil, getWaitingStatus("deis-builder", crashLoopBackOffReason)),
+	}}
+	findings, err := CrashLoopCheck{}.Run(k, testNow)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(findings), 1, "number of findings")
+	assert.Equal(t, findings[0].Name, "deis-builder-1", "pod name")
+	assert.Equal(t, findings[0].Severity, SeverityCritical, "severity")
+	assert.Equal(t, findings[0].Message, "container deis-builder is crash looping, and has restarted 7 times", "message")
+	assert.True(t, findings[0].Remediation != "", "finding had no remediation")
+}
+
+func TestImagePullCheck(t *testing.T) {
+	k := mockRunningK8sData{namespace: "deis", pods: []api.Pod{
+		getTestPod("deis-router-1", api.PodPending, nil, getWaitingStatus("deis-router", imagePullBackOffReason)),
+		getTestPod("deis-builder-1", api.PodPending, nil, getWaitingStatus("deis-builder", errImagePullReason)),
+		getTestPod("deis-registry-1", api.PodPending, nil, getWaitingStatus("deis-registry", "ContainerCreating")),
+	}}
+	findings, err := ImagePullCheck{}.Run(k, testNow)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(findings), 2, "number of findings")
+	assert.Equal(t, findings[0].Name, "deis-router-1", "pod name")
+	assert.Equal(t, findings[1].Name, "deis-builder-1", "pod name")
+}
+
+func TestPendingPodCheck(t *testing.T) {
+	k := mockRunningK8sData{namespace: "deis", pods: []api.Pod{
+		getTestPod("deis-router-1", api.PodPending, nil),
+		getTestPod("deis-builder-1", api.PodRunning, nil),
+	}}
+	findings, err := PendingPodCheck{Threshold: 5 * time.Minute}.Run(k, testNow)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(findings), 1, "number of findings")
+	assert.Equal(t, findings[0].Name, "deis-router-1", "pod name")
+	assert.Equal(t, findings[0].Message, "pod has been pending for 1h0m0s", "message")
+	findings, err = PendingPodCheck{Threshold: 2 * time.Hour}.Run(k, testNow)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(findings), 0, "number of findings")
+}
+
+func TestDeploymentReplicasCheck(t *testing.T) {
+	k := mockRunningK8sData{namespace: "deis", deployments: []extensions.Deployment{
+		{
+			ObjectMeta: api.ObjectMeta{Name: "deis-router"},
+			Spec:       extensions.DeploymentSpec{Replicas: 3},
+			Status:     extensions.DeploymentStatus{AvailableReplicas: 3},
+		},
+		{
+			ObjectMeta: api.ObjectMeta{Name: "deis-controller"},
+			Spec:       extensions.DeploymentSpec{Replicas: 3},
+			Status:     extensions.DeploymentStatus{AvailableReplicas: 1},
+		},
+		{
+			ObjectMeta: api.ObjectMeta{Name: "deis-builder"},
+			Spec:       extensions.DeploymentSpec{Replicas: 1},
+		},
+	}}
+	findings, err := DeploymentReplicasCheck{}.Run(k, testNow)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(findings), 2, "number of findings")
+	assert.Equal(t, findings[0].Name, "deis-controller", "deployment name")
+	assert.Equal(t, findings[0].Severity, SeverityWarning, "severity")
+	assert.Equal(t, findings[0].Message, "1 of 3 desired replicas are available", "message")
+	assert.Equal(t, findings[1].Name, "deis-builder", "deployment name")
+	assert.Equal(t, findings[1].Severity, SeverityCritical, "severity")
+}
+
+func TestNodePressureCheck(t *testing.T) {
+	k := mockRunningK8sData{namespace: "deis", nodes: []api.Node{
+		{
+			ObjectMeta: api.ObjectMeta{Name: "node-1"},
+			Status: api.NodeStatus{Conditions: []api.NodeCondition{
+				{Type: api.NodeReady, Status: api.ConditionTrue},
+				{Type: nodeMemoryPressure, Status: api.ConditionTrue},
+			}},
+		},
+		{
+			ObjectMeta: api.ObjectMeta{Name: "node-2"},
+			Status: api.NodeStatus{Conditions: []api.NodeCondition{
+				{Type: api.NodeReady, Status: api.ConditionTrue},
+				{Type: nodeDiskPressure, Status: api.ConditionFalse},
+			}},
+		},
+	}}
+	check := NodePressureCheck{}
+	assert.True(t, check.ClusterScoped(), "node check wasn't cluster scoped")
+	findings, err := check.Run(k, testNow)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(findings), 1, "number of findings")
+	assert.Equal(t, findings[0].Name, "node-1", "node name")
+	assert.Equal(t, findings[0].Severity, SeverityWarning, "severity")
+	assert.Equal(t, findings[0].Message, "node reports MemoryPressure", "message")
+}
+
+func TestEventBurstCheck(t *testing.T) {
+	getEvent := func(name, eventType, reason string, count int, age time.Duration) api.Event {
+		return api.Event{
+			InvolvedObject: api.ObjectReference{Kind: "Pod", Name: name},
+			Type:           eventType,
+			Reason:         reason,
+			Message:        "Back-off restarting failed container",
+			Count:          count,
+			FirstTimestamp: unversioned.NewTime(testNow.Add(-age)),
+			LastTimestamp:  unversioned.NewTime(testNow.Add(-age)),
+		}
+	}
+	// an event that first occurred long ago, and recurred recently
+	recurring := getEvent("deis-controller-1", warningEventType, "BackOff", 50, time.Minute)
+	recurring.FirstTimestamp = unversioned.NewTime(testNow.Add(-2 * time.Hour))
+	k := mockRunningK8sData{namespace: "deis", events: []api.Event{
+		getEvent("deis-builder-1", warningEventType, "BackOff", 6, time.Minute),
+		getEvent("deis-builder-1", warningEventType, "BackOff", 4, 2*time.Minute),
+		// too old
+		getEvent("deis-router-1", warningEventType, "BackOff", 20, time.Hour),
+		// not a warning
+		getEvent("deis-registry-1", "Normal", "Pulled", 20, time.Minute),
+		// only its last occurrence is known to be in the window
+		recurring,
+	}}
+	findings, err := EventBurstCheck{Threshold: 10, Window: 10 * time.Minute}.Run(k, testNow)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(findings), 1, "number of findings")
+	assert.Equal(t, findings[0].Kind, "Pod", "kind")
+	assert.Equal(t, findings[0].Name, "deis-builder-1", "name")
+	assert.Equal(t, findings[0].Message, "10 BackOff warning events in the last 10m0s, most recently: Back-off restarting failed container", "message")
+}
+
+func TestServiceEndpointsCheck(t *testing.T) {
+	k := mockRunningK8sData{
+		namespace: "deis",
+		pods: []api.Pod{
+			getTestPod("deis-router-1", api.PodRunning, map[string]string{"app": "deis-router"}),
+			getTestPod("deis-builder-1", api.PodPending, map[string]string{"app": "deis-builder"}),
+		},
+		services: []api.Service{
+			{ObjectMeta: api.ObjectMeta{Name: "deis-router"}, Spec: api.ServiceSpec{Selector: map[string]string{"app": "deis-router"}}},
+			{ObjectMeta: api.ObjectMeta{Name: "deis-builder"}, Spec: api.ServiceSpec{Selector: map[string]string{"app": "deis-builder"}}},
+			{ObjectMeta: api.ObjectMeta{Name: "external-db"}},
+		},
+	}
+	findings, err := ServiceEndpointsCheck{}.Run(k, testNow)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(findings), 1, "number of findings")
+	assert.Equal(t, findings[0].Name, "deis-builder", "service name")
+	assert.Equal(t, findings[0].Message, "no ready pods match the service's selector app=deis-builder", "message")
+}
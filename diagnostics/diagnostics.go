@@ -0,0 +1,190 @@
+package diagnostics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+)
+
+// the severities of findings, from the least to the most severe
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var severityRanks = map[string]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+// Check is an interface for diagnostic checks, which look for known problems in the k8s data of a
+// namespace
+type Check interface {
+	// Name identifies the check in its findings
+	Name() string
+	// Run returns the check's findings for k's data, as of now
+	Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error)
+}
+
+// ClusterCheck is an interface for the checks of cluster scoped resources, such as nodes
+type ClusterCheck interface {
+	Check
+	// ClusterScoped returns true if the check is run once, rather than once per namespace
+	ClusterScoped() bool
+}
+
+// Engine runs diagnostic checks over the k8s data of each reported namespace
+type Engine struct {
+	checks []Check
+	now    func() time.Time
+}
+
+// NewEngine returns an Engine that runs checks
+func NewEngine(checks ...Check) *Engine {
+	return &Engine{checks: checks, now: time.Now}
+}
+
+// ConfiguredChecks returns every built-in check, with the thresholds configured in config.Spec
+func ConfiguredChecks() []Check {
+	return []Check{
+		CrashLoopCheck{},
+		ImagePullCheck{},
+		PendingPodCheck{Threshold: time.Duration(config.Spec.DiagnosticsPendingThreshold) * time.Second},
+		DeploymentReplicasCheck{},
+		NodePressureCheck{},
+		EventBurstCheck{
+			Threshold: config.Spec.DiagnosticsEventBurstThreshold,
+			Window:    time.Duration(config.Spec.DiagnosticsEventBurstWindow) * time.Second,
+		},
+		ServiceEndpointsCheck{},
+	}
+}
+
+// Run runs every check over ks and returns the findings, most severe first. Cluster checks are run
+// once, with the first namespace's data. A check that fails is logged with the logger carried by
+// ctx and skipped, so that the other checks still report
+func (e *Engine) Run(ctx context.Context, ks []k8s.RunningK8sData) []*models.Finding {
+	log := logger.FromContext(ctx)
+	now := e.now()
+	var findings []*models.Finding
+	for _, check := range e.checks {
+		clusterScoped := false
+		if cc, ok := check.(ClusterCheck); ok {
+			clusterScoped = cc.ClusterScoped()
+		}
+		for i, k := range ks {
+			if clusterScoped && i > 0 {
+				break
+			}
+			fs, err := check.Run(k, now)
+			if err != nil {
+				log.WithError(err).WithFields(logger.Fields{
+					"check":     check.Name(),
+					"namespace": k.Namespace(),
+				}).Warnf("unable to run diagnostic check")
+				continue
+			}
+			for _, f := range fs {
+				f.Check = check.Name()
+				if f.Namespace == "" && !clusterScoped {
+					f.Namespace = k.Namespace()
+				}
+			}
+			findings = append(findings, fs...)
+		}
+	}
+	sort.Stable(bySeverity(findings))
+	return findings
+}
+
+type bySeverity []*models.Finding
+
+func (b bySeverity) Len() int      { return len(b) }
+func (b bySeverity) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b bySeverity) Less(i, j int) bool {
+	return severityRanks[b[i].Severity] > severityRanks[b[j].Severity]
+}
+
+// getPods returns the pods in k's data
+func getPods(k k8s.RunningK8sData) ([]*api.Pod, error) {
+	res, err := k.Pods()
+	if err != nil {
+		return nil, err
+	}
+	var ret []*api.Pod
+	for _, r := range res {
+		if pod, ok := r.Data.(*api.Pod); ok {
+			ret = append(ret, pod)
+		}
+	}
+	return ret, nil
+}
+
+// getDeployments returns the Deployments in k's data
+func getDeployments(k k8s.RunningK8sData) ([]*extensions.Deployment, error) {
+	res, err := k.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	var ret []*extensions.Deployment
+	for _, r := range res {
+		if d, ok := r.Data.(*extensions.Deployment); ok {
+			ret = append(ret, d)
+		}
+	}
+	return ret, nil
+}
+
+// getNodes returns the nodes in k's data
+func getNodes(k k8s.RunningK8sData) ([]*api.Node, error) {
+	res, err := k.Nodes()
+	if err != nil {
+		return nil, err
+	}
+	var ret []*api.Node
+	for _, r := range res {
+		if node, ok := r.Data.(*api.Node); ok {
+			ret = append(ret, node)
+		}
+	}
+	return ret, nil
+}
+
+// getEvents returns the events in k's data
+func getEvents(k k8s.RunningK8sData) ([]*api.Event, error) {
+	res, err := k.Events()
+	if err != nil {
+		return nil, err
+	}
+	var ret []*api.Event
+	for _, r := range res {
+		if e, ok := r.Data.(*api.Event); ok {
+			ret = append(ret, e)
+		}
+	}
+	return ret, nil
+}
+
+// getServices returns the Services in k's data
+func getServices(k k8s.RunningK8sData) ([]*api.Service, error) {
+	res, err := k.Services()
+	if err != nil {
+		return nil, err
+	}
+	var ret []*api.Service
+	for _, r := range res {
+		if svc, ok := r.Data.(*api.Service); ok {
+			ret = append(ret, svc)
+		}
+	}
+	return ret, nil
+}
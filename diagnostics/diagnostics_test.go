@@ -0,0 +1,147 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+)
+
+// Creating a novel mock struct that fulfills the k8s.RunningK8sData interface
+type mockRunningK8sData struct {
+	namespace   string
+	pods        []api.Pod
+	deployments []extensions.Deployment
+	events      []api.Event
+	nodes       []api.Node
+	services    []api.Service
+}
+
+func (m mockRunningK8sData) Namespace() string {
+	return m.namespace
+}
+
+func (m mockRunningK8sData) DaemonSets() ([]*models.K8sResource, error) {
+	return []*models.K8sResource{}, nil
+}
+
+func (m mockRunningK8sData) Deployments() ([]*models.K8sResource, error) {
+	ret := make([]*models.K8sResource, len(m.deployments))
+	for i := range m.deployments {
+		ret[i] = &models.K8sResource{Data: &m.deployments[i]}
+	}
+	return ret, nil
+}
+
+func (m mockRunningK8sData) Events() ([]*models.K8sResource, error) {
+	ret := make([]*models.K8sResource, len(m.events))
+	for i := range m.events {
+		ret[i] = &models.K8sResource{Data: &m.events[i]}
+	}
+	return ret, nil
+}
+
+func (m mockRunningK8sData) Logs(log *logger.Logger, opts k8s.PodLogOptions) ([]*models.ContainerLog, error) {
+	return []*models.ContainerLog{}, nil
+}
+
+func (m mockRunningK8sData) Nodes() ([]*models.K8sResource, error) {
+	ret := make([]*models.K8sResource, len(m.nodes))
+	for i := range m.nodes {
+		ret[i] = &models.K8sResource{Data: &m.nodes[i]}
+	}
+	return ret, nil
+}
+
+func (m mockRunningK8sData) NodeSummaries() ([]*models.NodeSummary, error) {
+	return []*models.NodeSummary{}, nil
+}
+
+func (m mockRunningK8sData) Pods() ([]*models.K8sResource, error) {
+	ret := make([]*models.K8sResource, len(m.pods))
+	for i := range m.pods {
+		ret[i] = &models.K8sResource{Data: &m.pods[i]}
+	}
+	return ret, nil
+}
+
+func (m mockRunningK8sData) ReplicaSets() ([]*models.K8sResource, error) {
+	return []*models.K8sResource{}, nil
+}
+
+func (m mockRunningK8sData) ReplicationControllers() ([]*models.K8sResource, error) {
+	return []*models.K8sResource{}, nil
+}
+
+func (m mockRunningK8sData) Services() ([]*models.K8sResource, error) {
+	ret := make([]*models.K8sResource, len(m.services))
+	for i := range m.services {
+		ret[i] = &models.K8sResource{Data: &m.services[i]}
+	}
+	return ret, nil
+}
+
+// Creating a novel mock struct that fulfills the Check interface
+type mockCheck struct {
+	name     string
+	severity string
+	err      error
+}
+
+func (c mockCheck) Name() string {
+	return c.name
+}
+
+func (c mockCheck) Run(k k8s.RunningK8sData, now time.Time) ([]*models.Finding, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return []*models.Finding{{Severity: c.severity, Message: "found something"}}, nil
+}
+
+func TestEngineRun(t *testing.T) {
+	ks := []k8s.RunningK8sData{
+		mockRunningK8sData{namespace: "deis"},
+		mockRunningK8sData{namespace: "kube-system"},
+		mockRunningK8sData{namespace: "apps"},
+	}
+	engine := NewEngine(
+		mockCheck{name: "info", severity: SeverityInfo},
+		mockCheck{name: "broken", err: errors.New("boom")},
+		mockCheck{name: "critical", severity: SeverityCritical},
+		NodePressureCheck{},
+	)
+	findings := engine.Run(context.Background(), ks)
+	assert.Equal(t, len(findings), 6, "number of findings")
+	for i, ns := range []string{"deis", "kube-system", "apps"} {
+		assert.Equal(t, findings[i].Check, "critical", "check")
+		assert.Equal(t, findings[i].Namespace, ns, "namespace")
+		assert.Equal(t, findings[i+3].Severity, SeverityInfo, "severity")
+	}
+}
+
+func TestEngineRunClusterCheck(t *testing.T) {
+	node := api.Node{
+		ObjectMeta: api.ObjectMeta{Name: "node-1"},
+		Status: api.NodeStatus{Conditions: []api.NodeCondition{
+			{Type: api.NodeReady, Status: api.ConditionFalse, Reason: "KubeletNotReady"},
+		}},
+	}
+	ks := []k8s.RunningK8sData{
+		mockRunningK8sData{namespace: "deis", nodes: []api.Node{node}},
+		mockRunningK8sData{namespace: "apps", nodes: []api.Node{node}},
+	}
+	findings := NewEngine(NodePressureCheck{}).Run(context.Background(), ks)
+	assert.Equal(t, len(findings), 1, "number of findings")
+	assert.Equal(t, findings[0].Check, "node-pressure", "check")
+	assert.Equal(t, findings[0].Namespace, "", "namespace")
+	assert.Equal(t, findings[0].Severity, SeverityCritical, "severity")
+	assert.Equal(t, findings[0].Message, "node is not ready (KubeletNotReady)", "message")
+}
@@ -2,46 +2,112 @@ package handlers
 
 // handler echoes the HTTP request.
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/deis/workflow-manager/config"
 	"github.com/deis/workflow-manager/data"
+	"github.com/deis/workflow-manager/diagnostics"
+	"github.com/deis/workflow-manager/jobs"
 	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/metrics"
 	apiclient "github.com/deis/workflow-manager/pkg/swagger/client"
 	"github.com/deis/workflow-manager/pkg/swagger/client/operations"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
 	"github.com/gorilla/mux"
 	"github.com/satori/go.uuid"
 )
 
 const (
-	componentsRoute = "/components" // resource value for components route
-	idRoute         = "/id"         // resource value for ID route
-	doctorRoute     = "/doctor"
+	componentsRoute    = "/components" // resource value for components route
+	idRoute            = "/id"         // resource value for ID route
+	doctorRoute        = "/doctor"
+	doctorDiffRoute    = "/doctor/diff"    // resource value for doctor report diff route
+	doctorReportsRoute = "/doctor/reports" // resource value for the kept doctor reports listing route
+	doctorReportRoute  = "/doctor/{uuid}"  // resource value for kept doctor reports route
+	diagnosticsRoute   = "/diagnostics"    // resource value for diagnostic findings route
+	nodesRoute         = "/nodes"          // resource value for node summaries route
+	healthRoute        = "/healthz"        // resource value for liveness route
+	readyRoute         = "/readyz"         // resource value for readiness route
+	metricsRoute       = "/metrics"        // resource value for Prometheus metrics route
 )
 
-// RegisterRoutes attaches handler functions to routes
+// the formats that doctor reports can be downloaded in
+const (
+	doctorFormatJSON    = "json"
+	doctorFormatTarball = "tar.gz"
+	doctorFormatText    = "text" // for doctor diffs only
+)
+
+// doctorCurrentReport stands for a newly collected report in a doctor diff
+const doctorCurrentReport = "current"
+
+// doctorReportIDHeader carries the UUID of the doctor report in a response
+const doctorReportIDHeader = "X-Doctor-Report-ID"
+
+// RegisterRoutes attaches handler functions to routes. k8sResources is bound to the Deis namespace,
+// and namespaces to each namespace that's reported in doctor reports. installedData is used to list
+// the installed components, and doctorAPIClient is used to publish doctor reports, after they're
+// scrubbed according to redaction. doctorAPIClient must not be shared with clients for other APIs.
+// diagEngine runs the checks whose findings are served and embedded in doctor reports, and history
+// keeps the most recent doctor reports, or is nil if they aren't kept. Every request is logged with
+// a correlation ID
 func RegisterRoutes(
 	r *mux.Router,
 	availVers data.AvailableVersions,
 	k8sResources *k8s.ResourceInterfaceNamespaced,
+	namespaces []*k8s.ResourceInterfaceNamespaced,
+	installedData data.InstalledData,
+	clusterID data.ClusterID,
+	results *jobs.Results,
+	doctorAPIClient *apiclient.WorkflowManager,
+	redaction data.RedactionRules,
+	diagEngine *diagnostics.Engine,
+	history data.DoctorHistory,
 ) *mux.Router {
 
-	clusterID := data.NewClusterIDFromPersistentStorage(k8sResources.Secrets())
-	r.Handle(componentsRoute, ComponentsHandler(
-		data.NewInstalledDeisData(k8sResources),
+	r.Handle(componentsRoute, instrument(componentsRoute, markStaleVersions(availVers, ComponentsHandler(
+		installedData,
 		clusterID,
 		data.NewLatestReleasedComponent(k8sResources, availVers),
-	))
-	r.Handle(idRoute, IDHandler(clusterID))
-	doctorAPIClient, _ := config.GetSwaggerClient(config.Spec.DoctorAPIURL)
-	r.Handle(doctorRoute, DoctorHandler(
-		data.NewInstalledDeisData(k8sResources),
-		k8s.NewRunningK8sData(k8sResources),
+	))))
+	runningK8sData := make([]k8s.RunningK8sData, len(namespaces))
+	for i, ns := range namespaces {
+		runningK8sData[i] = k8s.NewRunningK8sData(ns)
+	}
+	r.Handle(idRoute, instrument(idRoute, IDHandler(clusterID)))
+	r.Handle(doctorRoute, instrument(doctorRoute, DoctorHandler(
+		installedData,
+		runningK8sData,
 		clusterID,
 		data.NewLatestReleasedComponent(k8sResources, availVers),
 		doctorAPIClient,
-	)).Methods("POST")
+		redaction,
+		diagEngine,
+		history,
+	))).Methods("GET", "POST")
+	// the listing and diff routes are registered first, so that they aren't matched as a report UUID
+	r.Handle(doctorReportsRoute, instrument(doctorReportsRoute, DoctorHistoryHandler(history))).Methods("GET")
+	r.Handle(doctorDiffRoute, instrument(doctorDiffRoute, DoctorDiffHandler(
+		installedData,
+		runningK8sData,
+		clusterID,
+		data.NewLatestReleasedComponent(k8sResources, availVers),
+		redaction,
+		diagEngine,
+		history,
+	))).Methods("GET")
+	r.Handle(doctorReportRoute, instrument(doctorReportRoute, DoctorReportHandler(history))).Methods("GET")
+	r.Handle(diagnosticsRoute, instrument(diagnosticsRoute, DiagnosticsHandler(runningK8sData, diagEngine)))
+	// nodes aren't namespaced, so they're summarized through the Deis namespace's data
+	r.Handle(nodesRoute, instrument(nodesRoute, NodesHandler(k8s.NewRunningK8sData(k8sResources))))
+	r.Handle(healthRoute, instrument(healthRoute, HealthHandler(clusterID, availVers, results)))
+	r.Handle(readyRoute, instrument(readyRoute, ReadinessHandler(k8sResources, clusterID, availVers, results)))
+	r.Handle(metricsRoute, metrics.Handler())
 	return r
 }
 
@@ -52,8 +118,9 @@ func ComponentsHandler(
 	availVers data.AvailableComponentVersion,
 ) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cluster, err := data.GetCluster(workflow, clusterID, availVers)
+		cluster, err := data.GetCluster(r.Context(), workflow, clusterID, availVers)
 		if err != nil {
+			logger.FromContext(r.Context()).WithError(err).Errorf("unable to get the installed components")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -63,27 +130,271 @@ func ComponentsHandler(
 	})
 }
 
-// DoctorHandler route handler
+// DoctorHandler route handler. Reports embed the findings of diagEngine, and are scrubbed according
+// to redaction before they leave the handler. A POST publishes the report to the doctor API and
+// responds with its UUID. A GET, or a POST with ?publish=false, responds with the report itself:
+// as JSON, or as a tarball with ?format=tar.gz. Either way, the report is kept in history unless
+// history is nil
 func DoctorHandler(
 	workflow data.InstalledData,
-	k8sData k8s.RunningK8sData,
+	k8sData []k8s.RunningK8sData,
 	clusterID data.ClusterID,
 	availVers data.AvailableComponentVersion,
 	apiClient *apiclient.WorkflowManager,
+	redaction data.RedactionRules,
+	diagEngine *diagnostics.Engine,
+	history data.DoctorHistory,
 ) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		doctor, err := data.GetDoctorInfo(workflow, k8sData, clusterID, availVers)
+		log := logger.FromContext(r.Context())
+		publish := r.Method == "POST" && r.URL.Query().Get("publish") != "false"
+		format := r.URL.Query().Get("format")
+		if !publish && !validDoctorFormat(format) {
+			http.Error(w, fmt.Sprintf("unknown doctor report format %q", format), http.StatusBadRequest)
+			return
+		}
+		doctor, err := collectDoctorReport(r, workflow, k8sData, clusterID, availVers, redaction, diagEngine)
 		if err != nil {
+			log.WithError(err).Errorf("unable to collect the doctor report")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		entry := data.DoctorReportEntry{UUID: uuid.NewV4().String(), CreatedAt: time.Now().UTC()}
+		var publishErr error
+		if publish {
+			_, publishErr = config.WithContext(r.Context(), apiClient).Operations.PublishDoctorInfo(&operations.PublishDoctorInfoParams{
+				Body: &doctor,
+				UUID: entry.UUID,
+			})
+			entry.Published = publishErr == nil
+		}
+		// reports that couldn't be published are kept too, so that they can still be retrieved
+		if history != nil {
+			if err := history.Save(entry, doctor); err != nil {
+				log.WithError(err).Warnf("unable to keep doctor report %s", entry.UUID)
+			}
+		}
+		if !publish {
+			w.Header().Set(doctorReportIDHeader, entry.UUID)
+			if err := writeDoctorReport(w, doctor, format, entry.CreatedAt); err != nil {
+				log.WithError(err).Errorf("unable to write the doctor report")
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		if publishErr != nil {
+			log.WithError(publishErr).Errorf("unable to publish doctor report %s", entry.UUID)
+			http.Error(w, publishErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Infof("published doctor report %s", entry.UUID)
+		writePlainText(entry.UUID, w)
+	})
+}
+
+// DoctorHistoryHandler route handler. It responds with the entries of the doctor reports kept in
+// history, newest first
+func DoctorHistoryHandler(history data.DoctorHistory) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entries := []data.DoctorReportEntry{}
+		if history != nil {
+			var err error
+			entries, err = history.List()
+			if err != nil {
+				logger.FromContext(r.Context()).WithError(err).Errorf("unable to list the kept doctor reports")
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// DoctorReportHandler route handler. It responds with the doctor report kept in history under the
+// UUID in the route: as JSON, or as a tarball with ?format=tar.gz
+func DoctorReportHandler(history data.DoctorHistory) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+		format := r.URL.Query().Get("format")
+		if !validDoctorFormat(format) {
+			http.Error(w, fmt.Sprintf("unknown doctor report format %q", format), http.StatusBadRequest)
+			return
+		}
+		uid := mux.Vars(r)["uuid"]
+		entry, doctor, err := getKeptDoctorReport(history, uid)
+		if err == data.ErrDoctorReportNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
-		uid := uuid.NewV4().String()
-		_, err = apiClient.Operations.PublishDoctorInfo(&operations.PublishDoctorInfoParams{Body: &doctor, UUID: uid})
 		if err != nil {
+			log.WithError(err).Errorf("unable to get doctor report %s", uid)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		writePlainText(uid, w)
+		w.Header().Set(doctorReportIDHeader, entry.UUID)
+		if err := writeDoctorReport(w, doctor, format, entry.CreatedAt); err != nil {
+			log.WithError(err).Errorf("unable to write doctor report %s", uid)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// DoctorDiffHandler route handler. It responds with the changes between the doctor reports in the
+// from and to query parameters: the UUID of a report kept in history, or "current" for a report
+// that's collected like DoctorHandler's, which to defaults to. The diff is JSON, or human readable
+// text with ?format=text
+func DoctorDiffHandler(
+	workflow data.InstalledData,
+	k8sData []k8s.RunningK8sData,
+	clusterID data.ClusterID,
+	availVers data.AvailableComponentVersion,
+	redaction data.RedactionRules,
+	diagEngine *diagnostics.Engine,
+	history data.DoctorHistory,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format != "" && format != doctorFormatJSON && format != doctorFormatText {
+			http.Error(w, fmt.Sprintf("unknown doctor diff format %q", format), http.StatusBadRequest)
+			return
+		}
+		fromID := query.Get("from")
+		if fromID == "" {
+			http.Error(w, "a doctor report to diff from is required", http.StatusBadRequest)
+			return
+		}
+		toID := query.Get("to")
+		if toID == "" {
+			toID = doctorCurrentReport
+		}
+		reports := make([]models.DoctorInfo, 2)
+		for i, id := range []string{fromID, toID} {
+			var err error
+			if id == doctorCurrentReport {
+				reports[i], err = collectDoctorReport(r, workflow, k8sData, clusterID, availVers, redaction, diagEngine)
+			} else {
+				_, reports[i], err = getKeptDoctorReport(history, id)
+			}
+			if err == data.ErrDoctorReportNotFound {
+				http.Error(w, fmt.Sprintf("doctor report %s not found", id), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.WithError(err).Errorf("unable to get doctor report %s", id)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		diff := data.DiffDoctorReports(reports[0], reports[1])
+		diff.From = fromID
+		diff.To = toID
+		var buf bytes.Buffer
+		contentType := "application/json"
+		if format == doctorFormatText {
+			contentType = "text/plain"
+			if err := diff.WriteText(&buf); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		} else if err := json.NewEncoder(&buf).Encode(diff); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		buf.WriteTo(w)
+	})
+}
+
+// collectDoctorReport collects a doctor report for r, embeds the findings of diagEngine in it, and
+// scrubs it according to redaction
+func collectDoctorReport(
+	r *http.Request,
+	workflow data.InstalledData,
+	k8sData []k8s.RunningK8sData,
+	clusterID data.ClusterID,
+	availVers data.AvailableComponentVersion,
+	redaction data.RedactionRules,
+	diagEngine *diagnostics.Engine,
+) (models.DoctorInfo, error) {
+	doctor, err := data.GetDoctorInfo(r.Context(), workflow, k8sData, clusterID, availVers)
+	if err != nil {
+		return models.DoctorInfo{}, err
+	}
+	doctor.Findings = diagEngine.Run(r.Context(), k8sData)
+	if err := data.RedactDoctorInfo(&doctor, redaction); err != nil {
+		return models.DoctorInfo{}, err
+	}
+	return doctor, nil
+}
+
+// getKeptDoctorReport returns the doctor report kept in history under uid, and its entry. It returns
+// data.ErrDoctorReportNotFound if uid isn't a UUID, or if history is nil
+func getKeptDoctorReport(history data.DoctorHistory, uid string) (data.DoctorReportEntry, models.DoctorInfo, error) {
+	if _, err := uuid.FromString(uid); err != nil || history == nil {
+		return data.DoctorReportEntry{}, models.DoctorInfo{}, data.ErrDoctorReportNotFound
+	}
+	return history.Get(uid)
+}
+
+// validDoctorFormat returns true if format is a format that doctor reports can be downloaded in, or
+// empty
+func validDoctorFormat(format string) bool {
+	return format == "" || format == doctorFormatJSON || format == doctorFormatTarball
+}
+
+// writeDoctorReport writes doctor, which was collected at createdAt, to w in the given format, which
+// is doctorFormatJSON if it's empty
+func writeDoctorReport(w http.ResponseWriter, doctor models.DoctorInfo, format string, createdAt time.Time) error {
+	var buf bytes.Buffer
+	contentType := "application/json"
+	if format == doctorFormatTarball {
+		contentType = "application/gzip"
+		if err := data.WriteDoctorBundle(&buf, doctor); err != nil {
+			return err
+		}
+		filename := fmt.Sprintf("doctor-%s.tar.gz", createdAt.UTC().Format("20060102T150405Z"))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	} else if err := json.NewEncoder(&buf).Encode(doctor); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// DiagnosticsHandler route handler. It responds with the findings of diagEngine for k8sData, most
+// severe first
+func DiagnosticsHandler(k8sData []k8s.RunningK8sData, diagEngine *diagnostics.Engine) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		findings := diagEngine.Run(r.Context(), k8sData)
+		if findings == nil {
+			findings = []*models.Finding{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(findings); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// NodesHandler route handler. It responds with a health and capacity summary of each node
+func NodesHandler(k8sData k8s.RunningK8sData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		summaries, err := k8s.GetNodeSummariesModels(k8sData)
+		if err != nil {
+			logger.FromContext(r.Context()).WithError(err).Errorf("unable to summarize the nodes")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(summaries); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 	})
 }
 
@@ -1,16 +1,25 @@
 package handlers
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/arschles/assert"
 	"github.com/deis/workflow-manager/config"
 	"github.com/deis/workflow-manager/data"
+	"github.com/deis/workflow-manager/diagnostics"
+	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/logger"
+	apiclient "github.com/deis/workflow-manager/pkg/swagger/client"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
 	"github.com/gorilla/mux"
 	"github.com/satori/go.uuid"
@@ -23,8 +32,8 @@ const mockInstalledComponentVersion = "1.2.3"
 // Creating a novel mock struct that fulfills the data.InstalledData interface
 type mockInstalledComponents struct{}
 
-func (g mockInstalledComponents) Get() ([]byte, error) {
-	return []byte(fmt.Sprintf(`{
+func (g mockInstalledComponents) Get() (models.Cluster, error) {
+	return data.ParseJSONCluster([]byte(fmt.Sprintf(`{
 	  "components": [
 	    {
 	      "component": {
@@ -36,12 +45,16 @@ func (g mockInstalledComponents) Get() ([]byte, error) {
 	      }
 	    }
 	  ]
-	}`, mockInstalledComponentName, mockInstalledComponentDescription, mockInstalledComponentVersion)), nil
+	}`, mockInstalledComponentName, mockInstalledComponentDescription, mockInstalledComponentVersion)))
 }
 
 // Creating a novel mock struct that fulfills the data.RunningK8sData interface
 type mockRunningK8sData struct{}
 
+func (g mockRunningK8sData) Namespace() string {
+	return "deis"
+}
+
 func (g mockRunningK8sData) DaemonSets() ([]*models.K8sResource, error) {
 	// TODO: implement
 	return []*models.K8sResource{}, nil
@@ -57,11 +70,21 @@ func (g mockRunningK8sData) Events() ([]*models.K8sResource, error) {
 	return []*models.K8sResource{}, nil
 }
 
+func (g mockRunningK8sData) Logs(log *logger.Logger, opts k8s.PodLogOptions) ([]*models.ContainerLog, error) {
+	// TODO: implement
+	return []*models.ContainerLog{}, nil
+}
+
 func (g mockRunningK8sData) Nodes() ([]*models.K8sResource, error) {
 	// TODO: implement
 	return []*models.K8sResource{}, nil
 }
 
+func (g mockRunningK8sData) NodeSummaries() ([]*models.NodeSummary, error) {
+	// TODO: implement
+	return []*models.NodeSummary{}, nil
+}
+
 func (g mockRunningK8sData) Pods() ([]*models.K8sResource, error) {
 	// TODO: implement
 	return []*models.K8sResource{}, nil
@@ -108,13 +131,20 @@ const mockAvailableComponentVersion = "3.2.1"
 // Creating a novel mock struct that fulfills the data.AvailableComponentVersion interface
 type mockAvailableVersion struct{}
 
-func (c mockAvailableVersion) Get(component string, cluster models.Cluster) (models.Version, error) {
-	if component == "component" {
+func (c mockAvailableVersion) Get(ctx context.Context, component models.Component, cluster models.Cluster) (models.Version, error) {
+	if component.Name == "component" {
 		return models.Version{Version: "v2-beta"}, nil
 	}
 	return models.Version{}, fmt.Errorf("mock getter only accepts 'component' arg")
 }
 
+// Creating a novel mock struct that fulfills the data.RedactionKey interface
+type mockRedactionKey struct{}
+
+func (r mockRedactionKey) Get() ([]byte, error) {
+	return []byte("redaction-key"), nil
+}
+
 type genericJSON struct {
 	Foo string `json:"foo"`
 }
@@ -136,8 +166,21 @@ func TestComponentsHandler(t *testing.T) {
 	assert.Equal(t, cluster.Components[0].Component.Name, mockInstalledComponentName, "Name value")
 	assert.Equal(t, *cluster.Components[0].Component.Description, mockInstalledComponentDescription, "Description value")
 	assert.Equal(t, cluster.Components[0].Version.Version, mockInstalledComponentVersion, "Version value")
-	//TODO
-	//assert.Equal(t, cluster.Components[0].UpdateAvailable, mockAvailableComponentVersion, "available Version value")
+	assert.True(t, cluster.Components[0].UpdateAvailable != nil, "no update available")
+	assert.Equal(t, *cluster.Components[0].UpdateAvailable, "v2-beta", "available Version value")
+}
+
+func getTestDoctorHandler(apiClient *apiclient.WorkflowManager, history data.DoctorHistory) http.Handler {
+	return DoctorHandler(
+		mockInstalledComponents{},
+		[]k8s.RunningK8sData{mockRunningK8sData{}}, // TODO: mock k8s node data
+		&mockClusterID{},
+		mockAvailableVersion{},
+		apiClient,
+		data.RedactionRules{DropEnvValues: true, HashAddresses: true, HashKey: mockRedactionKey{}},
+		diagnostics.NewEngine(diagnostics.ConfiguredChecks()...),
+		history,
+	)
 }
 
 func TestDoctorHandler(t *testing.T) {
@@ -150,14 +193,9 @@ func TestDoctorHandler(t *testing.T) {
 	}))
 	defer ts.Close()
 	apiClient, err := config.GetSwaggerClient(ts.URL)
-	doctorHandler := DoctorHandler(
-		mockInstalledComponents{},
-		mockRunningK8sData{}, // TODO: mock k8s node data
-		&mockClusterID{},
-		mockAvailableVersion{},
-		apiClient,
-	)
-	resp, err := getTestHandlerResponse(doctorHandler)
+	assert.NoErr(t, err)
+	doctorHandler := getTestDoctorHandler(apiClient, nil)
+	resp, err := getTestHandlerResponseFor(doctorHandler, "POST", "/")
 	assert.NoErr(t, err)
 	assert200(t, resp)
 	respData, err := ioutil.ReadAll(resp.Body)
@@ -167,7 +205,7 @@ func TestDoctorHandler(t *testing.T) {
 	assert.NoErr(t, err)
 	// invoke the handler a 2nd time to ensure that unique IDs are created for
 	// each request
-	resp2, err := getTestHandlerResponse(doctorHandler)
+	resp2, err := getTestHandlerResponseFor(doctorHandler, "POST", "/")
 	assert.NoErr(t, err)
 	assert200(t, resp2)
 	respData2, err := ioutil.ReadAll(resp2.Body)
@@ -179,6 +217,176 @@ func TestDoctorHandler(t *testing.T) {
 	}
 }
 
+func TestDoctorHandlerLocal(t *testing.T) {
+	published := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		published = true
+	}))
+	defer ts.Close()
+	apiClient, err := config.GetSwaggerClient(ts.URL)
+	assert.NoErr(t, err)
+	doctorHandler := getTestDoctorHandler(apiClient, nil)
+	for _, req := range []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/"},
+		{"GET", "/?format=json"},
+		{"POST", "/?publish=false"},
+		{"POST", "/?publish=false&format=json"},
+	} {
+		resp, err := getTestHandlerResponseFor(doctorHandler, req.method, req.path)
+		assert.NoErr(t, err)
+		assert200(t, resp)
+		assert.Equal(t, resp.Header.Get("Content-Type"), "application/json", "Content-Type value")
+		doctor := new(models.DoctorInfo)
+		assert.NoErr(t, json.NewDecoder(resp.Body).Decode(doctor))
+		assert.Equal(t, doctor.Workflow.ID, mockID, "cluster ID")
+		assert.Equal(t, len(doctor.Namespaces), 1, "number of namespaces")
+	}
+	resp, err := getTestHandlerResponseFor(doctorHandler, "GET", "/?format=tar.gz")
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "application/gzip", "Content-Type value")
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment;"), "Content-Disposition was %q", resp.Header.Get("Content-Disposition"))
+	gzr, err := gzip.NewReader(resp.Body)
+	assert.NoErr(t, err)
+	_, err = tar.NewReader(gzr).Next()
+	assert.NoErr(t, err)
+	resp, err = getTestHandlerResponseFor(doctorHandler, "GET", "/?format=xml")
+	assert.NoErr(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest, "response code")
+	assert.False(t, published, "a local doctor report was published")
+}
+
+func TestDoctorHistoryHandlers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doctor")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+	history, err := data.NewDoctorHistoryFromDir(dir, 2)
+	assert.NoErr(t, err)
+	apiClient, err := config.GetSwaggerClient("http://localhost:0")
+	assert.NoErr(t, err)
+	doctorHandler := getTestDoctorHandler(apiClient, history)
+	var uids []string
+	for i := 0; i < 3; i++ {
+		resp, err := getTestHandlerResponseFor(doctorHandler, "POST", "/?publish=false")
+		assert.NoErr(t, err)
+		assert200(t, resp)
+		uids = append(uids, resp.Header.Get(doctorReportIDHeader))
+	}
+
+	resp, err := getTestHandlerResponse(DoctorHistoryHandler(history))
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	var entries []data.DoctorReportEntry
+	assert.NoErr(t, json.NewDecoder(resp.Body).Decode(&entries))
+	assert.Equal(t, len(entries), 2, "number of kept reports")
+	assert.Equal(t, entries[0].UUID, uids[2], "newest report UUID")
+	assert.False(t, entries[0].Published, "a local doctor report was listed as published")
+
+	reportHandler := DoctorReportHandler(history)
+	resp, err = getTestHandlerResponseAt(reportHandler, doctorReportRoute, "GET", "/doctor/"+uids[1])
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	doctor := new(models.DoctorInfo)
+	assert.NoErr(t, json.NewDecoder(resp.Body).Decode(doctor))
+	assert.Equal(t, doctor.Workflow.ID, mockID, "cluster ID")
+	for _, path := range []string{"/doctor/" + uids[0], "/doctor/not-a-uuid"} {
+		resp, err = getTestHandlerResponseAt(reportHandler, doctorReportRoute, "GET", path)
+		assert.NoErr(t, err)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound, "response code for "+path)
+	}
+
+	// without a history, nothing is kept
+	resp, err = getTestHandlerResponse(DoctorHistoryHandler(nil))
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	entries = nil
+	assert.NoErr(t, json.NewDecoder(resp.Body).Decode(&entries))
+	assert.Equal(t, len(entries), 0, "number of kept reports without a history")
+}
+
+func TestDoctorDiffHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doctor")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+	history, err := data.NewDoctorHistoryFromDir(dir, 2)
+	assert.NoErr(t, err)
+	apiClient, err := config.GetSwaggerClient("http://localhost:0")
+	assert.NoErr(t, err)
+	resp, err := getTestHandlerResponseFor(getTestDoctorHandler(apiClient, history), "POST", "/?publish=false")
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	uid := resp.Header.Get(doctorReportIDHeader)
+	diffHandler := DoctorDiffHandler(
+		mockInstalledComponents{},
+		[]k8s.RunningK8sData{mockRunningK8sData{}},
+		&mockClusterID{},
+		mockAvailableVersion{},
+		data.RedactionRules{DropEnvValues: true, HashAddresses: true, HashKey: mockRedactionKey{}},
+		diagnostics.NewEngine(diagnostics.ConfiguredChecks()...),
+		history,
+	)
+
+	resp, err = getTestHandlerResponseFor(diffHandler, "GET", "/?from="+uid)
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "application/json", "Content-Type value")
+	diff := new(data.DoctorDiff)
+	assert.NoErr(t, json.NewDecoder(resp.Body).Decode(diff))
+	assert.Equal(t, diff.From, uid, "diff from")
+	assert.Equal(t, diff.To, doctorCurrentReport, "diff to")
+	assert.Equal(t, len(diff.Components), 0, "number of component changes")
+
+	resp, err = getTestHandlerResponseFor(diffHandler, "GET", "/?from="+uid+"&to="+uid+"&format=text")
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "text/plain", "Content-Type value")
+	text, err := ioutil.ReadAll(resp.Body)
+	assert.NoErr(t, err)
+	assert.True(t, strings.Contains(string(text), "No changes"), "diff of a report with itself was %q", text)
+
+	for _, req := range []struct {
+		path string
+		code int
+	}{
+		{"/", http.StatusBadRequest},
+		{"/?from=" + uid + "&format=xml", http.StatusBadRequest},
+		{"/?from=" + uuid.NewV4().String(), http.StatusNotFound},
+		{"/?from=" + uid + "&to=yesterday", http.StatusNotFound},
+	} {
+		resp, err = getTestHandlerResponseFor(diffHandler, "GET", req.path)
+		assert.NoErr(t, err)
+		assert.Equal(t, resp.StatusCode, req.code, "response code for "+req.path)
+	}
+}
+
+func TestDiagnosticsHandler(t *testing.T) {
+	diagnosticsHandler := DiagnosticsHandler(
+		[]k8s.RunningK8sData{mockRunningK8sData{}},
+		diagnostics.NewEngine(diagnostics.ConfiguredChecks()...),
+	)
+	resp, err := getTestHandlerResponse(diagnosticsHandler)
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "application/json", "Content-Type value")
+	var findings []*models.Finding
+	assert.NoErr(t, json.NewDecoder(resp.Body).Decode(&findings))
+	assert.True(t, findings != nil, "findings weren't a JSON array")
+	assert.Equal(t, len(findings), 0, "number of findings")
+}
+
+func TestNodesHandler(t *testing.T) {
+	resp, err := getTestHandlerResponse(NodesHandler(mockRunningK8sData{}))
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "application/json", "Content-Type value")
+	var summaries []*models.NodeSummary
+	assert.NoErr(t, json.NewDecoder(resp.Body).Decode(&summaries))
+	assert.True(t, summaries != nil, "node summaries weren't a JSON array")
+}
+
 func TestIDHandler(t *testing.T) {
 	idHandler := IDHandler(&mockClusterID{})
 	resp, err := getTestHandlerResponse(idHandler)
@@ -203,11 +411,27 @@ func TestWritePlainText(t *testing.T) {
 }
 
 func getTestHandlerResponse(handler http.Handler) (*http.Response, error) {
+	return getTestHandlerResponseFor(handler, "GET", "/")
+}
+
+// getTestHandlerResponseFor serves a request with the given method and path (which may include a
+// query) with handler, routed at "/"
+func getTestHandlerResponseFor(handler http.Handler, method, path string) (*http.Response, error) {
+	return getTestHandlerResponseAt(handler, "/", method, path)
+}
+
+// getTestHandlerResponseAt serves a request with the given method and path with handler, routed at
+// route
+func getTestHandlerResponseAt(handler http.Handler, route, method, path string) (*http.Response, error) {
 	r := mux.NewRouter()
-	r.Handle("/", handler)
+	r.Handle(route, handler)
 	server := httptest.NewServer(r)
 	defer server.Close()
-	resp, err := http.Get(server.URL)
+	req, err := http.NewRequest(method, server.URL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/data"
+	"github.com/deis/workflow-manager/jobs"
+	"github.com/deis/workflow-manager/k8s"
+)
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+
+	versionsFetchedAtHeader = "X-Versions-Fetched-At"
+)
+
+// healthReport is the JSON body returned by the health and readiness handlers. Kubernetes is only
+// checked for readiness
+type healthReport struct {
+	Status            string                  `json:"status"`
+	Kubernetes        *checkStatus            `json:"kubernetes,omitempty"`
+	ClusterID         checkStatus             `json:"clusterID"`
+	AvailableVersions availableVersionsStatus `json:"availableVersions"`
+	Jobs              []jobs.Result           `json:"jobs"`
+}
+
+// checkStatus is the outcome of a single health check
+type checkStatus struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// availableVersionsStatus describes the state of the available versions cache. OK is true if any
+// versions are cached, including those persisted by an earlier process, and Refreshed is true once
+// they've been refreshed by this one
+type availableVersionsStatus struct {
+	OK         bool       `json:"ok"`
+	Refreshed  bool       `json:"refreshed"`
+	Stale      bool       `json:"stale"`
+	CachedAt   *time.Time `json:"cachedAt,omitempty"`
+	AgeSeconds float64    `json:"ageSeconds,omitempty"`
+}
+
+// HealthHandler route handler. It only checks the process itself, so it always responds with 200:
+// an unreachable Kubernetes API or versions service makes the manager unready, but restarting it
+// wouldn't help. The response body contains the health report, without the Kubernetes check
+func HealthHandler(
+	clusterID data.ClusterID,
+	availVers data.AvailableVersions,
+	results *jobs.Results,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(getHealthReport(clusterID, availVers, results), w)
+	})
+}
+
+// ReadinessHandler route handler. It responds with 200 if the Kubernetes API is reachable and
+// the available versions have been successfully refreshed at least once since the manager started,
+// and 503 otherwise. The response body always contains the full health report
+func ReadinessHandler(
+	pinger k8s.Pinger,
+	clusterID data.ClusterID,
+	availVers data.AvailableVersions,
+	results *jobs.Results,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := getHealthReport(clusterID, availVers, results)
+		report.Kubernetes = new(checkStatus)
+		if err := pinger.Ping(); err != nil {
+			report.Kubernetes.Error = err.Error()
+		} else {
+			report.Kubernetes.OK = true
+		}
+		if !report.Kubernetes.OK || !report.AvailableVersions.Refreshed {
+			report.Status = statusFail
+		}
+		writeHealthReport(report, w)
+	})
+}
+
+// getHealthReport runs the health checks that don't leave the process, and returns a report with an
+// "ok" status. Callers decide which checks are required to pass
+func getHealthReport(
+	clusterID data.ClusterID,
+	availVers data.AvailableVersions,
+	results *jobs.Results,
+) healthReport {
+	report := healthReport{Status: statusOK, Jobs: results.Get()}
+	report.ClusterID.OK = clusterID.Cached() != ""
+	if cachedAt := availVers.CachedAt(); !cachedAt.IsZero() {
+		report.AvailableVersions = availableVersionsStatus{
+			OK:         true,
+			Stale:      data.VersionsAreStale(availVers, versionsCacheMaxAge()),
+			CachedAt:   &cachedAt,
+			AgeSeconds: time.Since(cachedAt).Seconds(),
+		}
+	}
+	// the cache may have been loaded from an earlier process, so only a successful run of the job
+	// that refreshes it counts
+	report.AvailableVersions.Refreshed = results.Succeeded(jobs.LatestVersionDataJob)
+	return report
+}
+
+// writeHealthReport is a helper function for writing a health report as JSON, using a status code
+// that matches its status
+func writeHealthReport(report healthReport, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != statusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// markStaleVersions wraps h so that responses carry the time at which the available versions were
+// fetched, and a "110 Response is Stale" warning if they are older than the configured maximum age
+func markStaleVersions(availVers data.AvailableVersions, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cachedAt := availVers.CachedAt(); !cachedAt.IsZero() {
+			w.Header().Set(versionsFetchedAtHeader, cachedAt.UTC().Format(time.RFC3339))
+		}
+		if data.VersionsAreStale(availVers, versionsCacheMaxAge()) {
+			w.Header().Set("Warning", `110 workflow-manager "Available versions are stale"`)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func versionsCacheMaxAge() time.Duration {
+	return time.Duration(config.Spec.VersionsCacheMaxAge) * time.Second
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/jobs"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+// Creating a novel mock struct that fulfills the k8s.Pinger interface
+type mockPinger struct {
+	err error
+}
+
+func (p mockPinger) Ping() error {
+	return p.err
+}
+
+// Creating a novel mock struct that fulfills the data.AvailableVersions interface
+type mockAvailableVersions struct {
+	cachedAt time.Time
+}
+
+func (a mockAvailableVersions) Cached() []models.ComponentVersion {
+	return nil
+}
+
+func (a mockAvailableVersions) Refresh(ctx context.Context, cluster models.Cluster) ([]models.ComponentVersion, error) {
+	return nil, nil
+}
+
+func (a mockAvailableVersions) Store(c []models.ComponentVersion) {}
+
+func (a mockAvailableVersions) CachedAt() time.Time {
+	return a.cachedAt
+}
+
+func TestHealthHandler(t *testing.T) {
+	handler := HealthHandler(&mockClusterID{cached: mockID}, mockAvailableVersions{}, jobs.NewResults())
+	resp, err := getTestHandlerResponse(handler)
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	report := decodeHealthReport(t, resp)
+	assert.Equal(t, report.Status, statusOK, "health status")
+	assert.True(t, report.Kubernetes == nil, "liveness checked the kubernetes API")
+	assert.True(t, report.ClusterID.OK, "cluster ID check failed")
+	assert.False(t, report.AvailableVersions.OK, "available versions check passed before the first refresh")
+
+	// liveness doesn't depend on anything outside the process
+	handler = HealthHandler(&mockClusterID{}, mockAvailableVersions{}, jobs.NewResults())
+	resp, err = getTestHandlerResponse(handler)
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	report = decodeHealthReport(t, resp)
+	assert.Equal(t, report.Status, statusOK, "health status")
+	assert.False(t, report.ClusterID.OK, "cluster ID check passed before the ID was resolved")
+}
+
+func TestReadinessHandler(t *testing.T) {
+	handler := ReadinessHandler(mockPinger{}, &mockClusterID{}, mockAvailableVersions{}, jobs.NewResults())
+	resp, err := getTestHandlerResponse(handler)
+	assert.NoErr(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusServiceUnavailable, "response code before the first refresh")
+
+	// versions persisted by an earlier process don't make the manager ready
+	cachedAt := time.Now().Add(-time.Minute)
+	results := jobs.NewResults()
+	handler = ReadinessHandler(mockPinger{}, &mockClusterID{}, mockAvailableVersions{cachedAt: cachedAt}, results)
+	resp, err = getTestHandlerResponse(handler)
+	assert.NoErr(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusServiceUnavailable, "response code with only persisted versions")
+	report := decodeHealthReport(t, resp)
+	assert.True(t, report.AvailableVersions.OK, "available versions check failed")
+	assert.False(t, report.AvailableVersions.Refreshed, "available versions were refreshed before the first refresh")
+
+	results.Record(jobs.LatestVersionDataJob, time.Now(), time.Second, nil)
+	resp, err = getTestHandlerResponse(handler)
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	report = decodeHealthReport(t, resp)
+	assert.True(t, report.Kubernetes != nil && report.Kubernetes.OK, "kubernetes check failed")
+	assert.True(t, report.AvailableVersions.Refreshed, "available versions weren't refreshed")
+	assert.True(t, report.AvailableVersions.AgeSeconds >= 60, "available versions cache age was %f", report.AvailableVersions.AgeSeconds)
+
+	handler = ReadinessHandler(mockPinger{err: errors.New("unreachable")}, &mockClusterID{}, mockAvailableVersions{cachedAt: cachedAt}, results)
+	resp, err = getTestHandlerResponse(handler)
+	assert.NoErr(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusServiceUnavailable, "response code")
+	report = decodeHealthReport(t, resp)
+	assert.Equal(t, report.Status, statusFail, "readiness status")
+	assert.Equal(t, report.Kubernetes.Error, "unreachable", "kubernetes check error")
+}
+
+func TestMarkStaleVersions(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	resp, err := getTestHandlerResponse(markStaleVersions(mockAvailableVersions{}, ok))
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	assert.Equal(t, resp.Header.Get(versionsFetchedAtHeader), "", "fetched-at header before the first refresh")
+	assert.Equal(t, resp.Header.Get("Warning"), "", "warning header before the first refresh")
+
+	fresh := time.Now().Add(-time.Minute)
+	resp, err = getTestHandlerResponse(markStaleVersions(mockAvailableVersions{cachedAt: fresh}, ok))
+	assert.NoErr(t, err)
+	assert.Equal(t, resp.Header.Get(versionsFetchedAtHeader), fresh.UTC().Format(time.RFC3339), "fetched-at header")
+	assert.Equal(t, resp.Header.Get("Warning"), "", "warning header for fresh versions")
+
+	stale := time.Now().Add(-versionsCacheMaxAge() - time.Minute)
+	resp, err = getTestHandlerResponse(markStaleVersions(mockAvailableVersions{cachedAt: stale}, ok))
+	assert.NoErr(t, err)
+	assert200(t, resp)
+	assert.True(t, resp.Header.Get("Warning") != "", "no warning header for stale versions")
+}
+
+func decodeHealthReport(t *testing.T, resp *http.Response) healthReport {
+	defer resp.Body.Close()
+	var report healthReport
+	assert.NoErr(t, json.NewDecoder(resp.Body).Decode(&report))
+	return report
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/metrics"
+)
+
+// requestIDHeader carries the correlation ID of a request. It's reused if the client sends one, and
+// is always set on the response
+const requestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen is the length of the longest client supplied request ID that's reused
+const maxRequestIDLen = 128
+
+// instrument wraps h so that every request it serves is recorded in the metrics under route, and
+// is logged with a correlation ID
+func instrument(route string, h http.Handler) http.Handler {
+	return logRequests(route, metrics.InstrumentHandler(route, h))
+}
+
+// logRequests wraps h so that every request it serves carries a logger with a correlation ID in
+// its context, and is logged once it has been served. Probes are logged at debug level
+func logRequests(route string, h http.Handler) http.Handler {
+	level := logger.InfoLevel
+	if route == healthRoute || route == readyRoute {
+		level = logger.DebugLevel
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > maxRequestIDLen {
+			id = logger.NewCorrelationID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		l := logger.FromContext(r.Context()).WithFields(logger.Fields{
+			logger.CorrelationIDField: id,
+			"route":                   route,
+		})
+		start := time.Now()
+		lw := &loggedResponseWriter{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(lw, r.WithContext(logger.NewContext(r.Context(), l)))
+		l = l.WithFields(logger.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      lw.code,
+			"duration_ms": time.Since(start).Seconds() * 1000,
+		})
+		switch {
+		case lw.code >= http.StatusInternalServerError:
+			l.Warnf("served request")
+		case level == logger.DebugLevel:
+			l.Debugf("served request")
+		default:
+			l.Infof("served request")
+		}
+	})
+}
+
+// loggedResponseWriter is an http.ResponseWriter that remembers the status code written to it
+type loggedResponseWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+// WriteHeader is the http.ResponseWriter interface implementation
+func (l *loggedResponseWriter) WriteHeader(code int) {
+	l.code = code
+	l.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/logger"
+)
+
+func TestLogRequests(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := logRequests(idRoute, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the handler's logger carries the request's correlation ID
+		logger.FromContext(r.Context()).Infof("handling")
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	ctx := logger.NewContext(context.Background(), logger.New(buf, logger.DebugLevel))
+
+	req, err := http.NewRequest("GET", idRoute, nil)
+	assert.NoErr(t, err)
+	req.Header.Set(requestIDHeader, "client-id")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req.WithContext(ctx))
+	assert.Equal(t, w.Header().Get(requestIDHeader), "client-id", "request ID header")
+	dec := json.NewDecoder(buf)
+	var handling, served map[string]interface{}
+	assert.NoErr(t, dec.Decode(&handling))
+	assert.NoErr(t, dec.Decode(&served))
+	assert.Equal(t, handling[logger.CorrelationIDField], "client-id", "handler correlation ID")
+	assert.Equal(t, served[logger.CorrelationIDField], "client-id", "served correlation ID")
+	assert.Equal(t, served["status"], float64(http.StatusInternalServerError), "served status")
+	assert.Equal(t, served["level"], "warn", "served level")
+
+	// a new ID is generated if the client doesn't send one
+	req, err = http.NewRequest("GET", idRoute, nil)
+	assert.NoErr(t, err)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req.WithContext(ctx))
+	id := w.Header().Get(requestIDHeader)
+	assert.True(t, id != "" && id != "client-id", "expected a new request ID, got %q", id)
+}
@@ -1,43 +1,118 @@
 package jobs
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/deis/workflow-manager/config"
 	"github.com/deis/workflow-manager/data"
 	"github.com/deis/workflow-manager/k8s"
-	apiclient "github.com/deis/workflow-manager/pkg/swagger/client"
-	"github.com/deis/workflow-manager/pkg/swagger/client/operations"
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/metrics"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"github.com/deis/workflow-manager/upstream"
 )
 
 // Periodic is an interface for managing periodic job invocation
 type Periodic interface {
-	// Do begins the periodic job. It starts the first execution of the job, and then is
-	// repsonsible for executing it every Frequency() thereafter
-	Do() error
+	// Do executes a single run of the periodic job. Implementations should return early, with
+	// ctx.Err(), when ctx is done
+	Do(ctx context.Context) error
 	Frequency() time.Duration
+	// Name returns a short, unique name for the job, used when reporting its results
+	Name() string
 }
 
+// Result is the outcome of the most recent execution of a Periodic
+type Result struct {
+	Name            string    `json:"name"`
+	LastRun         time.Time `json:"lastRun"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	Error           string    `json:"error,omitempty"`
+	// LastSuccess is the time that the job last ran without error. It is nil if it never has
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+}
+
+// Results records the Result of each Periodic run by DoPeriodic. It is safe for concurrent use
+type Results struct {
+	rwm     *sync.RWMutex
+	results map[string]Result
+}
+
+// NewResults creates a new, empty Results
+func NewResults() *Results {
+	return &Results{rwm: new(sync.RWMutex), results: make(map[string]Result)}
+}
+
+// Get returns the Result of each Periodic that has run at least once, sorted by name
+func (r *Results) Get() []Result {
+	r.rwm.RLock()
+	defer r.rwm.RUnlock()
+	ret := make([]Result, 0, len(r.results))
+	for _, res := range r.results {
+		ret = append(ret, res)
+	}
+	sort.Sort(resultsByName(ret))
+	return ret
+}
+
+// Record stores the outcome of a single execution of the Periodic named name
+func (r *Results) Record(name string, start time.Time, dur time.Duration, err error) {
+	r.rwm.Lock()
+	defer r.rwm.Unlock()
+	res := Result{
+		Name:            name,
+		LastRun:         start,
+		DurationSeconds: dur.Seconds(),
+		LastSuccess:     r.results[name].LastSuccess,
+	}
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.LastSuccess = &start
+	}
+	r.results[name] = res
+}
+
+// Succeeded returns true if the Periodic named name has run without error at least once since
+// results was created
+func (r *Results) Succeeded(name string) bool {
+	r.rwm.RLock()
+	defer r.rwm.RUnlock()
+	return r.results[name].LastSuccess != nil
+}
+
+type resultsByName []Result
+
+func (r resultsByName) Len() int           { return len(r) }
+func (r resultsByName) Less(i, j int) bool { return r[i].Name < r[j].Name }
+func (r resultsByName) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
 // SendVersions fulfills the Periodic interface
 type sendVersions struct {
 	k8sResources      *k8s.ResourceInterfaceNamespaced
+	installedData     data.InstalledData
 	clusterID         data.ClusterID
-	apiClient         *apiclient.WorkflowManager
+	apiClient         upstream.Client
 	availableVersions data.AvailableVersions
 	frequency         time.Duration
 }
 
 // NewSendVersionsPeriodic creates a new SendVersions using sgc and rcl as the the secret getter / creator and replication controller lister implementations (respectively)
 func NewSendVersionsPeriodic(
-	apiClient *apiclient.WorkflowManager,
+	apiClient upstream.Client,
 	clusterID data.ClusterID,
 	ri *k8s.ResourceInterfaceNamespaced,
+	installedData data.InstalledData,
 	availableVersions data.AvailableVersions,
 	frequency time.Duration,
 ) Periodic {
 	return &sendVersions{
 		k8sResources:      ri,
+		installedData:     installedData,
 		clusterID:         clusterID,
 		apiClient:         apiClient,
 		availableVersions: availableVersions,
@@ -46,9 +121,9 @@ func NewSendVersionsPeriodic(
 }
 
 // Do is the Periodic interface implementation
-func (s sendVersions) Do() error {
+func (s sendVersions) Do(ctx context.Context) error {
 	if config.Spec.CheckVersions {
-		err := sendVersionsImpl(s.apiClient, s.clusterID, s.k8sResources, s.availableVersions)
+		err := sendVersionsImpl(ctx, s.apiClient, s.clusterID, s.k8sResources, s.installedData, s.availableVersions)
 		if err != nil {
 			return err
 		}
@@ -61,21 +136,41 @@ func (s sendVersions) Frequency() time.Duration {
 	return s.frequency
 }
 
+// Name is the Periodic interface implementation
+func (s sendVersions) Name() string {
+	return "sendVersions"
+}
+
+// LatestVersionDataJob is the name of the Periodic returned by NewGetLatestVersionDataPeriodic.
+// Each of its successful runs has refreshed the available versions
+const LatestVersionDataJob = "getLatestVersionData"
+
+// UpdateNotifier is an interface for notifying operators of the components that have an update
+// available
+type UpdateNotifier interface {
+	// NotifyUpdates notifies of the updates available to cluster's components. Implementations
+	// should notify of each update once, and return early, with ctx.Err(), when ctx is done
+	NotifyUpdates(ctx context.Context, cluster models.Cluster) error
+}
+
 type getLatestVersionData struct {
 	vsns                  data.AvailableVersions
 	installedData         data.InstalledData
 	clusterID             data.ClusterID
 	availableComponentVsn data.AvailableComponentVersion
 	k8sResources          k8s.ResourceInterfaceNamespaced
+	notifier              UpdateNotifier
 	frequency             time.Duration
 }
 
-// NewGetLatestVersionDataPeriodic creates a new periodic implementation that gets latest version data. It uses sgc and rcl as the secret getter/creator and replication controller lister implementations (respectively)
+// NewGetLatestVersionDataPeriodic creates a new periodic implementation that gets latest version data. It uses sgc and rcl as the secret getter/creator and replication controller lister implementations (respectively).
+// Once the latest version data is refreshed, notifier is notified of the available updates, unless it's nil
 func NewGetLatestVersionDataPeriodic(
 	installedData data.InstalledData,
 	clusterID data.ClusterID,
 	availVsn data.AvailableVersions,
 	availCompVsn data.AvailableComponentVersion,
+	notifier UpdateNotifier,
 	frequency time.Duration,
 ) Periodic {
 
@@ -84,19 +179,38 @@ func NewGetLatestVersionDataPeriodic(
 		installedData:         installedData,
 		clusterID:             clusterID,
 		availableComponentVsn: availCompVsn,
+		notifier:              notifier,
 		frequency:             frequency,
 	}
 }
 
 // Do is the Periodic interface implementation
-func (u *getLatestVersionData) Do() error {
-	cluster, err := data.GetCluster(u.installedData, u.clusterID, u.availableComponentVsn)
+func (u *getLatestVersionData) Do(ctx context.Context) error {
+	cluster, err := data.GetCluster(ctx, u.installedData, u.clusterID, u.availableComponentVsn)
 	if err != nil {
 		return err
 	}
-	if _, err := u.vsns.Refresh(cluster); err != nil {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
+	if _, err := u.vsns.Refresh(ctx, cluster); err != nil {
+		return err
+	}
+	// failures after the refresh are only logged, so that they don't cause the refresh to be retried
+	log := logger.FromContext(ctx)
+	// updates are determined again, with the versions that were just refreshed
+	cluster, err = data.GetCluster(ctx, u.installedData, u.clusterID, u.availableComponentVsn)
+	if err != nil {
+		log.WithError(err).Warnf("unable to get the available updates")
+		return nil
+	}
+	metrics.ObserveCluster(cluster)
+	if u.notifier == nil {
+		return nil
+	}
+	if err := u.notifier.NotifyUpdates(ctx, cluster); err != nil {
+		log.WithError(err).Warnf("unable to notify of the available updates")
+	}
 	return nil
 }
 
@@ -105,57 +219,147 @@ func (u getLatestVersionData) Frequency() time.Duration {
 	return u.frequency
 }
 
+// Name is the Periodic interface implementation
+func (u getLatestVersionData) Name() string {
+	return LatestVersionDataJob
+}
+
+// Handle controls the goroutines started by DoPeriodic
+type Handle struct {
+	stopCh   chan struct{}
+	stopOnce *sync.Once
+	wg       *sync.WaitGroup
+}
+
+// Stop stops scheduling new runs of all jobs. Runs that are waiting to retry give up, but runs
+// that are in progress aren't interrupted; cancel the context passed to DoPeriodic to do so
+func (h *Handle) Stop() {
+	h.stopOnce.Do(func() { close(h.stopCh) })
+}
+
+// Wait blocks until all jobs have returned after a call to Stop, or after the context passed to
+// DoPeriodic is done
+func (h *Handle) Wait() {
+	h.wg.Wait()
+}
+
+// Drain calls Stop, and then waits for runs in progress to return. It returns ctx.Err() if ctx is
+// done before they do
+func (h *Handle) Drain(ctx context.Context) error {
+	h.Stop()
+	drained := make(chan struct{})
+	go func() {
+		h.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopKey is the context key of the channel that's closed when the Handle running a job is stopped
+type stopKey struct{}
+
+// stopped returns the channel that's closed when the Handle running the job that ctx was passed to
+// is stopped. It returns nil, which never closes, if ctx wasn't passed by DoPeriodic
+func stopped(ctx context.Context) <-chan struct{} {
+	stopCh, _ := ctx.Value(stopKey{}).(chan struct{})
+	return stopCh
+}
+
 // DoPeriodic calls p.Do() once, and then again every p.Frequency() on each element p in pSlice.
-// For each p in pSlice, a new goroutine is started, and the returned channel can be closed
-// to stop all of the goroutines.
-func DoPeriodic(pSlice []Periodic) chan<- struct{} {
-	doneCh := make(chan struct{})
+// For each p in pSlice, a new goroutine is started. All goroutines exit when ctx is done or when
+// the returned Handle is stopped, and ctx is passed to every call to p.Do(), carrying the Handle's
+// stop channel so that jobs wrapped by WithRetry stop retrying when it's stopped. The outcome of each
+// call to p.Do() is recorded in results. The first call to each p.Do() is delayed by a random
+// duration of up to startupJitter, so that many managers started at once don't all call upstream
+// APIs at the same instant
+func DoPeriodic(ctx context.Context, pSlice []Periodic, results *Results, startupJitter time.Duration) *Handle {
+	h := &Handle{
+		stopCh:   make(chan struct{}),
+		stopOnce: new(sync.Once),
+		wg:       new(sync.WaitGroup),
+	}
+	ctx = context.WithValue(ctx, stopKey{}, h.stopCh)
 	for _, p := range pSlice {
+		h.wg.Add(1)
 		go func(p Periodic) {
-			// execute once at the beginning
-			err := p.Do()
-			if err != nil {
-				log.Printf("periodic job ran and returned error (%s)", err)
+			defer h.wg.Done()
+			select {
+			case <-time.After(Jitter(startupJitter, 1)):
+			case <-h.stopCh:
+				return
+			case <-ctx.Done():
+				return
 			}
+			// execute once at the beginning
+			doAndRecord(ctx, p, results)
 			ticker := time.NewTicker(p.Frequency())
+			defer ticker.Stop()
 			for {
 				select {
 				case <-ticker.C:
-					err := p.Do()
-					if err != nil {
-						log.Printf("periodic job ran and returned error (%s)", err)
-					}
-				case <-doneCh:
-					ticker.Stop()
+					doAndRecord(ctx, p, results)
+				case <-h.stopCh:
+					return
+				case <-ctx.Done():
 					return
 				}
 			}
 		}(p)
 	}
-	return doneCh
+	return h
+}
+
+// doAndRecord calls p.Do() and records its outcome in results and in the job metrics. Each run
+// logs with its own correlation ID
+func doAndRecord(ctx context.Context, p Periodic, results *Results) {
+	log := logger.FromContext(ctx).WithFields(logger.Fields{
+		logger.CorrelationIDField: logger.NewCorrelationID(),
+		"job":                     p.Name(),
+	})
+	ctx = logger.NewContext(ctx, log)
+	log.Debugf("starting periodic job")
+	start := time.Now()
+	err := p.Do(ctx)
+	dur := time.Since(start)
+	if err != nil {
+		log.WithError(err).Errorf("periodic job ran and returned error")
+	} else {
+		log.With("duration_ms", dur.Seconds()*1000).Infof("periodic job succeeded")
+	}
+	results.Record(p.Name(), start, dur, err)
+	metrics.ObserveJob(p.Name(), dur, err)
 }
 
-//  sendVersions sends cluster version data
+// sendVersions sends cluster version data
 func sendVersionsImpl(
-	apiClient *apiclient.WorkflowManager,
+	ctx context.Context,
+	apiClient upstream.Client,
 	clusterID data.ClusterID,
 	k8sResources *k8s.ResourceInterfaceNamespaced,
+	installedData data.InstalledData,
 	availableVersions data.AvailableVersions,
 ) error {
+	// errors are logged once, by doAndRecord
 	cluster, err := data.GetCluster(
-		data.NewInstalledDeisData(k8sResources),
+		ctx,
+		installedData,
 		clusterID,
 		data.NewLatestReleasedComponent(k8sResources, availableVersions),
 	)
 	if err != nil {
-		log.Println("error getting installed components data")
+		return fmt.Errorf("getting installed components data (%s)", err)
+	}
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	_, err = apiClient.Operations.CreateClusterDetails(&operations.CreateClusterDetailsParams{Body: &cluster})
-	if err != nil {
-		log.Println("error sending diagnostic data")
-		return err
+	if err := apiClient.SendClusterDetails(ctx, &cluster); err != nil {
+		return fmt.Errorf("sending diagnostic data (%s)", err)
 	}
 	return nil
 }
@@ -1,6 +1,8 @@
 package jobs
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -14,7 +16,7 @@ type testPeriodic struct {
 	freq time.Duration
 }
 
-func (t *testPeriodic) Do() error {
+func (t *testPeriodic) Do(ctx context.Context) error {
 	t.t.Logf("testPeriodic Do at %s", time.Now())
 	t.i++
 	return t.err
@@ -24,15 +26,83 @@ func (t testPeriodic) Frequency() time.Duration {
 	return t.freq
 }
 
+func (t testPeriodic) Name() string {
+	return "testPeriodic"
+}
+
 func TestDoPeriodic(t *testing.T) {
 	interval := time.Duration(3000) * time.Millisecond
 	p := &testPeriodic{t: t, err: nil, freq: interval}
-	closeCh1 := DoPeriodic([]Periodic{p})
+	handle := DoPeriodic(context.Background(), []Periodic{p}, NewResults(), 0)
 	time.Sleep(interval / 2) // wait a little while for the goroutine to call the job once
 	assert.True(t, p.i == 1, "the periodic wasn't called once")
 	time.Sleep(interval)
 	assert.True(t, p.i == 2, "the periodic wasn't called twice")
 	time.Sleep(interval)
 	assert.True(t, p.i == 3, "the periodic wasn't called thrice")
-	close(closeCh1)
+	handle.Stop()
+	handle.Wait()
+	time.Sleep(interval)
+	assert.True(t, p.i == 3, "the periodic was called after it was stopped")
+}
+
+// blockingPeriodic is a Periodic whose Do blocks until its context is done
+type blockingPeriodic struct {
+	started chan struct{}
+}
+
+func (b blockingPeriodic) Do(ctx context.Context) error {
+	close(b.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b blockingPeriodic) Frequency() time.Duration {
+	return time.Hour
+}
+
+func (b blockingPeriodic) Name() string {
+	return "blockingPeriodic"
+}
+
+func TestHandleDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := blockingPeriodic{started: make(chan struct{})}
+	results := NewResults()
+	handle := DoPeriodic(ctx, []Periodic{p}, results, 0)
+	<-p.started
+	// the job is blocked, so draining must time out
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer drainCancel()
+	assert.Equal(t, handle.Drain(drainCtx), context.DeadlineExceeded, "drain error")
+	// cancelling the job context interrupts the run in progress
+	cancel()
+	handle.Wait()
+	res := results.Get()
+	assert.Equal(t, len(res), 1, "number of results")
+	assert.Equal(t, res[0].Error, context.Canceled.Error(), "cancelled job error")
+}
+
+func TestResults(t *testing.T) {
+	results := NewResults()
+	assert.Equal(t, len(results.Get()), 0, "number of results")
+	start := time.Now()
+	results.Record("b", start, time.Second, nil)
+	results.Record("a", start, time.Second, errors.New("test error"))
+	res := results.Get()
+	assert.Equal(t, len(res), 2, "number of results")
+	assert.Equal(t, res[0].Name, "a", "first result name")
+	assert.Equal(t, res[0].Error, "test error", "first result error")
+	assert.True(t, res[0].LastSuccess == nil, "failed job has a last success time")
+	assert.Equal(t, res[1].Name, "b", "second result name")
+	assert.True(t, res[1].LastSuccess != nil, "succeeded job has no last success time")
+	// a failure after a success keeps the last success time
+	results.Record("b", start.Add(time.Minute), time.Second, errors.New("test error"))
+	res = results.Get()
+	assert.Equal(t, res[1].Error, "test error", "second result error")
+	assert.True(t, res[1].LastSuccess != nil && res[1].LastSuccess.Equal(start), "last success time was not kept")
+	assert.True(t, results.Succeeded("b"), "job that succeeded once was not reported as succeeded")
+	assert.False(t, results.Succeeded("a"), "failed job was reported as succeeded")
+	assert.False(t, results.Succeeded("c"), "job that never ran was reported as succeeded")
 }
@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/logger"
+)
+
+var (
+	randMut = new(sync.Mutex)
+	randSrc = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// RetryPolicy describes how a failed run of a Periodic is retried before giving up until its next
+// scheduled run
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times Do is called per scheduled run, including the
+	// first. Values less than 2 disable retries
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the time to wait before any retry. It is ignored if it is zero
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the backoff grows after each retry
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, of each backoff that is randomized
+	Jitter float64
+}
+
+// ConfiguredRetryPolicy returns the RetryPolicy configured in config.Spec. If maxAttempts is greater
+// than zero, it overrides the configured maximum number of attempts
+func ConfiguredRetryPolicy(maxAttempts int) RetryPolicy {
+	if maxAttempts <= 0 {
+		maxAttempts = config.Spec.RetryMaxAttempts
+	}
+	return RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Duration(config.Spec.RetryInitialBackoff) * time.Second,
+		MaxBackoff:     time.Duration(config.Spec.RetryMaxBackoff) * time.Second,
+		Multiplier:     2,
+		Jitter:         config.Spec.RetryJitter,
+	}
+}
+
+// Backoff returns the time to wait before the given retry, where 1 is the first retry
+func (r RetryPolicy) Backoff(retry int) time.Duration {
+	mult := r.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(r.InitialBackoff) * math.Pow(mult, float64(retry-1))
+	if r.MaxBackoff > 0 && d > float64(r.MaxBackoff) {
+		d = float64(r.MaxBackoff)
+	}
+	return Jitter(time.Duration(d), r.Jitter)
+}
+
+// Jitter returns a random duration between (1-fraction)*d and d. fraction is clamped to [0, 1]
+func Jitter(d time.Duration, fraction float64) time.Duration {
+	if d <= 0 || fraction <= 0 {
+		return d
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	delta := float64(d) * fraction
+	return time.Duration(float64(d) - delta + randFloat64()*delta)
+}
+
+// retryingPeriodic is a Periodic that retries the Periodic it wraps according to a RetryPolicy
+type retryingPeriodic struct {
+	Periodic
+	policy RetryPolicy
+}
+
+// WithRetry returns a Periodic that calls p.Do() until it succeeds, policy.MaxAttempts calls have
+// been made, or the context passed to Do is done. Successive calls are separated by the backoff
+// described by policy. If the Handle running it is stopped during a backoff, the last error is
+// returned without retrying, so that draining the Handle doesn't wait for the backoff
+func WithRetry(p Periodic, policy RetryPolicy) Periodic {
+	return &retryingPeriodic{Periodic: p, policy: policy}
+}
+
+// Do is the Periodic interface implementation
+func (r *retryingPeriodic) Do(ctx context.Context) error {
+	for attempt := 1; ; attempt++ {
+		err := r.Periodic.Do(ctx)
+		if err == nil || attempt >= r.policy.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		wait := r.policy.Backoff(attempt)
+		logger.FromContext(ctx).WithError(err).Warnf(
+			"periodic job failed on attempt %d of %d, retrying in %s",
+			attempt,
+			r.policy.MaxAttempts,
+			wait,
+		)
+		select {
+		case <-time.After(wait):
+		case <-stopped(ctx):
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func randFloat64() float64 {
+	randMut.Lock()
+	defer randMut.Unlock()
+	return randSrc.Float64()
+}
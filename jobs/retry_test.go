@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+)
+
+// failingPeriodic is a Periodic that fails until it has been called succeedAfter times
+type failingPeriodic struct {
+	calls        int
+	succeedAfter int
+}
+
+func (f *failingPeriodic) Do(ctx context.Context) error {
+	f.calls++
+	if f.calls < f.succeedAfter {
+		return errors.New("test failure")
+	}
+	return nil
+}
+
+func (f *failingPeriodic) Frequency() time.Duration {
+	return time.Hour
+}
+
+func (f *failingPeriodic) Name() string {
+	return "failingPeriodic"
+}
+
+// Creating a novel mock struct that fulfills the Periodic interface, which always fails and signals
+// its first call on called
+type alwaysFailingPeriodic struct {
+	called chan struct{}
+	once   sync.Once
+}
+
+func (a *alwaysFailingPeriodic) Do(ctx context.Context) error {
+	a.once.Do(func() { close(a.called) })
+	return errors.New("test failure")
+}
+
+func (a *alwaysFailingPeriodic) Frequency() time.Duration {
+	return time.Hour
+}
+
+func (a *alwaysFailingPeriodic) Name() string {
+	return "alwaysFailingPeriodic"
+}
+
+func TestWithRetryDrain(t *testing.T) {
+	p := &alwaysFailingPeriodic{called: make(chan struct{})}
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}
+	results := NewResults()
+	handle := DoPeriodic(context.Background(), []Periodic{WithRetry(p, policy)}, results, 0)
+	<-p.called
+	// the job is backing off after its first attempt, and must give up as soon as it's drained
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer drainCancel()
+	start := time.Now()
+	assert.NoErr(t, handle.Drain(drainCtx))
+	assert.True(t, time.Since(start) < time.Second, "draining took %s", time.Since(start))
+	res := results.Get()
+	assert.Equal(t, len(res), 1, "number of results")
+	assert.Equal(t, res[0].Error, "test failure", "job error")
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+	assert.Equal(t, policy.Backoff(1), time.Second, "first backoff")
+	assert.Equal(t, policy.Backoff(2), 2*time.Second, "second backoff")
+	assert.Equal(t, policy.Backoff(3), 4*time.Second, "third backoff")
+	assert.Equal(t, policy.Backoff(4), 5*time.Second, "capped backoff")
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		b := policy.Backoff(2)
+		assert.True(t, b >= time.Second && b <= 2*time.Second, "jittered backoff %s out of range", b)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	assert.Equal(t, Jitter(time.Second, 0), time.Second, "unjittered duration")
+	assert.Equal(t, Jitter(0, 1), time.Duration(0), "jittered zero duration")
+	for i := 0; i < 100; i++ {
+		j := Jitter(time.Second, 2)
+		assert.True(t, j >= 0 && j <= time.Second, "jittered duration %s out of range", j)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+	p := &failingPeriodic{succeedAfter: 3}
+	assert.NoErr(t, WithRetry(p, policy).Do(context.Background()))
+	assert.Equal(t, p.calls, 3, "number of calls")
+
+	p = &failingPeriodic{succeedAfter: 4}
+	err := WithRetry(p, policy).Do(context.Background())
+	assert.True(t, err != nil, "expected an error after the last attempt")
+	assert.Equal(t, p.calls, 3, "number of calls")
+
+	// no retries after the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p = &failingPeriodic{succeedAfter: 4}
+	err = WithRetry(p, policy).Do(ctx)
+	assert.True(t, err != nil, "expected an error")
+	assert.Equal(t, p.calls, 1, "number of calls")
+
+	retrying := WithRetry(p, policy)
+	assert.Equal(t, retrying.Name(), p.Name(), "retrying periodic name")
+	assert.Equal(t, retrying.Frequency(), p.Frequency(), "retrying periodic frequency")
+}
@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/labels"
+)
+
+// IgnoreAnnotation is the annotation that opts a resource out of being reported as a component,
+// when set to "true"
+const IgnoreAnnotation = "component.deis.io/ignore"
+
+// ComponentFilter decides which of the workloads in a namespace are Workflow components.
+// The zero value matches every workload that isn't annotated with IgnoreAnnotation
+type ComponentFilter struct {
+	// Include selects the workloads that may be components. A nil Include selects everything
+	Include labels.Selector
+	// Exclude selects the workloads that are never components. A nil Exclude selects nothing
+	Exclude labels.Selector
+}
+
+// NewComponentFilter returns a ComponentFilter from the given include and exclude label selectors,
+// e.g. "heritage=deis" and "app in (my-app, my-other-app)". Empty selectors are ignored
+func NewComponentFilter(include, exclude string) (ComponentFilter, error) {
+	var f ComponentFilter
+	if include != "" {
+		sel, err := labels.Parse(include)
+		if err != nil {
+			return ComponentFilter{}, err
+		}
+		f.Include = sel
+	}
+	if exclude != "" {
+		sel, err := labels.Parse(exclude)
+		if err != nil {
+			return ComponentFilter{}, err
+		}
+		f.Exclude = sel
+	}
+	return f, nil
+}
+
+// Matches returns true if the resource described by meta is a component
+func (f ComponentFilter) Matches(meta api.ObjectMeta) bool {
+	if meta.Annotations[IgnoreAnnotation] == "true" {
+		return false
+	}
+	set := labels.Set(meta.Labels)
+	if f.Include != nil && !f.Include.Matches(set) {
+		return false
+	}
+	if f.Exclude != nil && f.Exclude.Matches(set) {
+		return false
+	}
+	return true
+}
+
+func (f ComponentFilter) daemonSets(in []extensions.DaemonSet) []extensions.DaemonSet {
+	ret := make([]extensions.DaemonSet, 0, len(in))
+	for _, ds := range in {
+		if f.Matches(ds.ObjectMeta) {
+			ret = append(ret, ds)
+		}
+	}
+	return ret
+}
+
+func (f ComponentFilter) deployments(in []extensions.Deployment) []extensions.Deployment {
+	ret := make([]extensions.Deployment, 0, len(in))
+	for _, d := range in {
+		if f.Matches(d.ObjectMeta) {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+func (f ComponentFilter) jobs(in []extensions.Job) []extensions.Job {
+	ret := make([]extensions.Job, 0, len(in))
+	for _, job := range in {
+		if f.Matches(job.ObjectMeta) {
+			ret = append(ret, job)
+		}
+	}
+	return ret
+}
+
+func (f ComponentFilter) replicaSets(in []extensions.ReplicaSet) []extensions.ReplicaSet {
+	ret := make([]extensions.ReplicaSet, 0, len(in))
+	for _, rs := range in {
+		if f.Matches(rs.ObjectMeta) {
+			ret = append(ret, rs)
+		}
+	}
+	return ret
+}
+
+func (f ComponentFilter) replicationControllers(in []api.ReplicationController) []api.ReplicationController {
+	ret := make([]api.ReplicationController, 0, len(in))
+	for _, rc := range in {
+		if f.Matches(rc.ObjectMeta) {
+			ret = append(ret, rc)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,50 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+)
+
+func TestComponentFilterMatches(t *testing.T) {
+	f, err := NewComponentFilter("heritage=deis", "app in (deis-monitor)")
+	assert.NoErr(t, err)
+	for _, tc := range []struct {
+		meta    api.ObjectMeta
+		matches bool
+	}{
+		{api.ObjectMeta{Labels: map[string]string{"heritage": "deis", "app": "deis-controller"}}, true},
+		{api.ObjectMeta{Labels: map[string]string{"app": "deis-controller"}}, false},
+		{api.ObjectMeta{Labels: map[string]string{"heritage": "deis", "app": "deis-monitor"}}, false},
+		{api.ObjectMeta{
+			Labels:      map[string]string{"heritage": "deis", "app": "deis-router"},
+			Annotations: map[string]string{IgnoreAnnotation: "true"},
+		}, false},
+	} {
+		assert.Equal(t, f.Matches(tc.meta), tc.matches, "match of "+tc.meta.Labels["app"])
+	}
+	// the zero value only honors the ignore annotation
+	assert.True(t, ComponentFilter{}.Matches(api.ObjectMeta{}), "zero value filter didn't match")
+	_, err = NewComponentFilter("heritage in deis", "")
+	assert.True(t, err != nil, "expected an error for an invalid selector")
+}
+
+func TestInventoryComponentFilter(t *testing.T) {
+	inv := newTestInventory()
+	assert.NoErr(t, inv.deployments.Add(&extensions.Deployment{
+		ObjectMeta: api.ObjectMeta{Name: "deis-controller", Namespace: namespace},
+	}))
+	assert.NoErr(t, inv.deployments.Add(&extensions.Deployment{
+		ObjectMeta: api.ObjectMeta{
+			Name:        "my-app",
+			Namespace:   namespace,
+			Annotations: map[string]string{IgnoreAnnotation: "true"},
+		},
+	}))
+	deployments, err := inv.Deployments()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(deployments), 1, "number of deployments")
+	assert.Equal(t, deployments[0].Name, "deis-controller", "deployment name")
+}
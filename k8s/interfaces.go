@@ -1,24 +1,39 @@
 package k8s
 
-import kcl "k8s.io/kubernetes/pkg/client/unversioned"
+import (
+	"k8s.io/kubernetes/pkg/api"
+	kcl "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/labels"
+)
 
 // ResourceInterface is an interface for k8s resources
 type ResourceInterface interface {
 	kcl.DaemonSetsNamespacer
 	kcl.DeploymentsNamespacer
 	kcl.EventNamespacer
+	kcl.NamespacesInterface
 	kcl.NodesInterface
 	kcl.PodsNamespacer
 	kcl.ReplicaSetsNamespacer
 	kcl.ReplicationControllersNamespacer
 	kcl.SecretsNamespacer
 	kcl.ServicesNamespacer
+	// Batch returns the client for the batch API group, which serves Jobs
+	Batch() kcl.BatchInterface
 }
 
-// ResourceInterfaceNamespaced is a "union" of ResourceInterface+namespace
+// Pinger is an interface for checking that the Kubernetes API is reachable
+type Pinger interface {
+	// Ping returns a non-nil error if the Kubernetes API could not be reached
+	Ping() error
+}
+
+// ResourceInterfaceNamespaced is a "union" of ResourceInterface+namespace, and of the filter that
+// decides which of the namespace's workloads are components
 type ResourceInterfaceNamespaced struct {
 	ri        ResourceInterface
 	namespace string
+	filter    ComponentFilter
 }
 
 // NewResourceInterfaceNamespaced constructs an instance of ResourceInterfaceNamespaced
@@ -26,6 +41,19 @@ func NewResourceInterfaceNamespaced(ri ResourceInterface, ns string) *ResourceIn
 	return &ResourceInterfaceNamespaced{ri: ri, namespace: ns}
 }
 
+// WithComponentFilter returns a copy of r whose component listers, inventories and running k8s
+// data only report the workloads that filter matches
+func (r *ResourceInterfaceNamespaced) WithComponentFilter(filter ComponentFilter) *ResourceInterfaceNamespaced {
+	r2 := *r
+	r2.filter = filter
+	return &r2
+}
+
+// Namespace returns the namespace that r is bound to
+func (r *ResourceInterfaceNamespaced) Namespace() string {
+	return r.namespace
+}
+
 // DaemonSets implementation
 func (r *ResourceInterfaceNamespaced) DaemonSets() kcl.DaemonSetInterface {
 	return r.ri.DaemonSets(r.namespace)
@@ -41,6 +69,11 @@ func (r *ResourceInterfaceNamespaced) Events() kcl.EventInterface {
 	return r.ri.Events(r.namespace)
 }
 
+// Jobs implementation
+func (r *ResourceInterfaceNamespaced) Jobs() kcl.JobInterface {
+	return r.ri.Batch().Jobs(r.namespace)
+}
+
 // Nodes implementation
 func (r *ResourceInterfaceNamespaced) Nodes() kcl.NodeInterface {
 	return r.ri.Nodes()
@@ -51,6 +84,12 @@ func (r *ResourceInterfaceNamespaced) Pods() kcl.PodInterface {
 	return r.ri.Pods(r.namespace)
 }
 
+// AllPods returns the pods of every namespace, which summaries of cluster scoped resources such as
+// nodes are computed from
+func (r *ResourceInterfaceNamespaced) AllPods() kcl.PodInterface {
+	return r.ri.Pods(api.NamespaceAll)
+}
+
 // ReplicaSets implementation
 func (r *ResourceInterfaceNamespaced) ReplicaSets() kcl.ReplicaSetInterface {
 	return r.ri.ReplicaSets(r.namespace)
@@ -70,3 +109,10 @@ func (r *ResourceInterfaceNamespaced) Services() kcl.ServiceInterface {
 func (r *ResourceInterfaceNamespaced) Secrets() kcl.SecretsInterface {
 	return r.ri.Secrets(r.namespace)
 }
+
+// Ping is the Pinger interface implementation. It lists the services in the namespace, which is
+// cheap and permitted for the workflow manager's service account
+func (r *ResourceInterfaceNamespaced) Ping() error {
+	_, err := r.Services().List(api.ListOptions{LabelSelector: labels.Everything()})
+	return err
+}
@@ -0,0 +1,430 @@
+package k8s
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-manager/logger"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/client/cache"
+	"k8s.io/kubernetes/pkg/controller/framework"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/watch"
+)
+
+// ErrInventoryNotSynced is returned by an Inventory that hasn't completed its initial list of its
+// namespace
+var ErrInventoryNotSynced = errors.New("component inventory has not synced yet")
+
+// ComponentLister is an interface for listing the k8s resources that make up Deis components. The
+// workloads it lists are filtered by a ComponentFilter, but all pods are listed
+type ComponentLister interface {
+	// Deployments returns the Deployments in the lister's namespace
+	Deployments() ([]extensions.Deployment, error)
+	// DaemonSets returns the DaemonSets in the lister's namespace
+	DaemonSets() ([]extensions.DaemonSet, error)
+	// ReplicaSets returns the ReplicaSets in the lister's namespace, including those managed by
+	// Deployments
+	ReplicaSets() ([]extensions.ReplicaSet, error)
+	// ReplicationControllers returns the ReplicationControllers in the lister's namespace
+	ReplicationControllers() ([]api.ReplicationController, error)
+	// Jobs returns the Jobs in the lister's namespace
+	Jobs() ([]extensions.Job, error)
+	// Pods returns the Pods in the lister's namespace
+	Pods() ([]api.Pod, error)
+}
+
+// componentLister fulfills the ComponentLister interface by listing resources from the k8s API
+type componentLister struct {
+	r *ResourceInterfaceNamespaced
+}
+
+// NewComponentLister returns a ComponentLister that lists resources from the k8s API on every call
+func NewComponentLister(r *ResourceInterfaceNamespaced) ComponentLister {
+	return &componentLister{r: r}
+}
+
+// Deployments is the ComponentLister interface implementation
+func (c *componentLister) Deployments() ([]extensions.Deployment, error) {
+	ds, err := GetDeployments(c.r.Deployments())
+	if err != nil {
+		return nil, err
+	}
+	return c.r.filter.deployments(ds), nil
+}
+
+// DaemonSets is the ComponentLister interface implementation
+func (c *componentLister) DaemonSets() ([]extensions.DaemonSet, error) {
+	ds, err := GetDaemonSets(c.r.DaemonSets())
+	if err != nil {
+		return nil, err
+	}
+	return c.r.filter.daemonSets(ds), nil
+}
+
+// ReplicaSets is the ComponentLister interface implementation
+func (c *componentLister) ReplicaSets() ([]extensions.ReplicaSet, error) {
+	rs, err := getReplicaSets(c.r.ReplicaSets())
+	if err != nil {
+		return nil, err
+	}
+	return c.r.filter.replicaSets(rs), nil
+}
+
+// ReplicationControllers is the ComponentLister interface implementation
+func (c *componentLister) ReplicationControllers() ([]api.ReplicationController, error) {
+	rcs, err := GetReplicationControllers(c.r.ReplicationControllers())
+	if err != nil {
+		return nil, err
+	}
+	return c.r.filter.replicationControllers(rcs), nil
+}
+
+// Jobs is the ComponentLister interface implementation
+func (c *componentLister) Jobs() ([]extensions.Job, error) {
+	jobs, err := getJobs(c.r.Jobs())
+	if err != nil {
+		return nil, err
+	}
+	return c.r.filter.jobs(jobs), nil
+}
+
+// Pods is the ComponentLister interface implementation
+func (c *componentLister) Pods() ([]api.Pod, error) {
+	return getPods(c.r.Pods())
+}
+
+// InventoryEventType is the type of change described by an InventoryEvent
+type InventoryEventType string
+
+const (
+	// InventoryAdded is the InventoryEventType of a resource that was created
+	InventoryAdded InventoryEventType = "added"
+	// InventoryUpdated is the InventoryEventType of a resource that was changed
+	InventoryUpdated InventoryEventType = "updated"
+	// InventoryDeleted is the InventoryEventType of a resource that was deleted
+	InventoryDeleted InventoryEventType = "deleted"
+)
+
+// InventoryEvent describes a change to a resource in an Inventory
+type InventoryEvent struct {
+	Type InventoryEventType
+	// Kind is the kind of the resource, e.g. "Deployment"
+	Kind string
+	Name string
+}
+
+// inventoryEventBuffer is the number of events buffered for each subscriber. Events are dropped
+// for subscribers that fall further behind, so that a slow subscriber can't stall the informers
+const inventoryEventBuffer = 64
+
+// Inventory fulfills the ComponentLister interface with an in-memory view of a namespace, kept up
+// to date by watching the k8s API. Listing resources from an Inventory never calls the k8s
+// API
+type Inventory struct {
+	deployments cache.Store
+	daemonSets  cache.Store
+	replicaSets cache.Store
+	rcs         cache.Store
+	jobs        cache.Store
+	pods        cache.Store
+	controllers []*framework.Controller
+	filter      ComponentFilter
+	namespace   string
+	log         *logger.Logger
+
+	subsMut *sync.Mutex
+	subs    map[chan InventoryEvent]struct{}
+}
+
+// NewInventory returns a new Inventory of the resources in r's namespace, which logs to log. Every
+// resyncPeriod, all resources are re-delivered as InventoryUpdated events. It isn't populated until
+// Run is called
+func NewInventory(log *logger.Logger, r *ResourceInterfaceNamespaced, resyncPeriod time.Duration) *Inventory {
+	inv := &Inventory{
+		filter:    r.filter,
+		namespace: r.namespace,
+		log:       log.With("namespace", r.namespace),
+		subsMut:   new(sync.Mutex),
+		subs:      make(map[chan InventoryEvent]struct{}),
+	}
+	var deployCtl, dsCtl, rsCtl, rcCtl, jobCtl, podCtl *framework.Controller
+	inv.deployments, deployCtl = framework.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				return r.Deployments().List(options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				return r.Deployments().Watch(options)
+			},
+		},
+		&extensions.Deployment{},
+		resyncPeriod,
+		inv.eventHandler("Deployment"),
+	)
+	inv.daemonSets, dsCtl = framework.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				return r.DaemonSets().List(options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				return r.DaemonSets().Watch(options)
+			},
+		},
+		&extensions.DaemonSet{},
+		resyncPeriod,
+		inv.eventHandler("DaemonSet"),
+	)
+	inv.replicaSets, rsCtl = framework.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				return r.ReplicaSets().List(options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				return r.ReplicaSets().Watch(options)
+			},
+		},
+		&extensions.ReplicaSet{},
+		resyncPeriod,
+		inv.eventHandler("ReplicaSet"),
+	)
+	inv.rcs, rcCtl = framework.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				return r.ReplicationControllers().List(options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				return r.ReplicationControllers().Watch(options)
+			},
+		},
+		&api.ReplicationController{},
+		resyncPeriod,
+		inv.eventHandler("ReplicationController"),
+	)
+	inv.jobs, jobCtl = framework.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				return r.Jobs().List(options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				return r.Jobs().Watch(options)
+			},
+		},
+		&extensions.Job{},
+		resyncPeriod,
+		inv.eventHandler("Job"),
+	)
+	inv.pods, podCtl = framework.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+				return r.Pods().List(options)
+			},
+			WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+				return r.Pods().Watch(options)
+			},
+		},
+		&api.Pod{},
+		resyncPeriod,
+		inv.eventHandler("Pod"),
+	)
+	inv.controllers = []*framework.Controller{deployCtl, dsCtl, rsCtl, rcCtl, jobCtl, podCtl}
+	return inv
+}
+
+// Run starts watching the k8s API, and keeps the Inventory up to date until stopCh is closed. It
+// doesn't block
+func (i *Inventory) Run(stopCh <-chan struct{}) {
+	for _, ctl := range i.controllers {
+		go ctl.Run(stopCh)
+	}
+}
+
+// HasSynced returns true once the initial list of every resource kind has been stored
+func (i *Inventory) HasSynced() bool {
+	for _, ctl := range i.controllers {
+		if !ctl.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// WaitForSync blocks until HasSynced returns true, or until timeout has elapsed. It returns
+// ErrInventoryNotSynced in the latter case
+func (i *Inventory) WaitForSync(timeout time.Duration) error {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	for !i.HasSynced() {
+		if time.Now().After(deadline) {
+			return ErrInventoryNotSynced
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	i.log.Debugf("Component inventory synced in %s", time.Since(start))
+	return nil
+}
+
+// Deployments is the ComponentLister interface implementation. Returns ErrInventoryNotSynced
+// until the initial list has completed
+func (i *Inventory) Deployments() ([]extensions.Deployment, error) {
+	if !i.HasSynced() {
+		return nil, ErrInventoryNotSynced
+	}
+	objs := sortedByName(i.deployments.List())
+	ret := make([]extensions.Deployment, 0, len(objs))
+	for _, obj := range objs {
+		ret = append(ret, *obj.(*extensions.Deployment))
+	}
+	return i.filter.deployments(ret), nil
+}
+
+// DaemonSets is the ComponentLister interface implementation. Returns ErrInventoryNotSynced until
+// the initial list has completed
+func (i *Inventory) DaemonSets() ([]extensions.DaemonSet, error) {
+	if !i.HasSynced() {
+		return nil, ErrInventoryNotSynced
+	}
+	objs := sortedByName(i.daemonSets.List())
+	ret := make([]extensions.DaemonSet, 0, len(objs))
+	for _, obj := range objs {
+		ret = append(ret, *obj.(*extensions.DaemonSet))
+	}
+	return i.filter.daemonSets(ret), nil
+}
+
+// ReplicaSets is the ComponentLister interface implementation. Returns ErrInventoryNotSynced until
+// the initial list has completed
+func (i *Inventory) ReplicaSets() ([]extensions.ReplicaSet, error) {
+	if !i.HasSynced() {
+		return nil, ErrInventoryNotSynced
+	}
+	objs := sortedByName(i.replicaSets.List())
+	ret := make([]extensions.ReplicaSet, 0, len(objs))
+	for _, obj := range objs {
+		ret = append(ret, *obj.(*extensions.ReplicaSet))
+	}
+	return i.filter.replicaSets(ret), nil
+}
+
+// ReplicationControllers is the ComponentLister interface implementation. Returns
+// ErrInventoryNotSynced until the initial list has completed
+func (i *Inventory) ReplicationControllers() ([]api.ReplicationController, error) {
+	if !i.HasSynced() {
+		return nil, ErrInventoryNotSynced
+	}
+	objs := sortedByName(i.rcs.List())
+	ret := make([]api.ReplicationController, 0, len(objs))
+	for _, obj := range objs {
+		ret = append(ret, *obj.(*api.ReplicationController))
+	}
+	return i.filter.replicationControllers(ret), nil
+}
+
+// Jobs is the ComponentLister interface implementation. Returns ErrInventoryNotSynced until the
+// initial list has completed
+func (i *Inventory) Jobs() ([]extensions.Job, error) {
+	if !i.HasSynced() {
+		return nil, ErrInventoryNotSynced
+	}
+	objs := sortedByName(i.jobs.List())
+	ret := make([]extensions.Job, 0, len(objs))
+	for _, obj := range objs {
+		ret = append(ret, *obj.(*extensions.Job))
+	}
+	return i.filter.jobs(ret), nil
+}
+
+// Pods is the ComponentLister interface implementation. Returns ErrInventoryNotSynced until the
+// initial list has completed
+func (i *Inventory) Pods() ([]api.Pod, error) {
+	if !i.HasSynced() {
+		return nil, ErrInventoryNotSynced
+	}
+	objs := sortedByName(i.pods.List())
+	ret := make([]api.Pod, 0, len(objs))
+	for _, obj := range objs {
+		ret = append(ret, *obj.(*api.Pod))
+	}
+	return ret, nil
+}
+
+// Subscribe returns a channel that receives an InventoryEvent for every change to the Inventory,
+// until stopCh is closed. The subscription is then removed and the channel closed. Events are
+// dropped if the channel's buffer is full
+func (i *Inventory) Subscribe(stopCh <-chan struct{}) <-chan InventoryEvent {
+	ch := make(chan InventoryEvent, inventoryEventBuffer)
+	i.subsMut.Lock()
+	i.subs[ch] = struct{}{}
+	i.subsMut.Unlock()
+	go func() {
+		<-stopCh
+		i.subsMut.Lock()
+		defer i.subsMut.Unlock()
+		delete(i.subs, ch)
+		close(ch)
+	}()
+	return ch
+}
+
+// publish sends evt to every subscriber that has room for it
+func (i *Inventory) publish(evt InventoryEvent) {
+	i.subsMut.Lock()
+	defer i.subsMut.Unlock()
+	for ch := range i.subs {
+		select {
+		case ch <- evt:
+		default:
+			i.log.WithFields(logger.Fields{
+				"kind": evt.Kind,
+				"name": evt.Name,
+			}).Debugf("dropped %s inventory event for a slow subscriber", evt.Type)
+		}
+	}
+}
+
+// eventHandler returns a framework.ResourceEventHandler that publishes events for resources of the
+// given kind
+func (i *Inventory) eventHandler(kind string) framework.ResourceEventHandler {
+	return framework.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			i.publish(InventoryEvent{Type: InventoryAdded, Kind: kind, Name: objectName(obj)})
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			i.publish(InventoryEvent{Type: InventoryUpdated, Kind: kind, Name: objectName(newObj)})
+		},
+		DeleteFunc: func(obj interface{}) {
+			i.publish(InventoryEvent{Type: InventoryDeleted, Kind: kind, Name: objectName(obj)})
+		},
+	}
+}
+
+// objectName returns the name of a resource delivered to a framework.ResourceEventHandler
+func objectName(obj interface{}) string {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	rObj, ok := obj.(runtime.Object)
+	if !ok {
+		return ""
+	}
+	meta, err := api.ObjectMetaFor(rObj)
+	if err != nil {
+		return ""
+	}
+	return meta.Name
+}
+
+// sortedByName sorts the given resources by name, so that listing an Inventory is deterministic
+func sortedByName(objs []interface{}) []interface{} {
+	sort.Sort(byName(objs))
+	return objs
+}
+
+type byName []interface{}
+
+func (b byName) Len() int           { return len(b) }
+func (b byName) Less(i, j int) bool { return objectName(b[i]) < objectName(b[j]) }
+func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
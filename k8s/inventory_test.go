@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/logger"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/client/cache"
+)
+
+// newTestInventory returns an Inventory backed by empty stores and no controllers, so that it's
+// considered synced
+func newTestInventory() *Inventory {
+	return &Inventory{
+		deployments: cache.NewStore(cache.MetaNamespaceKeyFunc),
+		daemonSets:  cache.NewStore(cache.MetaNamespaceKeyFunc),
+		replicaSets: cache.NewStore(cache.MetaNamespaceKeyFunc),
+		rcs:         cache.NewStore(cache.MetaNamespaceKeyFunc),
+		jobs:        cache.NewStore(cache.MetaNamespaceKeyFunc),
+		pods:        cache.NewStore(cache.MetaNamespaceKeyFunc),
+		log:         logger.Default(),
+		subsMut:     new(sync.Mutex),
+		subs:        make(map[chan InventoryEvent]struct{}),
+	}
+}
+
+func TestInventoryLists(t *testing.T) {
+	inv := newTestInventory()
+	for _, name := range []string{"deis-router", "deis-builder", "deis-controller"} {
+		assert.NoErr(t, inv.deployments.Add(&extensions.Deployment{
+			ObjectMeta: api.ObjectMeta{Name: name, Namespace: namespace},
+		}))
+	}
+	assert.NoErr(t, inv.daemonSets.Add(&extensions.DaemonSet{
+		ObjectMeta: api.ObjectMeta{Name: "deis-logger-fluentd", Namespace: namespace},
+	}))
+	deployments, err := inv.Deployments()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(deployments), 3, "number of deployments")
+	assert.Equal(t, deployments[0].Name, "deis-builder", "first deployment name")
+	assert.Equal(t, deployments[2].Name, "deis-router", "last deployment name")
+	daemonSets, err := inv.DaemonSets()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(daemonSets), 1, "number of daemon sets")
+	rcs, err := inv.ReplicationControllers()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(rcs), 0, "number of replication controllers")
+	assert.NoErr(t, inv.jobs.Add(&extensions.Job{
+		ObjectMeta: api.ObjectMeta{Name: "deis-database-backup", Namespace: namespace},
+	}))
+	jobs, err := inv.Jobs()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(jobs), 1, "number of jobs")
+	replicaSets, err := inv.ReplicaSets()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(replicaSets), 0, "number of replica sets")
+}
+
+func TestInventorySubscribe(t *testing.T) {
+	inv := newTestInventory()
+	stopCh := make(chan struct{})
+	events := inv.Subscribe(stopCh)
+	handler := inv.eventHandler("Deployment")
+	dep := &extensions.Deployment{ObjectMeta: api.ObjectMeta{Name: "deis-controller", Namespace: namespace}}
+	handler.OnAdd(dep)
+	handler.OnUpdate(dep, dep)
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "deis/deis-controller", Obj: dep})
+	for _, typ := range []InventoryEventType{InventoryAdded, InventoryUpdated, InventoryDeleted} {
+		evt := <-events
+		assert.Equal(t, evt, InventoryEvent{Type: typ, Kind: "Deployment", Name: "deis-controller"}, "inventory event")
+	}
+	close(stopCh)
+	_, open := <-events
+	assert.False(t, open, "events channel was open after stopping the subscription")
+	// publishing without subscribers must not block
+	handler.OnAdd(dep)
+}
@@ -1,6 +1,8 @@
 package k8s
 
 import (
+	"io"
+
 	"github.com/deis/kubeapp/api/daemonset"
 	"github.com/deis/kubeapp/api/deployment"
 	"github.com/deis/kubeapp/api/event"
@@ -9,22 +11,31 @@ import (
 	"github.com/deis/kubeapp/api/rc"
 	"github.com/deis/kubeapp/api/replicaset"
 	"github.com/deis/kubeapp/api/service"
+	"github.com/deis/workflow-manager/logger"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/apis/extensions"
+	kcl "k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/labels"
 )
 
 // RunningK8sData is an interface for representing installed K8s data RESTFully
 type RunningK8sData interface {
+	// Namespace returns the namespace that the data is collected from. Nodes aren't namespaced
+	Namespace() string
 	// get DaemonSet model data for RESTful consumption
 	DaemonSets() ([]*models.K8sResource, error)
 	// get Deployment model data for RESTful consumption
 	Deployments() ([]*models.K8sResource, error)
 	// get Event model data for RESTful consumption
 	Events() ([]*models.K8sResource, error)
+	// get the logs of the namespace's containers for RESTful consumption. Failures to read a log
+	// are reported to log
+	Logs(log *logger.Logger, opts PodLogOptions) ([]*models.ContainerLog, error)
 	// get Node model data for RESTful consumption
 	Nodes() ([]*models.K8sResource, error)
+	// get a health and capacity summary of each node for RESTful consumption
+	NodeSummaries() ([]*models.NodeSummary, error)
 	// get Pod model data for RESTful consumption
 	Pods() ([]*models.K8sResource, error)
 	// get ReplicaSet model data for RESTful consumption
@@ -35,13 +46,17 @@ type RunningK8sData interface {
 	Services() ([]*models.K8sResource, error)
 }
 
-// runningK8sData fulfills the RunningK8sData interface
+// runningK8sData fulfills the RunningK8sData interface. Its workloads are filtered by filter
 type runningK8sData struct {
+	namespace        string
+	filter           ComponentFilter
 	daemonSetLister  daemonset.Lister
 	deploymentLister deployment.Lister
 	eventLister      event.Lister
 	nodeLister       node.Lister
 	podLister        pod.Lister
+	allPodLister     pod.Lister
+	podLogs          podLogStreamer
 	rcLister         rc.Lister
 	replicaSetLister replicaset.Lister
 	serviceLister    service.Lister
@@ -50,23 +65,35 @@ type runningK8sData struct {
 // NewRunningK8sData returns a new runningK8sData using rcl as the rc.Lister implementation
 func NewRunningK8sData(r *ResourceInterfaceNamespaced) RunningK8sData {
 	return &runningK8sData{
+		namespace:        r.namespace,
+		filter:           r.filter,
 		daemonSetLister:  r.DaemonSets(),
 		deploymentLister: r.Deployments(),
 		eventLister:      r.Events(),
 		nodeLister:       r.Nodes(),
 		podLister:        r.Pods(),
+		allPodLister:     r.AllPods(),
+		podLogs: func(name string, opts *api.PodLogOptions) (io.ReadCloser, error) {
+			return r.Pods().GetLogs(name, opts).Stream()
+		},
 		rcLister:         r.ReplicationControllers(),
 		replicaSetLister: r.ReplicaSets(),
 		serviceLister:    r.Services(),
 	}
 }
 
+// Namespace method for runningK8sData
+func (rkd *runningK8sData) Namespace() string {
+	return rkd.namespace
+}
+
 // DaemonSets method for runningK8sData
 func (rkd *runningK8sData) DaemonSets() ([]*models.K8sResource, error) {
 	ds, err := GetDaemonSets(rkd.daemonSetLister)
 	if err != nil {
 		return nil, err
 	}
+	ds = rkd.filter.daemonSets(ds)
 	ret := make([]*models.K8sResource, len(ds))
 	for i, d := range ds {
 		d2 := d // grab a value copy of "d" to enforce block scope heap reference, and avoid shadowing non-block scope "d"
@@ -84,6 +111,7 @@ func (rkd *runningK8sData) Deployments() ([]*models.K8sResource, error) {
 	if err != nil {
 		return nil, err
 	}
+	ds = rkd.filter.deployments(ds)
 	ret := make([]*models.K8sResource, len(ds))
 	for i, d := range ds {
 		d2 := d // grab a value copy of "d" to enforce block scope heap reference, and avoid shadowing non-block scope "d"
@@ -112,6 +140,15 @@ func (rkd *runningK8sData) Events() ([]*models.K8sResource, error) {
 	return ret, nil
 }
 
+// Logs method for runningK8sData
+func (rkd *runningK8sData) Logs(log *logger.Logger, opts PodLogOptions) ([]*models.ContainerLog, error) {
+	pods, err := getPods(rkd.podLister)
+	if err != nil {
+		return nil, err
+	}
+	return getContainerLogs(log, pods, rkd.podLogs, opts), nil
+}
+
 // Nodes method for runningK8sData
 func (rkd *runningK8sData) Nodes() ([]*models.K8sResource, error) {
 	nodes, err := getNodes(rkd.nodeLister)
@@ -129,6 +166,19 @@ func (rkd *runningK8sData) Nodes() ([]*models.K8sResource, error) {
 	return ret, nil
 }
 
+// NodeSummaries method for runningK8sData
+func (rkd *runningK8sData) NodeSummaries() ([]*models.NodeSummary, error) {
+	nodes, err := getNodes(rkd.nodeLister)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := getPods(rkd.allPodLister)
+	if err != nil {
+		return nil, err
+	}
+	return getNodeSummaries(nodes, pods), nil
+}
+
 // Pods method for runningK8sData
 func (rkd *runningK8sData) Pods() ([]*models.K8sResource, error) {
 	pods, err := getPods(rkd.podLister)
@@ -152,6 +202,7 @@ func (rkd *runningK8sData) ReplicaSets() ([]*models.K8sResource, error) {
 	if err != nil {
 		return nil, err
 	}
+	rs = rkd.filter.replicaSets(rs)
 	ret := make([]*models.K8sResource, len(rs))
 	for i, r := range rs {
 		r2 := r // grab a value copy of "r" to enforce block scope heap reference, and avoid shadowing non-block scope "r"
@@ -169,6 +220,7 @@ func (rkd *runningK8sData) ReplicationControllers() ([]*models.K8sResource, erro
 	if err != nil {
 		return nil, err
 	}
+	rcs = rkd.filter.replicationControllers(rcs)
 	ret := make([]*models.K8sResource, len(rcs))
 	for i, rc := range rcs {
 		rc2 := rc // grab a value copy of "rc" to enforce block scope heap reference, and avoid shadowing non-block scope "rc"
@@ -206,6 +258,24 @@ func GetNodesModels(k RunningK8sData) ([]*models.K8sResource, error) {
 	return nodes, nil
 }
 
+// GetLogsModels gets k8s container log model data for RESTful consumption
+func GetLogsModels(log *logger.Logger, k RunningK8sData, opts PodLogOptions) ([]*models.ContainerLog, error) {
+	logs, err := k.Logs(log, opts)
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// GetNodeSummariesModels gets k8s node summary model data for RESTful consumption
+func GetNodeSummariesModels(k RunningK8sData) ([]*models.NodeSummary, error) {
+	summaries, err := k.NodeSummaries()
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
 // GetPodsModels gets k8s pod model data for RESTful consumption
 func GetPodsModels(k RunningK8sData) ([]*models.K8sResource, error) {
 	pods, err := k.Pods()
@@ -305,6 +375,18 @@ func GetReplicationControllers(rcLister rc.Lister) ([]api.ReplicationController,
 	return rcs.Items, nil
 }
 
+// getJobs is a helper function that returns a slice of
+// Job objects given a kcl.JobInterface
+func getJobs(jobLister kcl.JobInterface) ([]extensions.Job, error) {
+	jobs, err := jobLister.List(api.ListOptions{
+		LabelSelector: labels.Everything(),
+	})
+	if err != nil {
+		return []extensions.Job{}, err
+	}
+	return jobs.Items, nil
+}
+
 // getNodes is a helper function that returns a slice of
 // Node objects given a node.Lister interface
 func getNodes(nodeLister node.Lister) ([]api.Node, error) {
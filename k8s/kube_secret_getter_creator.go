@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"github.com/deis/kubeapp/api/secret"
+	"k8s.io/kubernetes/pkg/api"
 )
 
 // KubeSecretGetterCreator is a composition of secret.Getter and secret.Creator. Please refer to the Godoc for those two interfaces (https://godoc.org/github.com/arschles/kubeapp/api/secret)
@@ -10,6 +11,19 @@ type KubeSecretGetterCreator interface {
 	secret.Creator
 }
 
+// KubeSecretGetterCreatorUpdater is a KubeSecretGetterCreator that can also update existing secrets
+type KubeSecretGetterCreatorUpdater interface {
+	KubeSecretGetterCreator
+	Update(*api.Secret) (*api.Secret, error)
+}
+
+// KubeSecretStore is a KubeSecretGetterCreator that can also list and delete secrets
+type KubeSecretStore interface {
+	KubeSecretGetterCreator
+	List(api.ListOptions) (*api.SecretList, error)
+	Delete(name string) error
+}
+
 // FakeKubeSecretGetterCreator is a composition of the secret.FakeGetter and secret.FakeCreator structs
 type FakeKubeSecretGetterCreator struct {
 	*secret.FakeGetter
@@ -0,0 +1,190 @@
+package k8s
+
+import (
+	"strings"
+
+	"github.com/deis/workflow-manager/logger"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	kcl "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/labels"
+)
+
+// AllNamespaces is the namespace list entry that selects every namespace in the cluster
+const AllNamespaces = "*"
+
+// ParseNamespaces parses a comma separated list of namespaces, such as "deis,kube-system"
+func ParseNamespaces(s string) []string {
+	var ret []string
+	for _, ns := range strings.Split(s, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			ret = append(ret, ns)
+		}
+	}
+	return ret
+}
+
+// ResolveNamespaces returns the namespaces named in names, followed by those that match the label
+// selector. An AllNamespaces entry in names selects every namespace in the cluster. Namespaces are
+// only listed once. If neither names nor selector select anything, defaultNamespace is returned
+func ResolveNamespaces(
+	nsi kcl.NamespacesInterface,
+	names []string,
+	selector string,
+	defaultNamespace string,
+) ([]string, error) {
+	var ret []string
+	seen := make(map[string]bool)
+	add := func(ns string) {
+		if !seen[ns] {
+			seen[ns] = true
+			ret = append(ret, ns)
+		}
+	}
+	var listSelectors []labels.Selector
+	for _, name := range names {
+		if name == AllNamespaces {
+			listSelectors = append(listSelectors, labels.Everything())
+			continue
+		}
+		add(name)
+	}
+	if selector != "" {
+		sel, err := labels.Parse(selector)
+		if err != nil {
+			return nil, err
+		}
+		listSelectors = append(listSelectors, sel)
+	}
+	for _, sel := range listSelectors {
+		nsList, err := nsi.Namespaces().List(api.ListOptions{LabelSelector: sel})
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nsList.Items {
+			add(ns.Name)
+		}
+	}
+	if len(ret) == 0 {
+		add(defaultNamespace)
+	}
+	return ret, nil
+}
+
+// multiComponentLister fulfills the ComponentLister interface by concatenating the resources listed
+// by several ComponentListers, usually one per namespace
+type multiComponentLister struct {
+	log     *logger.Logger
+	listers []ComponentLister
+}
+
+// NewMultiComponentLister returns a ComponentLister that lists the resources of each of listers,
+// in order. Listers that return ErrInventoryNotSynced are skipped and reported to log, so that a
+// namespace that is slow to sync doesn't hide the resources of the others
+func NewMultiComponentLister(log *logger.Logger, listers ...ComponentLister) ComponentLister {
+	return multiComponentLister{log: log, listers: listers}
+}
+
+// Deployments is the ComponentLister interface implementation
+func (m multiComponentLister) Deployments() ([]extensions.Deployment, error) {
+	var ret []extensions.Deployment
+	for _, l := range m.listers {
+		ds, err := l.Deployments()
+		if m.skipUnsynced(l, err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ds...)
+	}
+	return ret, nil
+}
+
+// DaemonSets is the ComponentLister interface implementation
+func (m multiComponentLister) DaemonSets() ([]extensions.DaemonSet, error) {
+	var ret []extensions.DaemonSet
+	for _, l := range m.listers {
+		ds, err := l.DaemonSets()
+		if m.skipUnsynced(l, err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ds...)
+	}
+	return ret, nil
+}
+
+// ReplicaSets is the ComponentLister interface implementation
+func (m multiComponentLister) ReplicaSets() ([]extensions.ReplicaSet, error) {
+	var ret []extensions.ReplicaSet
+	for _, l := range m.listers {
+		rs, err := l.ReplicaSets()
+		if m.skipUnsynced(l, err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, rs...)
+	}
+	return ret, nil
+}
+
+// ReplicationControllers is the ComponentLister interface implementation
+func (m multiComponentLister) ReplicationControllers() ([]api.ReplicationController, error) {
+	var ret []api.ReplicationController
+	for _, l := range m.listers {
+		rcs, err := l.ReplicationControllers()
+		if m.skipUnsynced(l, err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, rcs...)
+	}
+	return ret, nil
+}
+
+// Jobs is the ComponentLister interface implementation
+func (m multiComponentLister) Jobs() ([]extensions.Job, error) {
+	var ret []extensions.Job
+	for _, l := range m.listers {
+		jobs, err := l.Jobs()
+		if m.skipUnsynced(l, err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, jobs...)
+	}
+	return ret, nil
+}
+
+// Pods is the ComponentLister interface implementation
+func (m multiComponentLister) Pods() ([]api.Pod, error) {
+	var ret []api.Pod
+	for _, l := range m.listers {
+		pods, err := l.Pods()
+		if m.skipUnsynced(l, err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, pods...)
+	}
+	return ret, nil
+}
+
+// skipUnsynced returns true if err is ErrInventoryNotSynced, reporting that l's namespace is left
+// out of the listing until its inventory has synced
+func (m multiComponentLister) skipUnsynced(l ComponentLister, err error) bool {
+	if err != ErrInventoryNotSynced {
+		return false
+	}
+	log := m.log
+	if inv, ok := l.(*Inventory); ok {
+		log = log.With("namespace", inv.namespace)
+	}
+	log.Warnf("Skipping the components of a namespace whose inventory hasn't synced")
+	return true
+}
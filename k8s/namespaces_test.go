@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/logger"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/testapi"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/client/unversioned/testclient/simple"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	assert.Equal(t, ParseNamespaces(" deis, kube-system,,"), []string{"deis", "kube-system"}, "namespaces")
+	assert.Equal(t, len(ParseNamespaces("")), 0, "number of namespaces")
+}
+
+func TestResolveNamespaces(t *testing.T) {
+	// no namespaces are listed without a selector or "*"
+	namespaces, err := ResolveNamespaces(nil, nil, "", namespace)
+	assert.NoErr(t, err)
+	assert.Equal(t, namespaces, []string{namespace}, "default namespaces")
+	namespaces, err = ResolveNamespaces(nil, []string{"deis", "kube-system", "deis"}, "", namespace)
+	assert.NoErr(t, err)
+	assert.Equal(t, namespaces, []string{"deis", "kube-system"}, "named namespaces")
+
+	c := getK8sClientForNamespaces(t)
+	namespaces, err = ResolveNamespaces(c, []string{"deis"}, "workflow=app", namespace)
+	assert.NoErr(t, err)
+	assert.Equal(t, namespaces, []string{"deis", "my-app"}, "selected namespaces")
+	_, err = ResolveNamespaces(c, nil, "workflow in app", namespace)
+	assert.True(t, err != nil, "expected an error for an invalid selector")
+}
+
+func TestMultiComponentLister(t *testing.T) {
+	deis, apps := newTestInventory(), newTestInventory()
+	assert.NoErr(t, deis.deployments.Add(&extensions.Deployment{
+		ObjectMeta: api.ObjectMeta{Name: "deis-controller", Namespace: "deis"},
+	}))
+	assert.NoErr(t, apps.deployments.Add(&extensions.Deployment{
+		ObjectMeta: api.ObjectMeta{Name: "my-app-web", Namespace: "my-app"},
+	}))
+	deployments, err := NewMultiComponentLister(logger.Default(), deis, apps).Deployments()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(deployments), 2, "number of deployments")
+	assert.Equal(t, deployments[0].Namespace, "deis", "first deployment namespace")
+	assert.Equal(t, deployments[1].Namespace, "my-app", "second deployment namespace")
+}
+
+func TestMultiComponentListerSkipsUnsynced(t *testing.T) {
+	deis := newTestInventory()
+	assert.NoErr(t, deis.deployments.Add(&extensions.Deployment{
+		ObjectMeta: api.ObjectMeta{Name: "deis-controller", Namespace: "deis"},
+	}))
+	assert.NoErr(t, deis.pods.Add(&api.Pod{
+		ObjectMeta: api.ObjectMeta{Name: "deis-controller-1", Namespace: "deis"},
+	}))
+	lister := NewMultiComponentLister(logger.Default(), unsyncedComponentLister{}, deis)
+	deployments, err := lister.Deployments()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(deployments), 1, "number of deployments")
+	assert.Equal(t, deployments[0].Name, "deis-controller", "deployment name")
+	pods, err := lister.Pods()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(pods), 1, "number of pods")
+	// other errors still fail the listing
+	_, err = NewMultiComponentLister(logger.Default(), deis, failingComponentLister{}).Deployments()
+	assert.True(t, err != nil, "expected an error from a failing lister")
+}
+
+// Creating a novel mock struct that fulfills the ComponentLister interface
+type unsyncedComponentLister struct {
+	failingComponentLister
+}
+
+func (unsyncedComponentLister) Deployments() ([]extensions.Deployment, error) {
+	return nil, ErrInventoryNotSynced
+}
+
+func (unsyncedComponentLister) Pods() ([]api.Pod, error) {
+	return nil, ErrInventoryNotSynced
+}
+
+// Creating a novel mock struct that fulfills the ComponentLister interface
+type failingComponentLister struct{}
+
+func (failingComponentLister) Deployments() ([]extensions.Deployment, error) {
+	return nil, errors.New("test error")
+}
+
+func (failingComponentLister) DaemonSets() ([]extensions.DaemonSet, error) {
+	return nil, errors.New("test error")
+}
+
+func (failingComponentLister) ReplicaSets() ([]extensions.ReplicaSet, error) {
+	return nil, errors.New("test error")
+}
+
+func (failingComponentLister) ReplicationControllers() ([]api.ReplicationController, error) {
+	return nil, errors.New("test error")
+}
+
+func (failingComponentLister) Jobs() ([]extensions.Job, error) {
+	return nil, errors.New("test error")
+}
+
+func (failingComponentLister) Pods() ([]api.Pod, error) {
+	return nil, errors.New("test error")
+}
+
+func getK8sClientForNamespaces(t *testing.T) *simple.Client {
+	c := &simple.Client{
+		Request: simple.Request{
+			Method: "GET",
+			Path:   testapi.Default.ResourcePath("namespaces", "", ""),
+		},
+		Response: simple.Response{StatusCode: 200,
+			Body: &api.NamespaceList{
+				Items: []api.Namespace{
+					{ObjectMeta: api.ObjectMeta{Name: "deis"}},
+					{ObjectMeta: api.ObjectMeta{Name: "my-app"}},
+				},
+			},
+		},
+	}
+	return c.Setup(t)
+}
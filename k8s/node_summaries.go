@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+// taintsAnnotation holds a node's taints. Kubernetes 1.2 has no taints field, so the annotation
+// that the alpha taint support reads is used
+const taintsAnnotation = "scheduler.alpha.kubernetes.io/taints"
+
+// nodeTaint is a taint in the taintsAnnotation of a node
+type nodeTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"`
+}
+
+// getNodeSummaries returns a summary of each of nodes. The resources that are requested on a node
+// are summed from the pods in pods that are scheduled on it and haven't terminated, so pods should
+// be listed from every namespace
+func getNodeSummaries(nodes []api.Node, pods []api.Pod) []*models.NodeSummary {
+	requested := make(map[string]*models.NodeResources)
+	podCounts := make(map[string]int64)
+	for _, pod := range pods {
+		nodeName := pod.Spec.NodeName
+		if nodeName == "" || pod.Status.Phase == api.PodSucceeded || pod.Status.Phase == api.PodFailed {
+			continue
+		}
+		if requested[nodeName] == nil {
+			requested[nodeName] = &models.NodeResources{}
+		}
+		podCounts[nodeName]++
+		for _, container := range pod.Spec.Containers {
+			addResources(requested[nodeName], container.Resources.Requests)
+		}
+	}
+	ret := make([]*models.NodeSummary, 0, len(nodes))
+	for _, node := range nodes {
+		summary := &models.NodeSummary{
+			Name:                    node.Name,
+			Unschedulable:           node.Spec.Unschedulable,
+			Conditions:              make(map[string]string),
+			Allocatable:             &models.NodeResources{},
+			Requested:               requested[node.Name],
+			Pods:                    podCounts[node.Name],
+			KubeletVersion:          node.Status.NodeInfo.KubeletVersion,
+			ContainerRuntimeVersion: node.Status.NodeInfo.ContainerRuntimeVersion,
+			Taints:                  getNodeTaints(node),
+		}
+		if summary.Requested == nil {
+			summary.Requested = &models.NodeResources{}
+		}
+		for _, cond := range node.Status.Conditions {
+			summary.Conditions[string(cond.Type)] = string(cond.Status)
+			if cond.Type == api.NodeReady {
+				summary.Ready = cond.Status == api.ConditionTrue
+			}
+		}
+		allocatable := node.Status.Allocatable
+		if len(allocatable) == 0 {
+			// nodes whose kubelet predates allocatable resources only report their capacity
+			allocatable = node.Status.Capacity
+		}
+		addResources(summary.Allocatable, allocatable)
+		ret = append(ret, summary)
+	}
+	return ret
+}
+
+// addResources adds the CPU and memory in list to res
+func addResources(res *models.NodeResources, list api.ResourceList) {
+	if cpu, ok := list[api.ResourceCPU]; ok {
+		res.CPUMillicores += cpu.MilliValue()
+	}
+	if memory, ok := list[api.ResourceMemory]; ok {
+		res.MemoryBytes += memory.Value()
+	}
+}
+
+// getNodeTaints returns node's taints, formatted as key=value:effect. Taints that can't be parsed
+// are left out
+func getNodeTaints(node api.Node) []string {
+	annotation, ok := node.Annotations[taintsAnnotation]
+	if !ok {
+		return nil
+	}
+	var taints []nodeTaint
+	if err := json.Unmarshal([]byte(annotation), &taints); err != nil {
+		return nil
+	}
+	ret := make([]string, 0, len(taints))
+	for _, t := range taints {
+		ret = append(ret, fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect))
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/resource"
+)
+
+func getTestSummaryPod(name, nodeName string, phase api.PodPhase, cpu, memory string) api.Pod {
+	return api.Pod{
+		ObjectMeta: api.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: api.PodSpec{
+			NodeName: nodeName,
+			Containers: []api.Container{{
+				Name: name,
+				Resources: api.ResourceRequirements{Requests: api.ResourceList{
+					api.ResourceCPU:    resource.MustParse(cpu),
+					api.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+		Status: api.PodStatus{Phase: phase},
+	}
+}
+
+func TestGetNodeSummaries(t *testing.T) {
+	nodes := []api.Node{
+		{
+			ObjectMeta: api.ObjectMeta{
+				Name:        "node-1",
+				Annotations: map[string]string{taintsAnnotation: `[{"key":"dedicated","value":"deis","effect":"NoSchedule"}]`},
+			},
+			Status: api.NodeStatus{
+				Allocatable: api.ResourceList{
+					api.ResourceCPU:    resource.MustParse("2"),
+					api.ResourceMemory: resource.MustParse("4Gi"),
+				},
+				Capacity: api.ResourceList{
+					api.ResourceCPU:    resource.MustParse("4"),
+					api.ResourceMemory: resource.MustParse("8Gi"),
+				},
+				Conditions: []api.NodeCondition{
+					{Type: api.NodeReady, Status: api.ConditionTrue},
+					{Type: api.NodeConditionType("MemoryPressure"), Status: api.ConditionFalse},
+				},
+				NodeInfo: api.NodeSystemInfo{KubeletVersion: "v1.2.4", ContainerRuntimeVersion: "docker://1.10.3"},
+			},
+		},
+		{
+			ObjectMeta: api.ObjectMeta{Name: "node-2"},
+			Spec:       api.NodeSpec{Unschedulable: true},
+			Status: api.NodeStatus{
+				Capacity: api.ResourceList{
+					api.ResourceCPU:    resource.MustParse("1"),
+					api.ResourceMemory: resource.MustParse("1Gi"),
+				},
+				Conditions: []api.NodeCondition{{Type: api.NodeReady, Status: api.ConditionUnknown}},
+			},
+		},
+	}
+	pods := []api.Pod{
+		getTestSummaryPod("deis-router-1", "node-1", api.PodRunning, "500m", "512Mi"),
+		getTestSummaryPod("deis-builder-1", "node-1", api.PodPending, "250m", "256Mi"),
+		getTestSummaryPod("deis-database-1", "node-1", api.PodSucceeded, "1", "1Gi"),
+		getTestSummaryPod("deis-registry-1", "", api.PodPending, "1", "1Gi"),
+	}
+	summaries := getNodeSummaries(nodes, pods)
+	assert.Equal(t, len(summaries), 2, "number of summaries")
+
+	node1 := summaries[0]
+	assert.Equal(t, node1.Name, "node-1", "node name")
+	assert.True(t, node1.Ready, "node-1 wasn't ready")
+	assert.Equal(t, node1.Conditions["MemoryPressure"], "False", "memory pressure condition")
+	assert.Equal(t, *node1.Allocatable, models.NodeResources{CPUMillicores: 2000, MemoryBytes: 4 << 30}, "allocatable resources")
+	assert.Equal(t, *node1.Requested, models.NodeResources{CPUMillicores: 750, MemoryBytes: 768 << 20}, "requested resources")
+	assert.Equal(t, node1.Pods, int64(2), "number of pods")
+	assert.Equal(t, node1.KubeletVersion, "v1.2.4", "kubelet version")
+	assert.Equal(t, node1.ContainerRuntimeVersion, "docker://1.10.3", "container runtime version")
+	assert.Equal(t, node1.Taints, []string{"dedicated=deis:NoSchedule"}, "taints")
+
+	node2 := summaries[1]
+	assert.False(t, node2.Ready, "node-2 was ready")
+	assert.True(t, node2.Unschedulable, "node-2 was schedulable")
+	// without allocatable resources, the node's capacity is reported
+	assert.Equal(t, *node2.Allocatable, models.NodeResources{CPUMillicores: 1000, MemoryBytes: 1 << 30}, "allocatable resources")
+	assert.Equal(t, *node2.Requested, models.NodeResources{}, "requested resources")
+	assert.Equal(t, node2.Pods, int64(0), "number of pods")
+}
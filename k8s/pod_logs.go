@@ -0,0 +1,231 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+// errLogTimeout is returned for the containers whose logs couldn't be read before their pod's
+// timeout expired
+var errLogTimeout = errors.New("timed out reading the container's logs")
+
+// errLogDeadline is returned for the containers whose logs weren't read at all, because the
+// deadline for collecting logs had passed
+var errLogDeadline = errors.New("ran out of time to collect the container's logs")
+
+// PodLogOptions configures how much of each container's logs is collected. Zero values don't
+// limit anything
+type PodLogOptions struct {
+	// TailLines is the number of lines to collect from the end of each log
+	TailLines int64
+	// Since only collects the lines logged in the last Since
+	Since time.Duration
+	// MaxBytes caps the size of each log. The most recent lines of longer logs are kept
+	MaxBytes int64
+	// MaxTotalBytes caps the combined size of a namespace's logs. Once it's reached, the remaining
+	// logs are reported as truncated without any lines
+	MaxTotalBytes int64
+	// Timeout is the time allowed to read the logs of each pod, for all of its containers
+	Timeout time.Duration
+	// Deadline is the time by which all logs must have been read. Logs are read one pod at a time,
+	// so it bounds the time taken by namespaces with many pods. Set the same Deadline for every
+	// namespace to bound the time taken by all of them
+	Deadline time.Time
+}
+
+// podLogStreamer opens a stream of the logs of the pod called name
+type podLogStreamer func(name string, opts *api.PodLogOptions) (io.ReadCloser, error)
+
+// getContainerLogs reads the logs of every container in pods, and the logs of the previous
+// instance of every container that has restarted. Failures to read a log are reported in the
+// log's Error and to log, so that one broken pod doesn't hide the logs of the others
+func getContainerLogs(log *logger.Logger, pods []api.Pod, stream podLogStreamer, opts PodLogOptions) []*models.ContainerLog {
+	var ret []*models.ContainerLog
+	var total int64
+	for _, pod := range pods {
+		podName := pod.Name
+		deadline := opts.Deadline
+		if podDeadline := time.Now().Add(opts.Timeout); opts.Timeout > 0 && (deadline.IsZero() || podDeadline.Before(deadline)) {
+			deadline = podDeadline
+		}
+		restarted := make(map[string]bool)
+		for _, status := range pod.Status.ContainerStatuses {
+			restarted[status.Name] = status.RestartCount > 0
+		}
+		for _, container := range pod.Spec.Containers {
+			previous := []bool{false}
+			if restarted[container.Name] {
+				previous = append(previous, true)
+			}
+			for _, prev := range previous {
+				l := &models.ContainerLog{Pod: podName, Container: container.Name, Previous: prev}
+				ret = append(ret, l)
+				if opts.MaxTotalBytes > 0 && total >= opts.MaxTotalBytes {
+					l.Truncated = true
+					continue
+				}
+				cLog := log.WithFields(logger.Fields{"pod": podName, "container": container.Name, "previous": prev})
+				if !opts.Deadline.IsZero() && !time.Now().Before(opts.Deadline) {
+					cLog.Debugf("Skipping the container's logs, the deadline for collecting logs has passed")
+					msg := errLogDeadline.Error()
+					l.Error = &msg
+					continue
+				}
+				logOpts := &api.PodLogOptions{Container: container.Name, Previous: prev}
+				if opts.TailLines > 0 {
+					tail := opts.TailLines
+					logOpts.TailLines = &tail
+				}
+				if opts.Since > 0 {
+					since := int64(opts.Since.Seconds())
+					logOpts.SinceSeconds = &since
+				}
+				podLog, truncated, err := readPodLog(func() (io.ReadCloser, error) {
+					return stream(podName, logOpts)
+				}, opts.MaxBytes, deadline)
+				if err != nil {
+					cLog.WithError(err).Warnf("unable to read the container's logs")
+					msg := err.Error()
+					l.Error = &msg
+				}
+				if opts.MaxTotalBytes > 0 && total+int64(len(podLog)) > opts.MaxTotalBytes {
+					podLog = trimToLastLines(podLog, opts.MaxTotalBytes-total)
+					truncated = true
+				}
+				total += int64(len(podLog))
+				l.Log = podLog
+				l.Truncated = truncated
+			}
+		}
+	}
+	return ret
+}
+
+// readPodLog reads the log stream that open returns, keeping its last maxBytes bytes if maxBytes
+// is greater than zero. It returns what it has read so far along with errLogTimeout if the stream
+// isn't read by deadline. A zero deadline never expires
+func readPodLog(open func() (io.ReadCloser, error), maxBytes int64, deadline time.Time) (string, bool, error) {
+	tr := &tailReader{maxBytes: maxBytes}
+	done := make(chan error, 1)
+	go func() {
+		done <- tr.readFrom(open)
+	}()
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(deadline.Sub(time.Now()))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case err = <-done:
+	case <-timeout:
+		tr.cancel()
+		err = errLogTimeout
+	}
+	log, truncated := tr.result()
+	return log, truncated, err
+}
+
+// tailReader reads a log stream, keeping its most recent bytes. It's safe for concurrent use, so
+// that a stream that's being read can be canceled
+type tailReader struct {
+	maxBytes int64
+
+	mut      sync.Mutex
+	rc       io.ReadCloser
+	canceled bool
+	buf      bytes.Buffer
+}
+
+// readFrom opens a stream with open and reads it until it ends, or tr is canceled
+func (tr *tailReader) readFrom(open func() (io.ReadCloser, error)) error {
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	tr.mut.Lock()
+	if tr.canceled {
+		tr.mut.Unlock()
+		return rc.Close()
+	}
+	tr.rc = rc
+	tr.mut.Unlock()
+	defer func() {
+		// a canceled stream has already been closed
+		tr.mut.Lock()
+		if !tr.canceled {
+			rc.Close()
+		}
+		tr.mut.Unlock()
+	}()
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := rc.Read(chunk)
+		tr.mut.Lock()
+		if tr.canceled {
+			tr.mut.Unlock()
+			return errLogTimeout
+		}
+		tr.buf.Write(chunk[:n])
+		// one byte more than maxBytes is kept, to tell whether the oldest line that's kept is whole
+		if keep := tr.maxBytes + 1; tr.maxBytes > 0 && int64(tr.buf.Len()) > keep {
+			tr.buf.Next(tr.buf.Len() - int(keep))
+		}
+		tr.mut.Unlock()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading the container's logs (%s)", err)
+		}
+	}
+}
+
+// cancel stops reading the stream, and closes it if it has been opened
+func (tr *tailReader) cancel() {
+	tr.mut.Lock()
+	defer tr.mut.Unlock()
+	tr.canceled = true
+	if tr.rc != nil {
+		tr.rc.Close()
+	}
+}
+
+// result returns what has been read so far, and whether it was truncated to maxBytes
+func (tr *tailReader) result() (string, bool) {
+	tr.mut.Lock()
+	defer tr.mut.Unlock()
+	log := tr.buf.String()
+	if tr.maxBytes > 0 && int64(len(log)) > tr.maxBytes {
+		return trimToLastLines(log, tr.maxBytes), true
+	}
+	return log, false
+}
+
+// trimToLastLines returns the whole lines at the end of log that fit in maxBytes. If not even one
+// whole line fits, the end of the last line is returned
+func trimToLastLines(log string, maxBytes int64) string {
+	if int64(len(log)) <= maxBytes {
+		return log
+	} else if maxBytes <= 0 {
+		return ""
+	}
+	cut := int64(len(log)) - maxBytes
+	tail := log[cut:]
+	if log[cut-1] == '\n' {
+		return tail
+	}
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		return tail[i+1:]
+	}
+	return tail
+}
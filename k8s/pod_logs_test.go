@@ -0,0 +1,122 @@
+package k8s
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/logger"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+// blockingReader is an io.ReadCloser whose reads block until it's closed
+type blockingReader struct {
+	closed chan struct{}
+}
+
+func (b *blockingReader) Read(p []byte) (int, error) {
+	<-b.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (b *blockingReader) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func getTestLogPod(name string, restarts int, containers ...string) api.Pod {
+	pod := api.Pod{ObjectMeta: api.ObjectMeta{Name: name, Namespace: namespace}}
+	for _, c := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, api.Container{Name: c})
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, api.ContainerStatus{Name: c, RestartCount: restarts})
+	}
+	return pod
+}
+
+func TestGetContainerLogs(t *testing.T) {
+	pods := []api.Pod{
+		getTestLogPod("deis-router-1", 0, "deis-router"),
+		getTestLogPod("deis-builder-1", 2, "deis-builder"),
+		getTestLogPod("deis-registry-1", 0, "deis-registry"),
+	}
+	var requested []*api.PodLogOptions
+	stream := func(name string, opts *api.PodLogOptions) (io.ReadCloser, error) {
+		requested = append(requested, opts)
+		switch {
+		case name == "deis-registry-1":
+			return nil, errors.New("container is waiting to start")
+		case opts.Previous:
+			return ioutil.NopCloser(strings.NewReader("OOMKilled\n")), nil
+		}
+		return ioutil.NopCloser(strings.NewReader("line 1\nline 2\nline 3\n")), nil
+	}
+	logs := getContainerLogs(logger.Default(), pods, stream, PodLogOptions{TailLines: 100, Since: time.Hour, MaxBytes: 14})
+	assert.Equal(t, len(logs), 4, "number of logs")
+	assert.Equal(t, logs[0].Pod, "deis-router-1", "pod")
+	assert.Equal(t, logs[0].Container, "deis-router", "container")
+	assert.Equal(t, logs[0].Log, "line 2\nline 3\n", "truncated log")
+	assert.True(t, logs[0].Truncated, "log wasn't truncated")
+	assert.False(t, logs[1].Previous, "current log was reported as previous")
+	assert.True(t, logs[2].Previous, "previous log wasn't reported as previous")
+	assert.Equal(t, logs[2].Log, "OOMKilled\n", "previous log")
+	assert.False(t, logs[2].Truncated, "short log was truncated")
+	assert.True(t, logs[3].Error != nil, "expected an error for a container that hasn't started")
+	assert.Equal(t, *requested[0].TailLines, int64(100), "tail lines")
+	assert.Equal(t, *requested[0].SinceSeconds, int64(3600), "since seconds")
+	assert.Equal(t, requested[0].Container, "deis-router", "requested container")
+}
+
+func TestGetContainerLogsMaxTotalBytes(t *testing.T) {
+	pods := []api.Pod{getTestLogPod("deis-router-1", 0, "a", "b", "c")}
+	stream := func(name string, opts *api.PodLogOptions) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("0123456789\n")), nil
+	}
+	logs := getContainerLogs(logger.Default(), pods, stream, PodLogOptions{MaxTotalBytes: 16})
+	assert.Equal(t, len(logs), 3, "number of logs")
+	assert.Equal(t, logs[0].Log, "0123456789\n", "first log")
+	assert.False(t, logs[0].Truncated, "first log was truncated")
+	assert.True(t, logs[1].Truncated, "second log wasn't truncated")
+	assert.True(t, len(logs[0].Log)+len(logs[1].Log) <= 16, "logs exceeded the total size cap")
+	assert.True(t, logs[2].Truncated, "third log wasn't truncated")
+	assert.Equal(t, logs[2].Log, "", "third log")
+}
+
+func TestGetContainerLogsTimeout(t *testing.T) {
+	pods := []api.Pod{getTestLogPod("deis-router-1", 0, "deis-router")}
+	stream := func(name string, opts *api.PodLogOptions) (io.ReadCloser, error) {
+		return &blockingReader{closed: make(chan struct{})}, nil
+	}
+	logs := getContainerLogs(logger.Default(), pods, stream, PodLogOptions{Timeout: 10 * time.Millisecond})
+	assert.Equal(t, len(logs), 1, "number of logs")
+	assert.True(t, logs[0].Error != nil, "expected a timeout error")
+	assert.Equal(t, *logs[0].Error, errLogTimeout.Error(), "error")
+}
+
+func TestGetContainerLogsDeadline(t *testing.T) {
+	pods := []api.Pod{
+		getTestLogPod("deis-router-1", 0, "deis-router"),
+		getTestLogPod("deis-builder-1", 0, "deis-builder"),
+	}
+	var mut sync.Mutex
+	opened := make(map[string]bool)
+	stream := func(name string, opts *api.PodLogOptions) (io.ReadCloser, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		opened[name] = true
+		return &blockingReader{closed: make(chan struct{})}, nil
+	}
+	// the deadline cuts the first pod's timeout short, and leaves no time for the second pod
+	opts := PodLogOptions{Timeout: time.Hour, Deadline: time.Now().Add(10 * time.Millisecond)}
+	logs := getContainerLogs(logger.Default(), pods, stream, opts)
+	assert.Equal(t, len(logs), 2, "number of logs")
+	assert.Equal(t, *logs[0].Error, errLogTimeout.Error(), "first log error")
+	assert.Equal(t, *logs[1].Error, errLogDeadline.Error(), "second log error")
+	mut.Lock()
+	defer mut.Unlock()
+	assert.False(t, opened["deis-builder-1"], "second log was read after the deadline")
+}
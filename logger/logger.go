@@ -0,0 +1,209 @@
+// Package logger is a small leveled logger that writes one JSON object per line. Loggers carry
+// fields, such as correlation IDs, that are written with every line they log
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+// Level is the severity of a log line. Lines below a Logger's level are discarded
+type Level int
+
+const (
+	// DebugLevel is the level of verbose, diagnostic lines
+	DebugLevel Level = iota
+	// InfoLevel is the level of routine lines
+	InfoLevel
+	// WarnLevel is the level of recoverable failures
+	WarnLevel
+	// ErrorLevel is the level of failures
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+// String is the fmt.Stringer interface implementation
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel parses a level name: "debug", "info", "warn" or "error"
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// Fields are the key/value pairs written with every line of a Logger
+type Fields map[string]interface{}
+
+// the field names written with every line
+const (
+	timeField  = "time"
+	levelField = "level"
+	msgField   = "msg"
+	errorField = "error"
+)
+
+// CorrelationIDField is the name of the field that correlates the lines logged while serving one
+// request, or during one run of a periodic job
+const CorrelationIDField = "correlation_id"
+
+// output is the destination shared by a Logger and all of the Loggers derived from it
+type output struct {
+	mut   *sync.Mutex
+	w     io.Writer
+	level Level
+}
+
+// Logger writes leveled lines, as JSON objects, to an io.Writer. It's safe for concurrent use
+type Logger struct {
+	out    *output
+	fields Fields
+}
+
+// New returns a new Logger that writes the lines at level or above to w
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{out: &output{mut: new(sync.Mutex), w: w, level: level}}
+}
+
+// With returns a Logger that writes key=value with every line, in addition to l's fields
+func (l *Logger) With(key string, value interface{}) *Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+// WithFields returns a Logger that writes fields with every line, in addition to l's fields
+func (l *Logger) WithFields(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Logger{out: l.out, fields: merged}
+}
+
+// WithError returns a Logger that writes err in the "error" field of every line
+func (l *Logger) WithError(err error) *Logger {
+	if err == nil {
+		return l
+	}
+	return l.With(errorField, err.Error())
+}
+
+// Enabled returns true if lines at level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.out.level
+}
+
+// Debugf logs a line at DebugLevel
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(DebugLevel, format, args)
+}
+
+// Infof logs a line at InfoLevel
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(InfoLevel, format, args)
+}
+
+// Warnf logs a line at WarnLevel
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(WarnLevel, format, args)
+}
+
+// Errorf logs a line at ErrorLevel
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(ErrorLevel, format, args)
+}
+
+// Fatalf logs a line at ErrorLevel, and exits the process with status 1
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(ErrorLevel, format, args)
+	os.Exit(1)
+}
+
+func (l *Logger) log(level Level, format string, args []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	line := make(Fields, len(l.fields)+3)
+	for k, v := range l.fields {
+		line[k] = v
+	}
+	line[timeField] = time.Now().UTC().Format(time.RFC3339Nano)
+	line[levelField] = level.String()
+	line[msgField] = fmt.Sprintf(format, args...)
+	b, err := json.Marshal(line)
+	if err != nil {
+		// a field couldn't be marshaled. Don't lose the message
+		b, _ = json.Marshal(Fields{
+			timeField:  line[timeField],
+			levelField: line[levelField],
+			msgField:   line[msgField],
+			errorField: fmt.Sprintf("unable to marshal log fields (%s)", err),
+		})
+	}
+	l.out.mut.Lock()
+	defer l.out.mut.Unlock()
+	l.out.w.Write(append(b, '\n'))
+}
+
+var (
+	defaultMut = new(sync.RWMutex)
+	defaultLog = New(os.Stderr, InfoLevel)
+)
+
+// Default returns the process wide Logger, which is used when no other Logger is at hand
+func Default() *Logger {
+	defaultMut.RLock()
+	defer defaultMut.RUnlock()
+	return defaultLog
+}
+
+// SetDefault replaces the process wide Logger
+func SetDefault(l *Logger) {
+	defaultMut.Lock()
+	defer defaultMut.Unlock()
+	defaultLog = l
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx that carries l
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, or Default() if it doesn't carry one
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return Default()
+}
+
+// NewCorrelationID returns a new, random correlation ID
+func NewCorrelationID() string {
+	return uuid.NewV4().String()
+}
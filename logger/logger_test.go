@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/arschles/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel} {
+		parsed, err := ParseLevel(strings.ToUpper(level.String()))
+		assert.NoErr(t, err)
+		assert.Equal(t, parsed, level, "parsed level")
+	}
+	_, err := ParseLevel("verbose")
+	assert.True(t, err != nil, "expected an error for an unknown level")
+}
+
+func TestLoggerJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(buf, InfoLevel).With(CorrelationIDField, "abc")
+	l.Debugf("not written")
+	l.WithError(errors.New("boom")).Errorf("job %s failed", "sendVersions")
+	l.Infof("done")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, len(lines), 2, "number of lines")
+	var line map[string]interface{}
+	assert.NoErr(t, json.Unmarshal([]byte(lines[0]), &line))
+	assert.Equal(t, line["level"], "error", "level")
+	assert.Equal(t, line["msg"], "job sendVersions failed", "message")
+	assert.Equal(t, line["error"], "boom", "error")
+	assert.Equal(t, line[CorrelationIDField], "abc", "correlation ID")
+	_, hasTime := line["time"]
+	assert.True(t, hasTime, "line had no time")
+	// fields added to a derived logger don't leak into its parent
+	line = nil
+	assert.NoErr(t, json.Unmarshal([]byte(lines[1]), &line))
+	_, hasErr := line["error"]
+	assert.False(t, hasErr, "error field leaked into the parent logger")
+}
+
+func TestFromContext(t *testing.T) {
+	assert.True(t, FromContext(context.Background()) == Default(), "expected the default logger")
+	l := New(new(bytes.Buffer), DebugLevel)
+	assert.True(t, FromContext(NewContext(context.Background(), l)) == l, "expected the context's logger")
+}
@@ -0,0 +1,218 @@
+// Package metrics contains the Prometheus metrics exported by the workflow manager, and helpers to
+// record them for periodic jobs, HTTP handlers and upstream API calls
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"github.com/go-swagger/go-swagger/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "workflow_manager"
+
+var (
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "runs_total",
+		Help:      "Number of times each periodic job has run.",
+	}, []string{"job"})
+	jobFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "failures_total",
+		Help:      "Number of times each periodic job has returned an error.",
+	}, []string{"job"})
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "duration_seconds",
+		Help:      "Time taken by each run of a periodic job.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"job"})
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests served, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+	upstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "requests_total",
+		Help:      "Number of calls made to the versions and doctor APIs, by operation.",
+	}, []string{"operation"})
+	upstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "errors_total",
+		Help:      "Number of failed calls made to the versions and doctor APIs, by operation.",
+	}, []string{"operation"})
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken by calls to the versions and doctor APIs, by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+	componentsInstalled = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "components_installed",
+		Help:      "Number of Deis Workflow components installed in the cluster.",
+	})
+	componentsUpdatable = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "components_updates_available",
+		Help:      "Number of installed components that have an update available.",
+	})
+	componentInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "component_info",
+		Help:      "Installed components and their versions. The value is always 1.",
+	}, []string{"component", "version"})
+	componentUpdateAvailable = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "component_update_available",
+		Help:      "Whether an update is available for each installed component: 1 if it is, and 0 otherwise.",
+	}, []string{"component", "version"})
+)
+
+// observedComponents holds the label values of the component series set by the last call to
+// ObserveCluster, so that the next call only deletes those that went stale
+var (
+	observedMut        sync.Mutex
+	observedComponents = make(map[[2]string]bool)
+)
+
+func init() {
+	prometheus.MustRegister(
+		jobRuns,
+		jobFailures,
+		jobDuration,
+		httpRequests,
+		httpDuration,
+		upstreamRequests,
+		upstreamErrors,
+		upstreamDuration,
+		componentsInstalled,
+		componentsUpdatable,
+		componentInfo,
+		componentUpdateAvailable,
+	)
+}
+
+// Handler returns the http.Handler that serves all registered metrics in the Prometheus text
+// exposition format
+func Handler() http.Handler {
+	return prometheus.Handler()
+}
+
+// ObserveJob records a single run of the periodic job named job, which took dur and returned err
+func ObserveJob(job string, dur time.Duration, err error) {
+	jobRuns.WithLabelValues(job).Inc()
+	jobDuration.WithLabelValues(job).Observe(dur.Seconds())
+	if err != nil {
+		jobFailures.WithLabelValues(job).Inc()
+	}
+}
+
+// ObserveCluster records the number of components installed in cluster and the number of them that
+// have an update available. The series of components that are no longer installed are deleted,
+// while the others are updated in place, so that scrapes never see a partial set
+func ObserveCluster(cluster models.Cluster) {
+	observedMut.Lock()
+	defer observedMut.Unlock()
+	observed := make(map[[2]string]bool, len(cluster.Components))
+	updatable := 0
+	for _, component := range cluster.Components {
+		if component.Component == nil {
+			continue
+		}
+		version := ""
+		if component.Version != nil {
+			version = component.Version.Version
+		}
+		updateAvailable := 0.0
+		if component.UpdateAvailable != nil {
+			updateAvailable = 1
+			updatable++
+		}
+		labels := [2]string{component.Component.Name, version}
+		// components of the same name in several namespaces share their series, and an update that's
+		// available for any of them is reported
+		if !observed[labels] || updateAvailable > 0 {
+			componentUpdateAvailable.WithLabelValues(labels[0], labels[1]).Set(updateAvailable)
+		}
+		componentInfo.WithLabelValues(labels[0], labels[1]).Set(1)
+		observed[labels] = true
+	}
+	for labels := range observedComponents {
+		if !observed[labels] {
+			componentInfo.DeleteLabelValues(labels[0], labels[1])
+			componentUpdateAvailable.DeleteLabelValues(labels[0], labels[1])
+		}
+	}
+	observedComponents = observed
+	componentsInstalled.Set(float64(len(cluster.Components)))
+	componentsUpdatable.Set(float64(updatable))
+}
+
+// InstrumentHandler wraps h so that the latency and status code of every request it serves is
+// recorded under route
+func InstrumentHandler(route string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(sw, r)
+		httpDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(sw.code)).Inc()
+	})
+}
+
+// statusWriter is an http.ResponseWriter that remembers the status code written to it
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+// WriteHeader is the http.ResponseWriter interface implementation
+func (s *statusWriter) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// instrumentedTransport is a swagger client.Transport that records metrics for each operation
+// it submits
+type instrumentedTransport struct {
+	transport client.Transport
+}
+
+// InstrumentTransport wraps t so that the latency and outcome of every operation submitted through
+// it is recorded, labeled with the operation ID
+func InstrumentTransport(t client.Transport) client.Transport {
+	return &instrumentedTransport{transport: t}
+}
+
+// Submit is the client.Transport interface implementation
+func (i *instrumentedTransport) Submit(op *client.Operation) (interface{}, error) {
+	start := time.Now()
+	res, err := i.transport.Submit(op)
+	upstreamDuration.WithLabelValues(op.ID).Observe(time.Since(start).Seconds())
+	upstreamRequests.WithLabelValues(op.ID).Inc()
+	if err != nil {
+		upstreamErrors.WithLabelValues(op.ID).Inc()
+	}
+	return res, err
+}
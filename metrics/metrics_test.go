@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"github.com/go-swagger/go-swagger/client"
+)
+
+// Creating a novel mock struct that fulfills the client.Transport interface
+type mockTransport struct {
+	err error
+}
+
+func (m mockTransport) Submit(op *client.Operation) (interface{}, error) {
+	return nil, m.err
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	h := InstrumentHandler("/teapot", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	assert.NoErr(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusTeapot, "response code")
+	assertMetric(t, `workflow_manager_http_requests_total{code="418",method="GET",route="/teapot"} 1`)
+	assertMetric(t, `workflow_manager_http_request_duration_seconds_count{route="/teapot"} 1`)
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	tr := InstrumentTransport(mockTransport{})
+	_, err := tr.Submit(&client.Operation{ID: "testOK"})
+	assert.NoErr(t, err)
+	tr = InstrumentTransport(mockTransport{err: errors.New("upstream error")})
+	_, err = tr.Submit(&client.Operation{ID: "testError"})
+	assert.True(t, err != nil, "transport error was not returned")
+	assertMetric(t, `workflow_manager_upstream_requests_total{operation="testOK"} 1`)
+	assertMetric(t, `workflow_manager_upstream_requests_total{operation="testError"} 1`)
+	assertMetric(t, `workflow_manager_upstream_errors_total{operation="testError"} 1`)
+}
+
+func TestObserveJob(t *testing.T) {
+	ObserveJob("testJob", time.Second, nil)
+	ObserveJob("testJob", time.Second, errors.New("job error"))
+	assertMetric(t, `workflow_manager_job_runs_total{job="testJob"} 2`)
+	assertMetric(t, `workflow_manager_job_failures_total{job="testJob"} 1`)
+	assertMetric(t, `workflow_manager_job_duration_seconds_sum{job="testJob"} 2`)
+}
+
+func TestObserveCluster(t *testing.T) {
+	update := "2.1.0"
+	ObserveCluster(models.Cluster{
+		Components: []*models.ComponentVersion{
+			{
+				Component:       &models.Component{Name: "controller"},
+				Version:         &models.Version{Version: "2.0.0"},
+				UpdateAvailable: &update,
+			},
+			{
+				Component: &models.Component{Name: "router"},
+				Version:   &models.Version{Version: "2.1.0"},
+			},
+		},
+	})
+	assertMetric(t, `workflow_manager_components_installed 2`)
+	assertMetric(t, `workflow_manager_components_updates_available 1`)
+	assertMetric(t, `workflow_manager_component_info{component="controller",version="2.0.0"} 1`)
+	assertMetric(t, `workflow_manager_component_info{component="router",version="2.1.0"} 1`)
+	assertMetric(t, `workflow_manager_component_update_available{component="controller",version="2.0.0"} 1`)
+	assertMetric(t, `workflow_manager_component_update_available{component="router",version="2.1.0"} 0`)
+
+	// the series of a component that's no longer installed are deleted
+	ObserveCluster(models.Cluster{
+		Components: []*models.ComponentVersion{
+			{
+				Component: &models.Component{Name: "router"},
+				Version:   &models.Version{Version: "2.1.0"},
+			},
+		},
+	})
+	assertMetric(t, `workflow_manager_component_info{component="router",version="2.1.0"} 1`)
+	assertNoMetric(t, `workflow_manager_component_info{component="controller",version="2.0.0"}`)
+	assertNoMetric(t, `workflow_manager_component_update_available{component="controller",version="2.0.0"}`)
+}
+
+// assertMetric fails the test if line is not in the output of the metrics handler
+func assertMetric(t *testing.T, line string) {
+	body := getMetrics(t)
+	if !strings.Contains(body, line+"\n") {
+		t.Fatalf("metric %s not found in:\n%s", line, body)
+	}
+}
+
+// assertNoMetric fails the test if the output of the metrics handler has a series called series
+func assertNoMetric(t *testing.T, series string) {
+	body := getMetrics(t)
+	if strings.Contains(body, series+" ") {
+		t.Fatalf("metric %s found in:\n%s", series, body)
+	}
+}
+
+// getMetrics returns the output of the metrics handler
+func getMetrics(t *testing.T) string {
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	assert.NoErr(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoErr(t, err)
+	return string(body)
+}
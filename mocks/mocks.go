@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -8,6 +9,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/deis/workflow-manager/k8s"
+	"github.com/deis/workflow-manager/logger"
 	"github.com/deis/workflow-manager/pkg/swagger/models"
 )
 
@@ -30,17 +33,28 @@ func getMocksWd() string {
 type InstalledMockData struct{}
 
 // Get method for InstalledMockData
-func (g InstalledMockData) Get() ([]byte, error) {
+func (g InstalledMockData) Get() (models.Cluster, error) {
 	data, err := GetMockComponents()
 	if err != nil {
 		log.Print(err)
-		return nil, err
+		return models.Cluster{}, err
 	}
-	return data, nil
+	var cluster models.Cluster
+	if err := json.Unmarshal(data, &cluster); err != nil {
+		return models.Cluster{}, err
+	}
+	return cluster, nil
 }
 
 // RunningK8sMockData data struct
-type RunningK8sMockData struct{}
+type RunningK8sMockData struct {
+	Name string // the namespace name
+}
+
+// Namespace method for RunningK8sMockData
+func (k RunningK8sMockData) Namespace() string {
+	return k.Name
+}
 
 // DaemonSets method for RunningK8sMockData
 func (k RunningK8sMockData) DaemonSets() ([]*models.K8sResource, error) {
@@ -60,12 +74,24 @@ func (k RunningK8sMockData) Events() ([]*models.K8sResource, error) {
 	return []*models.K8sResource{}, nil
 }
 
+// Logs method for RunningK8sMockData
+func (k RunningK8sMockData) Logs(log *logger.Logger, opts k8s.PodLogOptions) ([]*models.ContainerLog, error) {
+	// TODO: implement
+	return []*models.ContainerLog{}, nil
+}
+
 // Nodes method for RunningK8sMockData
 func (k RunningK8sMockData) Nodes() ([]*models.K8sResource, error) {
 	// TODO: implement
 	return []*models.K8sResource{}, nil
 }
 
+// NodeSummaries method for RunningK8sMockData
+func (k RunningK8sMockData) NodeSummaries() ([]*models.NodeSummary, error) {
+	// TODO: implement
+	return []*models.NodeSummary{}, nil
+}
+
 // Pods method for RunningK8sMockData
 func (k RunningK8sMockData) Pods() ([]*models.K8sResource, error) {
 	// TODO: implement
@@ -119,8 +145,8 @@ func (c *ClusterIDMockData) StoreInCache(cid string) {
 type LatestMockData struct{}
 
 // Get method for LatestMockData
-func (c LatestMockData) Get(component string, cluster models.Cluster) (models.Version, error) {
-	data, err := GetMockLatest(component)
+func (c LatestMockData) Get(ctx context.Context, component models.Component, cluster models.Cluster) (models.Version, error) {
+	data, err := GetMockLatest(component.Name)
 	if err != nil {
 		log.Print(err)
 		return models.Version{}, err
@@ -128,6 +154,14 @@ func (c LatestMockData) Get(component string, cluster models.Cluster) (models.Ve
 	return data, nil
 }
 
+// RedactionKeyMockData mock data struct
+type RedactionKeyMockData struct{}
+
+// Get is the RedactionKey interface implementation
+func (r RedactionKeyMockData) Get() ([]byte, error) {
+	return []byte("redaction-key"), nil
+}
+
 // GetMockCluster returns a mock JSON cluster response
 func GetMockCluster() ([]byte, error) {
 	data, err := getJSON(getMocksWd() + "cluster.json")
@@ -27,6 +27,10 @@ type Component struct {
 	*/
 	Name string `json:"name"`
 
+	/* namespace
+	 */
+	Namespace *string `json:"namespace,omitempty"`
+
 	/* type
 	 */
 	Type *string `json:"type,omitempty"`
@@ -0,0 +1,54 @@
+package models
+
+import "github.com/go-swagger/go-swagger/strfmt"
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+/*ComponentStatus component status
+
+swagger:model componentStatus
+*/
+type ComponentStatus struct {
+
+	/* available
+	 */
+	Available int64 `json:"available"`
+
+	/* desired
+	 */
+	Desired int64 `json:"desired"`
+
+	/* healthy
+	 */
+	Healthy bool `json:"healthy"`
+
+	/* misscheduled
+	 */
+	Misscheduled int64 `json:"misscheduled,omitempty"`
+
+	/* ready
+	 */
+	Ready int64 `json:"ready"`
+
+	/* restarts
+	 */
+	Restarts int64 `json:"restarts"`
+
+	/* complete, progressing or paused. Only set for Deployments
+	 */
+	Rollout string `json:"rollout,omitempty"`
+
+	/* scheduled
+	 */
+	Scheduled int64 `json:"scheduled,omitempty"`
+
+	/* updated
+	 */
+	Updated int64 `json:"updated,omitempty"`
+}
+
+// Validate validates this component status
+func (m *ComponentStatus) Validate(formats strfmt.Registry) error {
+	return nil
+}
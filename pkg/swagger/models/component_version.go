@@ -15,6 +15,10 @@ type ComponentVersion struct {
 	 */
 	Component *Component `json:"component,omitempty"`
 
+	/* status
+	 */
+	Status *ComponentStatus `json:"status,omitempty"`
+
 	/* update available
 	 */
 	UpdateAvailable *string `json:"updateAvailable,omitempty"`
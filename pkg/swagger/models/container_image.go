@@ -0,0 +1,73 @@
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-swagger/go-swagger/strfmt"
+
+	"github.com/go-swagger/go-swagger/errors"
+	"github.com/go-swagger/go-swagger/httpkit/validate"
+)
+
+/*ContainerImage container image
+
+swagger:model containerImage
+*/
+type ContainerImage struct {
+
+	/* digest
+	 */
+	Digest string `json:"digest,omitempty"`
+
+	/* image
+	 */
+	Image string `json:"image,omitempty"`
+
+	/* init
+	 */
+	Init bool `json:"init,omitempty"`
+
+	/* name
+
+	Required: true
+	Min Length: 1
+	*/
+	Name string `json:"name"`
+
+	/* tag
+	 */
+	Tag string `json:"tag,omitempty"`
+
+	/* whether the pods run the container's image, resolved to a single digest. Unset if unknown
+	 */
+	UpToDate *bool `json:"upToDate,omitempty"`
+}
+
+// Validate validates this container image
+func (m *ContainerImage) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateName(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ContainerImage) validateName(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("name", "body", string(m.Name)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("name", "body", string(m.Name), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
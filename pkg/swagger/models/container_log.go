@@ -0,0 +1,94 @@
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-swagger/go-swagger/strfmt"
+
+	"github.com/go-swagger/go-swagger/errors"
+	"github.com/go-swagger/go-swagger/httpkit/validate"
+)
+
+/*ContainerLog container log
+
+swagger:model containerLog
+*/
+type ContainerLog struct {
+
+	/* container
+
+	Required: true
+	Min Length: 1
+	*/
+	Container string `json:"container"`
+
+	/* error
+	 */
+	Error *string `json:"error,omitempty"`
+
+	/* log
+	 */
+	Log string `json:"log,omitempty"`
+
+	/* pod
+
+	Required: true
+	Min Length: 1
+	*/
+	Pod string `json:"pod"`
+
+	/* previous
+	 */
+	Previous bool `json:"previous,omitempty"`
+
+	/* truncated
+	 */
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Validate validates this container log
+func (m *ContainerLog) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateContainer(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validatePod(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ContainerLog) validateContainer(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("container", "body", string(m.Container)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("container", "body", string(m.Container), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *ContainerLog) validatePod(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("pod", "body", string(m.Pod)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("pod", "body", string(m.Pod), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ package models
 
 import (
 	strfmt "github.com/go-swagger/go-swagger/strfmt"
+	"github.com/go-swagger/go-swagger/swag"
 
 	"github.com/go-swagger/go-swagger/errors"
 	"github.com/go-swagger/go-swagger/httpkit/validate"
@@ -16,18 +17,30 @@ swagger:model doctorInfo
 */
 type DoctorInfo struct {
 
+	/* findings
+	 */
+	Findings []*Finding `json:"findings,omitempty"`
+
 	/* namespaces
 
 	Required: true
 	*/
 	Namespaces []*Namespace `json:"namespaces"`
 
+	/* node summaries
+	 */
+	NodeSummaries []*NodeSummary `json:"nodeSummaries,omitempty"`
+
 	/* nodes
 
 	Required: true
 	*/
 	Nodes []*K8sResource `json:"nodes"`
 
+	/* redactions
+	 */
+	Redactions []*Redaction `json:"redactions,omitempty"`
+
 	/* workflow
 
 	Required: true
@@ -39,16 +52,31 @@ type DoctorInfo struct {
 func (m *DoctorInfo) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateFindings(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateNamespaces(formats); err != nil {
 		// prop
 		res = append(res, err)
 	}
 
+	if err := m.validateNodeSummaries(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateNodes(formats); err != nil {
 		// prop
 		res = append(res, err)
 	}
 
+	if err := m.validateRedactions(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateWorkflow(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -60,6 +88,26 @@ func (m *DoctorInfo) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *DoctorInfo) validateFindings(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Findings) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Findings); i++ {
+
+		if m.Findings[i] != nil {
+
+			if err := m.Findings[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *DoctorInfo) validateNamespaces(formats strfmt.Registry) error {
 
 	if err := validate.Required("namespaces", "body", m.Namespaces); err != nil {
@@ -80,6 +128,26 @@ func (m *DoctorInfo) validateNamespaces(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *DoctorInfo) validateNodeSummaries(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.NodeSummaries) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.NodeSummaries); i++ {
+
+		if m.NodeSummaries[i] != nil {
+
+			if err := m.NodeSummaries[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *DoctorInfo) validateNodes(formats strfmt.Registry) error {
 
 	if err := validate.Required("nodes", "body", m.Nodes); err != nil {
@@ -100,6 +168,26 @@ func (m *DoctorInfo) validateNodes(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *DoctorInfo) validateRedactions(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Redactions) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Redactions); i++ {
+
+		if m.Redactions[i] != nil {
+
+			if err := m.Redactions[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *DoctorInfo) validateWorkflow(formats strfmt.Registry) error {
 
 	if m.Workflow != nil {
@@ -0,0 +1,119 @@
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-swagger/go-swagger/strfmt"
+
+	"github.com/go-swagger/go-swagger/errors"
+	"github.com/go-swagger/go-swagger/httpkit/validate"
+)
+
+/*Finding finding
+
+swagger:model finding
+*/
+type Finding struct {
+
+	/* check
+
+	Required: true
+	Min Length: 1
+	*/
+	Check string `json:"check"`
+
+	/* kind
+	 */
+	Kind string `json:"kind,omitempty"`
+
+	/* message
+
+	Required: true
+	Min Length: 1
+	*/
+	Message string `json:"message"`
+
+	/* name
+	 */
+	Name string `json:"name,omitempty"`
+
+	/* namespace
+	 */
+	Namespace string `json:"namespace,omitempty"`
+
+	/* remediation
+	 */
+	Remediation string `json:"remediation,omitempty"`
+
+	/* severity
+
+	Required: true
+	Min Length: 1
+	*/
+	Severity string `json:"severity"`
+}
+
+// Validate validates this finding
+func (m *Finding) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateCheck(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateMessage(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateSeverity(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *Finding) validateCheck(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("check", "body", string(m.Check)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("check", "body", string(m.Check), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Finding) validateMessage(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("message", "body", string(m.Message)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("message", "body", string(m.Message), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Finding) validateSeverity(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("severity", "body", string(m.Severity)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("severity", "body", string(m.Severity), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
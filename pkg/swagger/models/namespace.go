@@ -5,6 +5,7 @@ package models
 
 import (
 	strfmt "github.com/go-swagger/go-swagger/strfmt"
+	"github.com/go-swagger/go-swagger/swag"
 
 	"github.com/go-swagger/go-swagger/errors"
 	"github.com/go-swagger/go-swagger/httpkit/validate"
@@ -34,6 +35,10 @@ type Namespace struct {
 	*/
 	Events []*K8sResource `json:"events"`
 
+	/* logs
+	 */
+	Logs []*ContainerLog `json:"logs,omitempty"`
+
 	/* name
 
 	Required: true
@@ -84,6 +89,11 @@ func (m *Namespace) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateLogs(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateName(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -175,6 +185,26 @@ func (m *Namespace) validateEvents(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Namespace) validateLogs(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Logs) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Logs); i++ {
+
+		if m.Logs[i] != nil {
+
+			if err := m.Logs[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Namespace) validateName(formats strfmt.Registry) error {
 
 	if err := validate.RequiredString("name", "body", string(m.Name)); err != nil {
@@ -0,0 +1,28 @@
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-swagger/go-swagger/strfmt"
+)
+
+/*NodeResources node resources
+
+swagger:model nodeResources
+*/
+type NodeResources struct {
+
+	/* cpu millicores
+	 */
+	CPUMillicores int64 `json:"cpuMillicores"`
+
+	/* memory bytes
+	 */
+	MemoryBytes int64 `json:"memoryBytes"`
+}
+
+// Validate validates this node resources
+func (m *NodeResources) Validate(formats strfmt.Registry) error {
+	return nil
+}
@@ -0,0 +1,146 @@
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-swagger/go-swagger/strfmt"
+	"github.com/go-swagger/go-swagger/swag"
+
+	"github.com/go-swagger/go-swagger/errors"
+	"github.com/go-swagger/go-swagger/httpkit/validate"
+)
+
+/*NodeSummary node summary
+
+swagger:model nodeSummary
+*/
+type NodeSummary struct {
+
+	/* allocatable
+	 */
+	Allocatable *NodeResources `json:"allocatable,omitempty"`
+
+	/* conditions
+	 */
+	Conditions map[string]string `json:"conditions,omitempty"`
+
+	/* container runtime version
+	 */
+	ContainerRuntimeVersion string `json:"containerRuntimeVersion,omitempty"`
+
+	/* kubelet version
+	 */
+	KubeletVersion string `json:"kubeletVersion,omitempty"`
+
+	/* name
+
+	Required: true
+	Min Length: 1
+	*/
+	Name string `json:"name"`
+
+	/* pods
+	 */
+	Pods int64 `json:"pods"`
+
+	/* ready
+	 */
+	Ready bool `json:"ready"`
+
+	/* requested
+	 */
+	Requested *NodeResources `json:"requested,omitempty"`
+
+	/* taints
+	 */
+	Taints []string `json:"taints,omitempty"`
+
+	/* unschedulable
+	 */
+	Unschedulable bool `json:"unschedulable,omitempty"`
+}
+
+// Validate validates this node summary
+func (m *NodeSummary) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateAllocatable(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateName(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRequested(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateTaints(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *NodeSummary) validateAllocatable(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Allocatable) { // not required
+		return nil
+	}
+
+	if m.Allocatable != nil {
+
+		if err := m.Allocatable.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *NodeSummary) validateName(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("name", "body", string(m.Name)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("name", "body", string(m.Name), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NodeSummary) validateRequested(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Requested) { // not required
+		return nil
+	}
+
+	if m.Requested != nil {
+
+		if err := m.Requested.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *NodeSummary) validateTaints(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Taints) { // not required
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-swagger/go-swagger/strfmt"
+
+	"github.com/go-swagger/go-swagger/errors"
+	"github.com/go-swagger/go-swagger/httpkit/validate"
+)
+
+/*Redaction redaction
+
+swagger:model redaction
+*/
+type Redaction struct {
+
+	/* path
+
+	Required: true
+	Min Length: 1
+	*/
+	Path string `json:"path"`
+
+	/* rule
+
+	Required: true
+	Min Length: 1
+	*/
+	Rule string `json:"rule"`
+}
+
+// Validate validates this redaction
+func (m *Redaction) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validatePath(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRule(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *Redaction) validatePath(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("path", "body", string(m.Path)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("path", "body", string(m.Path), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Redaction) validateRule(formats strfmt.Registry) error {
+
+	if err := validate.RequiredString("rule", "body", string(m.Rule)); err != nil {
+		return err
+	}
+
+	if err := validate.MinLength("rule", "body", string(m.Rule), 1); err != nil {
+		return err
+	}
+
+	return nil
+}
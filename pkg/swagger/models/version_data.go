@@ -17,6 +17,10 @@ swagger:model versionData
 */
 type VersionData struct {
 
+	/* containers
+	 */
+	Containers []*ContainerImage `json:"containers,omitempty"`
+
 	/* description
 
 	Min Length: 1
@@ -38,6 +42,11 @@ type VersionData struct {
 func (m *VersionData) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateContainers(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateDescription(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -54,6 +63,26 @@ func (m *VersionData) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *VersionData) validateContainers(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Containers) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Containers); i++ {
+
+		if m.Containers[i] != nil {
+
+			if err := m.Containers[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *VersionData) validateDescription(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.Description) { // not required
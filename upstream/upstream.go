@@ -0,0 +1,165 @@
+// Package upstream contains a client for the workflow manager versions API that speaks either the
+// v2 or the v3 flavor of the API, as configured or as negotiated with the server
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/logger"
+	apiclient "github.com/deis/workflow-manager/pkg/swagger/client"
+	"github.com/deis/workflow-manager/pkg/swagger/client/operations"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+const (
+	// APIVersion2 is the API_VERSION value for the v2 versions API
+	APIVersion2 = "v2"
+	// APIVersion3 is the API_VERSION value for the v3 versions API
+	APIVersion3 = "v3"
+	// APIVersionAuto is the API_VERSION value that uses the v3 versions API if the server supports
+	// it, and the v2 versions API otherwise
+	APIVersionAuto = "auto"
+)
+
+// Client is an interface for the versions API operations used by the workflow manager
+type Client interface {
+	// SendClusterDetails sends the given cluster's components to the versions API. The request is
+	// cancelled when ctx is done, and logged with the logger that ctx carries
+	SendClusterDetails(ctx context.Context, cluster *models.Cluster) error
+	// GetLatestVersions returns the latest released version of each of the given components. The
+	// request is cancelled when ctx is done, and logged with the logger that ctx carries
+	GetLatestVersions(ctx context.Context, components []*models.ComponentVersion) ([]*models.ComponentVersion, error)
+	// APIVersion returns the version of the versions API in use. It returns APIVersionAuto if the
+	// version has not been negotiated yet
+	APIVersion() string
+}
+
+// versionedClient fulfills the Client interface using a swagger client
+type versionedClient struct {
+	apiClient *apiclient.WorkflowManager
+	rwm       *sync.RWMutex
+	version   string
+}
+
+// NewClient returns a new Client that calls the versions API with apiClient, using the given API
+// version. If apiVersion is APIVersionAuto, the first call tries the v3 API, and switches to (and
+// sticks with) the v2 API if the server doesn't support it. Returns an error if apiVersion isn't
+// one of APIVersion2, APIVersion3 or APIVersionAuto
+func NewClient(apiClient *apiclient.WorkflowManager, apiVersion string) (Client, error) {
+	if err := ValidateAPIVersion(apiVersion); err != nil {
+		return nil, err
+	}
+	return &versionedClient{apiClient: apiClient, rwm: new(sync.RWMutex), version: apiVersion}, nil
+}
+
+// ValidateAPIVersion returns an error if apiVersion isn't one of APIVersion2, APIVersion3 or
+// APIVersionAuto
+func ValidateAPIVersion(apiVersion string) error {
+	switch apiVersion {
+	case APIVersion2, APIVersion3, APIVersionAuto:
+		return nil
+	}
+	return fmt.Errorf("unsupported versions API version %q, expected %s, %s or %s", apiVersion, APIVersion2, APIVersion3, APIVersionAuto)
+}
+
+// APIVersion is the Client interface implementation
+func (c *versionedClient) APIVersion() string {
+	c.rwm.RLock()
+	defer c.rwm.RUnlock()
+	return c.version
+}
+
+// SendClusterDetails is the Client interface implementation. The v2 API takes the cluster ID as a
+// path parameter, so cluster.ID must be set
+func (c *versionedClient) SendClusterDetails(ctx context.Context, cluster *models.Cluster) error {
+	apiClient := config.WithContext(ctx, c.apiClient)
+	return c.negotiate(ctx, func(version string) error {
+		if version == APIVersion2 {
+			_, err := apiClient.Operations.CreateClusterDetailsForV2(&operations.CreateClusterDetailsForV2Params{
+				ID:   cluster.ID,
+				Body: cluster,
+			})
+			return err
+		}
+		_, err := apiClient.Operations.CreateClusterDetails(&operations.CreateClusterDetailsParams{Body: cluster})
+		return err
+	})
+}
+
+// GetLatestVersions is the Client interface implementation
+func (c *versionedClient) GetLatestVersions(ctx context.Context, components []*models.ComponentVersion) ([]*models.ComponentVersion, error) {
+	apiClient := config.WithContext(ctx, c.apiClient)
+	var ret []*models.ComponentVersion
+	err := c.negotiate(ctx, func(version string) error {
+		if version == APIVersion2 {
+			resp, err := apiClient.Operations.GetComponentsByLatestReleaseForV2(&operations.GetComponentsByLatestReleaseForV2Params{
+				Body: operations.GetComponentsByLatestReleaseForV2Body{Data: components},
+			})
+			if err != nil {
+				return err
+			}
+			ret = resp.Payload.Data
+			return nil
+		}
+		resp, err := apiClient.Operations.GetComponentsByLatestRelease(&operations.GetComponentsByLatestReleaseParams{
+			Body: operations.GetComponentsByLatestReleaseBody{Data: components},
+		})
+		if err != nil {
+			return err
+		}
+		ret = resp.Payload.Data
+		return nil
+	})
+	return ret, err
+}
+
+// negotiate calls op with the configured API version. If the API version is still to be
+// negotiated, op is called with APIVersion3, and again with APIVersion2 if the server doesn't
+// support v3. The first version that the server supports is used for all later calls. The fallback
+// is logged with the logger that ctx carries
+func (c *versionedClient) negotiate(ctx context.Context, op func(version string) error) error {
+	version := c.APIVersion()
+	if version != APIVersionAuto {
+		return op(version)
+	}
+	err := op(APIVersion3)
+	if err == nil {
+		c.setVersion(APIVersion3)
+		return nil
+	}
+	if !isUnsupported(err) {
+		return err
+	}
+	logger.FromContext(ctx).WithError(err).Infof("versions API doesn't support %s, falling back to %s", APIVersion3, APIVersion2)
+	if err := op(APIVersion2); err != nil {
+		return err
+	}
+	c.setVersion(APIVersion2)
+	return nil
+}
+
+func (c *versionedClient) setVersion(version string) {
+	c.rwm.Lock()
+	defer c.rwm.Unlock()
+	c.version = version
+}
+
+// isUnsupported returns true if err is an error response that indicates that the server doesn't
+// serve the requested route
+func isUnsupported(err error) bool {
+	coded, ok := err.(interface {
+		Code() int
+	})
+	if !ok {
+		return false
+	}
+	switch coded.Code() {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+)
+
+const mockClusterID = "f91378a6-a815-4c20-9b0d-77b205cd3ee4"
+
+// newVersionsServer returns a test server that only serves the given API version, and records the
+// paths of the requests that it receives
+func newVersionsServer(version string, paths *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*paths = append(*paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		var body interface{}
+		switch {
+		case r.URL.Path == "/"+version+"/versions/latest":
+			body = map[string][]models.ComponentVersion{
+				"data": {{Component: &models.Component{Name: "deis-router"}, Version: &models.Version{Version: "2.3.0"}}},
+			}
+		case version == APIVersion2 && r.URL.Path == "/v2/clusters/"+mockClusterID,
+			version == APIVersion3 && r.URL.Path == "/v3/clusters":
+			body = models.Cluster{ID: mockClusterID}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			body = models.Error{Code: http.StatusNotFound, Message: "not found"}
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+}
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient(nil, "v1")
+	assert.True(t, err != nil, "expected an error for an unsupported API version")
+	for _, version := range []string{APIVersion2, APIVersion3, APIVersionAuto} {
+		c, err := NewClient(nil, version)
+		assert.NoErr(t, err)
+		assert.Equal(t, c.APIVersion(), version, "API version")
+	}
+}
+
+func TestClientV2(t *testing.T) {
+	var paths []string
+	ts := newVersionsServer(APIVersion2, &paths)
+	defer ts.Close()
+	apiClient, err := config.GetSwaggerClient(ts.URL)
+	assert.NoErr(t, err)
+	c, err := NewClient(apiClient, APIVersion2)
+	assert.NoErr(t, err)
+	assert.NoErr(t, c.SendClusterDetails(context.Background(), &models.Cluster{ID: mockClusterID}))
+	latest, err := c.GetLatestVersions(context.Background(), []*models.ComponentVersion{{Component: &models.Component{Name: "deis-router"}}})
+	assert.NoErr(t, err)
+	assert.Equal(t, len(latest), 1, "number of latest versions")
+	assert.Equal(t, paths, []string{"/v2/clusters/" + mockClusterID, "/v2/versions/latest"}, "request paths")
+}
+
+func TestClientAutoFallsBackToV2(t *testing.T) {
+	var paths []string
+	ts := newVersionsServer(APIVersion2, &paths)
+	defer ts.Close()
+	apiClient, err := config.GetSwaggerClient(ts.URL)
+	assert.NoErr(t, err)
+	c, err := NewClient(apiClient, APIVersionAuto)
+	assert.NoErr(t, err)
+	_, err = c.GetLatestVersions(context.Background(), nil)
+	assert.NoErr(t, err)
+	assert.Equal(t, c.APIVersion(), APIVersion2, "negotiated API version")
+	assert.NoErr(t, c.SendClusterDetails(context.Background(), &models.Cluster{ID: mockClusterID}))
+	assert.Equal(t, paths, []string{
+		"/v3/versions/latest",
+		"/v2/versions/latest",
+		"/v2/clusters/" + mockClusterID,
+	}, "request paths")
+}
+
+func TestClientAutoUsesV3(t *testing.T) {
+	var paths []string
+	ts := newVersionsServer(APIVersion3, &paths)
+	defer ts.Close()
+	apiClient, err := config.GetSwaggerClient(ts.URL)
+	assert.NoErr(t, err)
+	c, err := NewClient(apiClient, APIVersionAuto)
+	assert.NoErr(t, err)
+	assert.NoErr(t, c.SendClusterDetails(context.Background(), &models.Cluster{ID: mockClusterID}))
+	assert.Equal(t, c.APIVersion(), APIVersion3, "negotiated API version")
+	assert.Equal(t, paths, []string{"/v3/clusters"}, "request paths")
+}
@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"encoding/json"
+
+	"github.com/deis/workflow-manager/k8s"
+	"k8s.io/kubernetes/pkg/api"
+	apierrors "k8s.io/kubernetes/pkg/api/errors"
+)
+
+const (
+	notifiedSecretName = "deis-workflow-manager-webhooks"
+	notifiedKey        = "notified"
+)
+
+// NotifiedStore is an interface for remembering the update notifications that were delivered across
+// restarts, so that webhooks aren't notified of the same version twice
+type NotifiedStore interface {
+	// Load returns the version that each webhook was last notified of, for each component. Returns
+	// an empty map if nothing has been stored
+	Load() (map[string]string, error)
+	// Save stores the given notified versions
+	Save(map[string]string) error
+}
+
+// secretNotifiedStore fulfills the NotifiedStore interface using a kubernetes secret
+type secretNotifiedStore struct {
+	secrets k8s.KubeSecretGetterCreatorUpdater
+}
+
+// NewNotifiedStoreFromSecret returns a new NotifiedStore that persists notified versions in a secret
+// next to the deis-workflow-manager secret, using secrets to get, create and update it
+func NewNotifiedStoreFromSecret(secrets k8s.KubeSecretGetterCreatorUpdater) NotifiedStore {
+	return &secretNotifiedStore{secrets: secrets}
+}
+
+// Load is the NotifiedStore interface implementation
+func (s *secretNotifiedStore) Load() (map[string]string, error) {
+	notified := make(map[string]string)
+	secret, err := s.secrets.Get(notifiedSecretName)
+	if apierrors.IsNotFound(err) {
+		return notified, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.Data[notifiedKey] == nil {
+		return notified, nil
+	}
+	if err := json.Unmarshal(secret.Data[notifiedKey], &notified); err != nil {
+		return nil, err
+	}
+	return notified, nil
+}
+
+// Save is the NotifiedStore interface implementation
+func (s *secretNotifiedStore) Save(notified map[string]string) error {
+	js, err := json.Marshal(notified)
+	if err != nil {
+		return err
+	}
+	secret, err := s.secrets.Get(notifiedSecretName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err != nil || secret == nil {
+		newSecret := new(api.Secret)
+		newSecret.Name = notifiedSecretName
+		newSecret.Data = map[string][]byte{notifiedKey: js}
+		_, err := s.secrets.Create(newSecret)
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[notifiedKey] = js
+	_, err = s.secrets.Update(secret)
+	return err
+}
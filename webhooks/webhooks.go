@@ -0,0 +1,298 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/workflow-manager/config"
+	"github.com/deis/workflow-manager/data"
+	"github.com/deis/workflow-manager/jobs"
+	"github.com/deis/workflow-manager/logger"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"github.com/satori/go.uuid"
+)
+
+// the headers of a webhook delivery
+const (
+	// EventHeader carries the event that's delivered
+	EventHeader = "X-Workflow-Manager-Event"
+	// DeliveryHeader carries an ID that's the same for every attempt to deliver a notification, so
+	// that receivers can drop duplicates
+	DeliveryHeader = "X-Workflow-Manager-Delivery"
+	// SignatureHeader carries "sha256=" and the hex encoded HMAC-SHA256 of the body, keyed with the
+	// webhook secret. It's left out if there's no secret
+	SignatureHeader = "X-Workflow-Manager-Signature"
+)
+
+// UpdateAvailableEvent is the event that's delivered when an update becomes available for a component
+const UpdateAvailableEvent = "component.update_available"
+
+// UpdatePayload is the JSON body of an UpdateAvailableEvent delivery
+type UpdatePayload struct {
+	Event            string            `json:"event"`
+	ClusterID        string            `json:"clusterID"`
+	Component        *models.Component `json:"component"`
+	InstalledVersion string            `json:"installedVersion"`
+	AvailableVersion string            `json:"availableVersion"`
+	Train            string            `json:"train,omitempty"`
+	Released         string            `json:"released,omitempty"`
+	// Description and Fixes are the release notes of the available version
+	Description string `json:"description,omitempty"`
+	Fixes       string `json:"fixes,omitempty"`
+}
+
+// Notifier fulfills the jobs.UpdateNotifier interface by POSTing an UpdatePayload to each of its
+// webhook URLs, once per component and available version
+type Notifier struct {
+	urls     []string
+	secret   []byte
+	client   *http.Client
+	policy   jobs.RetryPolicy
+	timeout  time.Duration
+	versions data.AvailableComponentVersion
+	store    NotifiedStore
+	mut      *sync.Mutex
+	// notified is the version that each webhook was last notified of, for each component, keyed by
+	// webhookID and componentKey. It's loaded from store on first use
+	notified map[string]string
+}
+
+// NewNotifier returns a new Notifier that delivers to urls with client, signing deliveries with
+// secret unless it's empty. Failed deliveries are retried according to policy, and all of the
+// deliveries of a call to NotifyUpdates, retries included, are cut off after timeout unless it's
+// zero. The release notes of available versions are read from versions, and the notifications
+// that were delivered are remembered in store
+func NewNotifier(
+	urls []string,
+	secret string,
+	client *http.Client,
+	policy jobs.RetryPolicy,
+	timeout time.Duration,
+	versions data.AvailableComponentVersion,
+	store NotifiedStore,
+) *Notifier {
+	return &Notifier{
+		urls:     urls,
+		secret:   []byte(secret),
+		client:   client,
+		policy:   policy,
+		timeout:  timeout,
+		versions: versions,
+		store:    store,
+		mut:      new(sync.Mutex),
+	}
+}
+
+// ConfiguredRetryPolicy returns the jobs.RetryPolicy for webhook deliveries configured in
+// config.Spec
+func ConfiguredRetryPolicy() jobs.RetryPolicy {
+	return jobs.RetryPolicy{
+		MaxAttempts:    config.Spec.WebhookRetryMaxAttempts,
+		InitialBackoff: time.Duration(config.Spec.WebhookRetryInitialBackoff) * time.Second,
+		MaxBackoff:     time.Duration(config.Spec.WebhookRetryMaxBackoff) * time.Second,
+		Multiplier:     2,
+		Jitter:         config.Spec.RetryJitter,
+	}
+}
+
+// ParseURLs returns the webhook URLs in the comma separated list urls. Returns an error if any of
+// them isn't an absolute http or https URL
+func ParseURLs(urls string) ([]string, error) {
+	var ret []string
+	for _, u := range strings.Split(urls, ",") {
+		if u = strings.TrimSpace(u); u == "" {
+			continue
+		}
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			// the URL may carry credentials, so only its position is reported
+			return nil, fmt.Errorf("webhook URL %d isn't an absolute http(s) URL", len(ret)+1)
+		}
+		ret = append(ret, u)
+	}
+	return ret, nil
+}
+
+// NotifyUpdates is the jobs.UpdateNotifier interface implementation. Each webhook is notified of
+// every component whose available update differs from the one it was last notified of. A
+// notification that can't be delivered, including one that's cut off by the notifier's timeout, is
+// tried again on the next call
+func (n *Notifier) NotifyUpdates(ctx context.Context, cluster models.Cluster) error {
+	if n.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.timeout)
+		defer cancel()
+	}
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	if n.notified == nil {
+		notified, err := n.store.Load()
+		if err != nil {
+			return err
+		}
+		n.notified = notified
+	}
+	log := logger.FromContext(ctx)
+	failed := 0
+	for _, cv := range cluster.Components {
+		if cv == nil || cv.Component == nil || cv.UpdateAvailable == nil || *cv.UpdateAvailable == "" {
+			continue
+		}
+		available := *cv.UpdateAvailable
+		component := componentKey(cv.Component)
+		var body []byte
+		var deliveryID string
+		for _, u := range n.urls {
+			key := webhookID(u) + " " + component
+			if n.notified[key] == available {
+				continue
+			}
+			if body == nil {
+				var err error
+				if body, err = n.payload(ctx, cluster, cv); err != nil {
+					return err
+				}
+				deliveryID = uuid.NewV4().String()
+			}
+			if err := n.deliver(ctx, u, body, deliveryID); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// the URL may carry credentials, so only its host is logged
+				log.WithError(err).WithFields(logger.Fields{
+					"component": component,
+					"version":   available,
+					"webhook":   webhookHost(u),
+				}).Warnf("unable to deliver update notification")
+				failed++
+				continue
+			}
+			n.notified[key] = available
+			if err := n.store.Save(n.notified); err != nil {
+				return err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d update notifications weren't delivered", failed)
+	}
+	return nil
+}
+
+// payload returns the JSON encoded UpdatePayload for cv, a component of cluster
+func (n *Notifier) payload(ctx context.Context, cluster models.Cluster, cv *models.ComponentVersion) ([]byte, error) {
+	p := UpdatePayload{
+		Event:            UpdateAvailableEvent,
+		ClusterID:        cluster.ID,
+		Component:        cv.Component,
+		AvailableVersion: *cv.UpdateAvailable,
+	}
+	if cv.Version != nil {
+		p.InstalledVersion = cv.Version.Version
+	}
+	latest, err := n.versions.Get(ctx, *cv.Component, cluster)
+	// release notes are a courtesy, so a notification is still sent without them
+	if err == nil && latest.Version == p.AvailableVersion {
+		p.Train = latest.Train
+		p.Released = latest.Released
+		if latest.Data != nil {
+			p.Description = latest.Data.Description
+			p.Fixes = latest.Data.Fixes
+		}
+	}
+	return json.Marshal(p)
+}
+
+// deliver POSTs body to webhookURL, retrying according to n's policy until it's accepted, it's
+// rejected with a client error other than 408 or 429, or ctx is done
+func (n *Notifier) deliver(ctx context.Context, webhookURL string, body []byte, deliveryID string) error {
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, webhookURL, body, deliveryID)
+		if err == nil || !retry || attempt >= n.policy.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-time.After(n.policy.Backoff(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// post makes a single delivery attempt, and returns whether it's worth retrying if it fails
+func (n *Notifier) post(ctx context.Context, webhookURL string, body []byte, deliveryID string) (bool, error) {
+	req, err := http.NewRequest("POST", webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", config.Spec.UserAgent)
+	req.Header.Set(EventHeader, UpdateAvailableEvent)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+	resp, err := n.client.Do(req)
+	if urlErr, ok := err.(*url.Error); ok {
+		// the URL may carry credentials, so it's left out of the error
+		return true, fmt.Errorf("%s failed (%s)", urlErr.Op, urlErr.Err)
+	}
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook responded with %s", resp.Status)
+}
+
+// Sign returns the value of the SignatureHeader of a delivery of body, signed with secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// componentKey identifies component in the notifications that were delivered
+func componentKey(component *models.Component) string {
+	parts := []string{"", "", component.Name}
+	if component.Namespace != nil {
+		parts[0] = *component.Namespace
+	}
+	if component.Type != nil {
+		parts[1] = *component.Type
+	}
+	return strings.Join(parts, "/")
+}
+
+// webhookID identifies the webhook URL u in the notifications that were delivered, without keeping
+// any credentials that u carries
+func webhookID(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(sum[:8])
+}
+
+// webhookHost returns the host of the webhook URL u, or the empty string if it can't be parsed
+func webhookHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
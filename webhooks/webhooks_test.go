@@ -0,0 +1,239 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/deis/workflow-manager/jobs"
+	"github.com/deis/workflow-manager/pkg/swagger/models"
+	"k8s.io/kubernetes/pkg/api"
+	apierrors "k8s.io/kubernetes/pkg/api/errors"
+)
+
+const testSecret = "s3cr3t"
+
+// Creating a novel mock struct that fulfills the NotifiedStore interface, storing in memory
+type mockNotifiedStore struct {
+	notified map[string]string
+	saves    int
+}
+
+func (m *mockNotifiedStore) Load() (map[string]string, error) {
+	ret := make(map[string]string)
+	for k, v := range m.notified {
+		ret[k] = v
+	}
+	return ret, nil
+}
+
+func (m *mockNotifiedStore) Save(notified map[string]string) error {
+	m.saves++
+	m.notified = make(map[string]string)
+	for k, v := range notified {
+		m.notified[k] = v
+	}
+	return nil
+}
+
+// Creating a novel mock struct that fulfills the data.AvailableComponentVersion interface
+type mockAvailableVersion struct {
+	version string
+}
+
+func (m mockAvailableVersion) Get(ctx context.Context, component models.Component, cluster models.Cluster) (models.Version, error) {
+	return models.Version{
+		Version: m.version,
+		Train:   "stable",
+		Data:    &models.VersionData{Description: component.Name + " release", Fixes: "bug fixes"},
+	}, nil
+}
+
+// Creating a novel mock struct that fulfills the k8s.KubeSecretGetterCreatorUpdater interface,
+// storing secrets in memory
+type mockSecrets struct {
+	secrets map[string]*api.Secret
+}
+
+func (m *mockSecrets) Get(name string) (*api.Secret, error) {
+	sec, ok := m.secrets[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(api.Resource("secrets"), name)
+	}
+	return sec, nil
+}
+
+func (m *mockSecrets) Create(sec *api.Secret) (*api.Secret, error) {
+	m.secrets[sec.Name] = sec
+	return sec, nil
+}
+
+func (m *mockSecrets) Update(sec *api.Secret) (*api.Secret, error) {
+	m.secrets[sec.Name] = sec
+	return sec, nil
+}
+
+// webhookServer records the payloads that it's delivered, and responds to each with the next of its
+// codes, or 200 once they're used up
+type webhookServer struct {
+	mut        sync.Mutex
+	codes      []int
+	payloads   []UpdatePayload
+	deliveries []string
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	code := http.StatusOK
+	if len(s.codes) > 0 {
+		code, s.codes = s.codes[0], s.codes[1:]
+	}
+	if code == http.StatusOK && r.Header.Get(SignatureHeader) != Sign([]byte(testSecret), body) {
+		code = http.StatusUnauthorized
+	}
+	if code == http.StatusOK {
+		var p UpdatePayload
+		json.Unmarshal(body, &p)
+		s.payloads = append(s.payloads, p)
+	}
+	s.deliveries = append(s.deliveries, r.Header.Get(DeliveryHeader))
+	w.WriteHeader(code)
+}
+
+func getTestCluster(available string) models.Cluster {
+	namespace := "deis"
+	componentType := "Deployment"
+	return models.Cluster{
+		ID: "cluster-id",
+		Components: []*models.ComponentVersion{
+			{
+				Component:       &models.Component{Name: "deis-router", Namespace: &namespace, Type: &componentType},
+				Version:         &models.Version{Version: "v2.2.0"},
+				UpdateAvailable: &available,
+			},
+			// a component without an update
+			{
+				Component: &models.Component{Name: "deis-builder", Namespace: &namespace, Type: &componentType},
+				Version:   &models.Version{Version: "v2.3.0"},
+			},
+		},
+	}
+}
+
+func getTestNotifier(url string, store NotifiedStore, version string) *Notifier {
+	return NewNotifier(
+		[]string{url},
+		testSecret,
+		http.DefaultClient,
+		jobs.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		0,
+		mockAvailableVersion{version: version},
+		store,
+	)
+}
+
+func TestNotifyUpdates(t *testing.T) {
+	// the first delivery attempt fails, and is retried
+	srv := &webhookServer{codes: []int{http.StatusServiceUnavailable}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	store := &mockNotifiedStore{}
+	notifier := getTestNotifier(ts.URL, store, "v2.3.0")
+	ctx := context.Background()
+	assert.NoErr(t, notifier.NotifyUpdates(ctx, getTestCluster("v2.3.0")))
+	assert.Equal(t, len(srv.payloads), 1, "number of delivered payloads")
+	assert.Equal(t, srv.deliveries[0], srv.deliveries[1], "delivery ID of a retried delivery")
+	p := srv.payloads[0]
+	assert.Equal(t, p.Event, UpdateAvailableEvent, "event")
+	assert.Equal(t, p.ClusterID, "cluster-id", "cluster ID")
+	assert.Equal(t, p.Component.Name, "deis-router", "component name")
+	assert.Equal(t, p.InstalledVersion, "v2.2.0", "installed version")
+	assert.Equal(t, p.AvailableVersion, "v2.3.0", "available version")
+	assert.Equal(t, p.Description, "deis-router release", "release description")
+	assert.Equal(t, p.Fixes, "bug fixes", "release fixes")
+
+	// the same version isn't notified again, even by a new notifier that loads the same store
+	assert.NoErr(t, notifier.NotifyUpdates(ctx, getTestCluster("v2.3.0")))
+	assert.NoErr(t, getTestNotifier(ts.URL, store, "v2.3.0").NotifyUpdates(ctx, getTestCluster("v2.3.0")))
+	assert.Equal(t, len(srv.payloads), 1, "number of delivered payloads after notifying twice")
+
+	// a newer version is notified
+	assert.NoErr(t, notifier.NotifyUpdates(ctx, getTestCluster("v2.4.0")))
+	assert.Equal(t, len(srv.payloads), 2, "number of delivered payloads after a newer version")
+	assert.Equal(t, srv.payloads[1].AvailableVersion, "v2.4.0", "newer available version")
+	// release notes of another version aren't sent
+	assert.Equal(t, srv.payloads[1].Description, "", "release description of a mismatched version")
+}
+
+func TestNotifyUpdatesRejected(t *testing.T) {
+	srv := &webhookServer{codes: []int{http.StatusBadRequest}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	store := &mockNotifiedStore{}
+	notifier := getTestNotifier(ts.URL, store, "v2.3.0")
+	err := notifier.NotifyUpdates(context.Background(), getTestCluster("v2.3.0"))
+	assert.True(t, err != nil, "no error for a rejected notification")
+	assert.Equal(t, len(srv.deliveries), 1, "number of attempts to deliver a rejected notification")
+	assert.Equal(t, store.saves, 0, "number of saves after a rejected notification")
+	// the notification is tried again on the next call
+	assert.NoErr(t, notifier.NotifyUpdates(context.Background(), getTestCluster("v2.3.0")))
+	assert.Equal(t, len(srv.payloads), 1, "number of delivered payloads")
+}
+
+func TestNotifyUpdatesTimeout(t *testing.T) {
+	srv := &webhookServer{codes: []int{http.StatusServiceUnavailable}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	store := &mockNotifiedStore{}
+	// the retry is cut off by the timeout, rather than waiting for its backoff
+	notifier := NewNotifier(
+		[]string{ts.URL},
+		testSecret,
+		http.DefaultClient,
+		jobs.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour},
+		50*time.Millisecond,
+		mockAvailableVersion{version: "v2.3.0"},
+		store,
+	)
+	start := time.Now()
+	err := notifier.NotifyUpdates(context.Background(), getTestCluster("v2.3.0"))
+	assert.True(t, err != nil, "no error for a notification that was cut off")
+	assert.True(t, time.Since(start) < 10*time.Second, "notifying took %s", time.Since(start))
+	assert.Equal(t, len(srv.deliveries), 1, "number of attempts to deliver a cut off notification")
+	assert.Equal(t, store.saves, 0, "number of saves after a cut off notification")
+	// the notification is tried again on the next call
+	assert.NoErr(t, notifier.NotifyUpdates(context.Background(), getTestCluster("v2.3.0")))
+	assert.Equal(t, len(srv.payloads), 1, "number of delivered payloads")
+}
+
+func TestParseURLs(t *testing.T) {
+	urls, err := ParseURLs(" https://hooks.example.com/a, http://10.0.0.1:8080/b ,")
+	assert.NoErr(t, err)
+	assert.Equal(t, urls, []string{"https://hooks.example.com/a", "http://10.0.0.1:8080/b"}, "webhook URLs")
+	urls, err = ParseURLs("")
+	assert.NoErr(t, err)
+	assert.Equal(t, len(urls), 0, "number of webhook URLs")
+	_, err = ParseURLs("https://hooks.example.com/a,hooks.example.com/b")
+	assert.True(t, err != nil, "no error for a relative webhook URL")
+}
+
+func TestSecretNotifiedStore(t *testing.T) {
+	store := NewNotifiedStoreFromSecret(&mockSecrets{secrets: make(map[string]*api.Secret)})
+	notified, err := store.Load()
+	assert.NoErr(t, err)
+	assert.Equal(t, len(notified), 0, "number of notified versions in an empty store")
+	for _, version := range []string{"v2.3.0", "v2.4.0"} {
+		assert.NoErr(t, store.Save(map[string]string{"webhook deis/Deployment/deis-router": version}))
+		notified, err = store.Load()
+		assert.NoErr(t, err)
+		assert.Equal(t, notified["webhook deis/Deployment/deis-router"], version, "notified version")
+	}
+}